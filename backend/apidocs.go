@@ -0,0 +1,616 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/handlers"
+	"hospital-backend/middleware"
+	"hospital-backend/models"
+	"hospital-backend/openapi"
+	"hospital-backend/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+// Tags of the documented routes
+const (
+	tagSystem       = "System"
+	tagDoctors      = "Doctors"
+	tagAppointments = "Appointments"
+	tagWaitlist     = "Waitlist"
+	tagAccount      = "Account"
+	tagAdmin        = "Admin"
+	tagDoctorDesk   = "Doctor"
+	tagLegacy       = "Legacy"
+)
+
+// Query parameters shared by several routes
+var (
+	pageParams = []openapi.Param{
+		{Name: "page", Type: "integer", Default: 1, Description: "Page number, from 1"},
+		{Name: "limit", Type: "integer", Default: 20, Description: "Items per page, at most 50"},
+	}
+	cursorParam       = openapi.Param{Name: "cursor", Description: "next_cursor or prev_cursor of a page, read instead of page"}
+	limitOffsetParams = []openapi.Param{
+		{Name: "limit", Type: "integer", Default: 50},
+		{Name: "offset", Type: "integer", Default: 0},
+	}
+	dateRangeParams = []openapi.Param{
+		{Name: "from", Format: "date", Description: "First date (YYYY-MM-DD), default today"},
+		{Name: "to", Format: "date", Description: "Last date (YYYY-MM-DD), inclusive"},
+	}
+	appointmentTypes = []string{models.TypeConsultation, models.TypeFollowUp, models.TypeProcedure}
+)
+
+// bookedFields are the flat fields of a booking in legacy responses
+var bookedFields = openapi.Fields{
+	"appointment_id":   uuid.UUID{},
+	"appointment_date": models.Appointment{}.AppointmentDate,
+	"ends_at":          models.Appointment{}.EndsAt,
+	"appointment_type": "",
+	"local_time":       "",
+	"timezone":         "",
+}
+
+// routeDocs documents the handler of every route, keyed by handler name.
+// newRouter refuses to start when a registered route's handler is missing
+// here.
+var routeDocs = map[string]openapi.Route{
+	// System
+	"health": {
+		Tag:     tagSystem,
+		Summary: "Health check",
+		Data:    openapi.Fields{"status": ""},
+	},
+	"openAPISpec": {
+		Tag:         tagSystem,
+		Summary:     "This OpenAPI document",
+		ContentType: "application/json",
+	},
+	"swaggerUI": {
+		Tag:         tagSystem,
+		Summary:     "Interactive API documentation",
+		ContentType: "text/html",
+	},
+	"swaggerAssets": {Hidden: true},
+
+	// Doctors
+	"ListDoctors": {
+		Tag:     tagDoctors,
+		Summary: "One page of active doctors",
+		Query:   append(append([]openapi.Param{{Name: "specialization"}}, pageParams...), cursorParam),
+		Data:    openapi.Fields{"doctors": []models.Doctor{}, "pagination": handlers.PaginationInfo{}},
+	},
+	"GetDoctors": {
+		Tag:     tagDoctors,
+		Summary: "All active doctors",
+		Data:    openapi.Fields{"doctors": []models.Doctor{}},
+	},
+	"SearchDoctors": {
+		Tag:     tagDoctors,
+		Summary: "Search doctors by name, specialization, language or clinic",
+		Description: "Words match by prefix, and misspelled queries by trigram similarity. " +
+			"Best matches come first, each with its score and its matching words in <mark> tags.",
+		Query: append([]openapi.Param{
+			{Name: "q", Description: "Required unless specialization is given"},
+			{Name: "specialization"},
+		}, pageParams...),
+		Data: openapi.Fields{
+			"doctors":        []models.DoctorMatch{},
+			"query":          "",
+			"specialization": "",
+			"pagination":     handlers.PaginationInfo{},
+		},
+	},
+	"GetDoctorByID": {
+		Tag:     tagDoctors,
+		Summary: "One active doctor",
+		Data:    openapi.Fields{"doctor": models.Doctor{}},
+	},
+	"GetDoctorAvailability": {
+		Tag:         tagDoctors,
+		Summary:     "A doctor's free start slots per day",
+		Description: "Covers the next 7 days by default and at most 31. Slots held by other patients are busy.",
+		Query: append(dateRangeParams,
+			openapi.Param{Name: "appointment_type", Enum: appointmentTypes, Default: models.TypeConsultation},
+			openapi.Param{Name: "hold_token", Format: "uuid", Description: "A hold of the caller, whose slot counts as free"},
+		),
+		Data: openapi.Fields{
+			"doctor_id":        uuid.UUID{},
+			"from":             "",
+			"to":               "",
+			"timezone":         "",
+			"appointment_type": "",
+			"duration_minutes": 0,
+			"availability":     []models.DayAvailability{},
+		},
+	},
+	"ListHolidays": {
+		Tag:     tagDoctors,
+		Summary: "Hospital holidays",
+		Query:   dateRangeParams,
+		Data:    openapi.Fields{"holidays": []models.Holiday{}},
+	},
+	"GetCalendarFeed": {
+		Tag:         tagAccount,
+		Summary:     "iCalendar feed named by a secret token",
+		Description: "The token is the authentication, so calendar apps can subscribe. Append .ics to the token.",
+		ContentType: "text/calendar",
+	},
+
+	// Account
+	"GetCurrentUser": {
+		Tag:     tagAccount,
+		Summary: "The authenticated user",
+		Access:  openapi.Authenticated,
+		Data:    openapi.Fields{"user": models.User{}},
+	},
+	"UpdateCurrentUser": {
+		Tag:     tagAccount,
+		Summary: "Change the authenticated user's phone",
+		Access:  openapi.Authenticated,
+		Body:    models.UpdateProfileRequest{},
+		Data:    openapi.Fields{"user": models.User{}},
+	},
+	"CreateCalendarFeed": {
+		Tag:     tagAccount,
+		Summary: "Issue a secret calendar feed URL, revoking the previous one",
+		Access:  openapi.Authenticated,
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"url": "", "webcal_url": ""},
+	},
+	"DeleteCalendarFeed": {
+		Tag:     tagAccount,
+		Summary: "Revoke the calendar feed URL",
+		Access:  openapi.Authenticated,
+	},
+	"RegisterDevice": {
+		Tag:     tagAccount,
+		Summary: "Register a device for push notifications",
+		Access:  openapi.Authenticated,
+		Body:    models.RegisterDeviceRequest{},
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"device": models.DeviceToken{}},
+	},
+	"UnregisterDevice": {
+		Tag:     tagAccount,
+		Summary: "Stop push notifications to a device",
+		Access:  openapi.Authenticated,
+	},
+
+	// Appointments
+	"CreateAppointment": {
+		Tag:        tagAppointments,
+		Summary:    "Book a slot",
+		Access:     openapi.Authenticated,
+		Body:       models.CreateAppointmentRequest{},
+		Status:     http.StatusCreated,
+		Data:       openapi.Fields{"appointment": models.Appointment{}},
+		Idempotent: true,
+	},
+	"CreateAppointmentLegacy": {
+		Tag:        tagAppointments,
+		Summary:    "Book a slot",
+		Access:     openapi.Authenticated,
+		Body:       models.CreateAppointmentRequest{},
+		Status:     http.StatusCreated,
+		Data:       bookedFields,
+		Idempotent: true,
+	},
+	"CreateAppointmentMobile": {
+		Tag:     tagAppointments,
+		Summary: "Book a slot",
+		Access:  openapi.Authenticated,
+		Body:    models.CreateAppointmentRequest{},
+		Status:  http.StatusCreated,
+		Data: openapi.Fields{
+			"appointment_id":   uuid.UUID{},
+			"appointment_date": "",
+			"appointment_time": "",
+			"slot":             "",
+			"appointment_type": "",
+			"duration_minutes": 0,
+			"start_time_utc":   models.Appointment{}.AppointmentDate,
+			"end_time_utc":     models.Appointment{}.EndsAt,
+			"start_time_local": "",
+			"timezone":         "",
+		},
+		Idempotent: true,
+	},
+	"ListAppointments": {
+		Tag:     tagAppointments,
+		Summary: "One page of the user's appointments",
+		Access:  openapi.Authenticated,
+		Query:   append(pageParams, cursorParam),
+		Data:    openapi.Fields{"appointments": []models.Appointment{}, "pagination": handlers.PaginationInfo{}},
+	},
+	"GetUserAppointments": {
+		Tag:     tagAppointments,
+		Summary: "The user's appointments",
+		Access:  openapi.Authenticated,
+		Query: []openapi.Param{
+			{Name: "limit", Type: "integer", Default: 10},
+			{Name: "offset", Type: "integer", Default: 0},
+		},
+		Data: openapi.Fields{"appointments": []models.Appointment{}},
+	},
+	"GetAppointment": {
+		Tag:         tagAppointments,
+		Summary:     "One appointment",
+		Description: "For its patient, its doctor and admins. An ID ending in .ics returns the appointment as a text/calendar event.",
+		Access:      openapi.Authenticated,
+		Data:        openapi.Fields{"appointment": models.Appointment{}},
+	},
+	"UpdateAppointment": {
+		Tag:         tagAppointments,
+		Summary:     "Reschedule an appointment or change its status",
+		Access:      openapi.Authenticated,
+		Body:        models.UpdateAppointmentRequest{},
+		Idempotent:  true,
+		Description: "Changing appointment_date or slot reschedules; status changes follow the appointment state machine.",
+	},
+	"CancelAppointment": {
+		Tag:        tagAppointments,
+		Summary:    "Cancel an appointment",
+		Access:     openapi.Authenticated,
+		Idempotent: true,
+	},
+	"HoldSlot": {
+		Tag:     tagAppointments,
+		Summary: "Hold a slot while booking it",
+		Access:  openapi.Authenticated,
+		Body:    models.HoldSlotRequest{},
+		Status:  http.StatusCreated,
+		Data: openapi.Fields{
+			"hold_token":       uuid.UUID{},
+			"doctor_id":        uuid.UUID{},
+			"appointment_date": "",
+			"slot":             "",
+			"appointment_type": "",
+			"start_time_utc":   models.SlotHold{}.StartsAt,
+			"expires_at":       models.SlotHold{}.ExpiresAt,
+			"ttl_seconds":      0,
+		},
+	},
+	"ReleaseSlotHold": {
+		Tag:     tagAppointments,
+		Summary: "Release a slot hold early",
+		Access:  openapi.Authenticated,
+	},
+
+	// Waitlist
+	"JoinWaitlist": {
+		Tag:     tagWaitlist,
+		Summary: "Join a doctor's waitlist",
+		Access:  openapi.Authenticated,
+		Body:    models.CreateWaitlistEntryRequest{},
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"entry": models.WaitlistEntry{}},
+	},
+	"GetWaitlist": {
+		Tag:     tagWaitlist,
+		Summary: "The user's waitlist entries and pending offers",
+		Access:  openapi.Authenticated,
+		Data:    openapi.Fields{"entries": []models.WaitlistEntry{}, "offers": []models.WaitlistOffer{}},
+	},
+	"LeaveWaitlist": {
+		Tag:     tagWaitlist,
+		Summary: "Leave the waitlist",
+		Access:  openapi.Authenticated,
+	},
+	"AcceptWaitlistOffer": {
+		Tag:        tagWaitlist,
+		Summary:    "Book the offered slot",
+		Access:     openapi.Authenticated,
+		Status:     http.StatusCreated,
+		Data:       bookedFields,
+		Idempotent: true,
+	},
+	"DeclineWaitlistOffer": {
+		Tag:     tagWaitlist,
+		Summary: "Decline an offer and stay on the waitlist",
+		Access:  openapi.Authenticated,
+	},
+
+	// Admin
+	"ListUsers": {
+		Tag:     tagAdmin,
+		Summary: "List users",
+		Access:  openapi.Admin,
+		Query:   append([]openapi.Param{{Name: "role", Enum: []string{models.RolePatient, models.RoleDoctor, models.RoleAdmin}}}, limitOffsetParams...),
+		Data:    openapi.Fields{"users": []models.User{}},
+	},
+	"CreateUser": {
+		Tag:     tagAdmin,
+		Summary: "Create a user ahead of their first sign-in",
+		Access:  openapi.Admin,
+		Body:    models.CreateUserRequest{},
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"user": models.User{}},
+	},
+	"GetUser": {
+		Tag:     tagAdmin,
+		Summary: "A user and their doctor profile, if any",
+		Access:  openapi.Admin,
+		Data:    openapi.Fields{"user": models.User{}, "doctor": models.Doctor{}},
+	},
+	"UpdateUserRole": {
+		Tag:     tagAdmin,
+		Summary: "Change a user's role",
+		Access:  openapi.Admin,
+		Body:    models.UpdateUserRoleRequest{},
+		Data:    openapi.Fields{"user": models.User{}},
+	},
+	"ListDoctorsAdmin": {
+		Tag:     tagAdmin,
+		Summary: "All doctors, including deactivated ones",
+		Access:  openapi.Admin,
+		Query:   append([]openapi.Param{{Name: "specialization"}}, limitOffsetParams...),
+		Data:    openapi.Fields{"doctors": []models.Doctor{}, "total": 0},
+	},
+	"CreateDoctor": {
+		Tag:     tagAdmin,
+		Summary: "Create the doctor profile of a user with the doctor role",
+		Access:  openapi.Admin,
+		Body:    models.CreateDoctorRequest{},
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"doctor": models.Doctor{}},
+	},
+	"UpdateDoctor": {
+		Tag:     tagAdmin,
+		Summary: "Change the fields of a doctor profile present in the request",
+		Access:  openapi.Admin,
+		Body:    models.UpdateDoctorRequest{},
+		Data:    openapi.Fields{"doctor": models.Doctor{}},
+	},
+	"DeactivateDoctor": {
+		Tag:     tagAdmin,
+		Summary: "Deactivate a doctor",
+		Access:  openapi.Admin,
+		Data:    openapi.Fields{"doctor": models.Doctor{}, "upcoming_appointments": 0},
+	},
+	"CreateHoliday": {
+		Tag:     tagAdmin,
+		Summary: "Add a hospital holiday",
+		Access:  openapi.Admin,
+		Body:    models.CreateHolidayRequest{},
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"holiday": models.Holiday{}, "flagged_appointments": []uuid.UUID{}},
+	},
+	"DeleteHoliday": {
+		Tag:     tagAdmin,
+		Summary: "Remove a hospital holiday",
+		Access:  openapi.Admin,
+	},
+	"ListAuditLog": {
+		Tag:     tagAdmin,
+		Summary: "Audit entries, newest first",
+		Access:  openapi.Admin,
+		Query: append([]openapi.Param{
+			{Name: "entity_type", Enum: []string{"user", "doctor"}},
+			{Name: "entity_id", Format: "uuid"},
+			{Name: "actor_id", Format: "uuid"},
+		}, limitOffsetParams...),
+		Data: openapi.Fields{"entries": []models.AuditEntry{}},
+	},
+	"ListReminders": {
+		Tag:     tagAdmin,
+		Summary: "Delivery state of appointment reminders",
+		Access:  openapi.Admin,
+		Query: append([]openapi.Param{
+			{Name: "status", Enum: []string{models.ReminderSending, models.ReminderSent, models.ReminderFailed, models.ReminderSkipped}},
+			{Name: "appointment_id", Format: "uuid"},
+		}, limitOffsetParams...),
+		Data: openapi.Fields{"reminders": []models.Reminder{}},
+	},
+
+	// Doctor
+	"GetDoctorProfile": {
+		Tag:     tagDoctorDesk,
+		Summary: "The authenticated doctor's profile",
+		Access:  openapi.Doctor,
+		Data:    openapi.Fields{"doctor": models.Doctor{}},
+	},
+	"GetDoctorAgenda": {
+		Tag:     tagDoctorDesk,
+		Summary: "The doctor's appointments on one date",
+		Access:  openapi.Doctor,
+		Query: []openapi.Param{
+			{Name: "date", Format: "date", Description: "Default today in the doctor's timezone"},
+			{Name: "include_cancelled", Type: "boolean", Default: false},
+		},
+		Data: openapi.Fields{
+			"doctor_id":    uuid.UUID{},
+			"date":         "",
+			"weekday":      "",
+			"timezone":     "",
+			"appointments": []models.Appointment{},
+		},
+	},
+	"GetDoctorWeekAgenda": {
+		Tag:     tagDoctorDesk,
+		Summary: "The doctor's appointments for seven days, by date",
+		Access:  openapi.Doctor,
+		Query: []openapi.Param{
+			{Name: "start", Format: "date", Description: "Default today in the doctor's timezone"},
+			{Name: "include_cancelled", Type: "boolean", Default: false},
+		},
+		Data: openapi.Fields{
+			"doctor_id": uuid.UUID{},
+			"from":      "",
+			"to":        "",
+			"timezone":  "",
+			"days":      []models.DayAgenda{},
+		},
+	},
+	"UpdateVisitStatus": {
+		Tag:        tagDoctorDesk,
+		Summary:    "Mark a visit completed or no-show",
+		Access:     openapi.Doctor,
+		Body:       models.UpdateAppointmentStatusRequest{},
+		Data:       openapi.Fields{"appointment": models.Appointment{}},
+		Idempotent: true,
+	},
+	"AddClinicalNotes": {
+		Tag:        tagDoctorDesk,
+		Summary:    "Save clinical notes on a visit",
+		Access:     openapi.Doctor,
+		Body:       models.ClinicalNotesRequest{},
+		Data:       openapi.Fields{"appointment": models.Appointment{}},
+		Idempotent: true,
+	},
+	"UpdateAvailableSlots": {
+		Tag:     tagDoctorDesk,
+		Summary: "Replace the weekly slot template",
+		Access:  openapi.Doctor,
+		Body:    models.UpdateAvailableSlotsRequest{},
+		Data:    openapi.Fields{"doctor": models.Doctor{}},
+	},
+	"UpdateWorkingHours": {
+		Tag:     tagDoctorDesk,
+		Summary: "Replace working hours and slot settings",
+		Access:  openapi.Doctor,
+		Body:    models.UpdateWorkingHoursRequest{},
+		Data:    openapi.Fields{"doctor": models.Doctor{}},
+	},
+	"ListScheduleExceptions": {
+		Tag:     tagDoctorDesk,
+		Summary: "The doctor's schedule exceptions",
+		Access:  openapi.Doctor,
+		Query:   dateRangeParams,
+		Data:    openapi.Fields{"exceptions": []models.ScheduleException{}},
+	},
+	"CreateScheduleException": {
+		Tag:     tagDoctorDesk,
+		Summary: "Block time or add extra slots on one date",
+		Access:  openapi.Doctor,
+		Body:    models.CreateScheduleExceptionRequest{},
+		Status:  http.StatusCreated,
+		Data:    openapi.Fields{"exception": models.ScheduleException{}, "flagged_appointments": []uuid.UUID{}},
+	},
+	"DeleteScheduleException": {
+		Tag:     tagDoctorDesk,
+		Summary: "Remove a schedule exception",
+		Access:  openapi.Doctor,
+	},
+}
+
+// buildSpec documents the served routes, failing when a route's handler is
+// missing from routeDocs
+func buildSpec(served gin.RoutesInfo) (*openapi.Document, error) {
+	codes := make([]string, len(apierror.Codes))
+	for i, code := range apierror.Codes {
+		codes[i] = string(code)
+	}
+
+	doc, missing := openapi.Build(served, routeDocs, openapi.Options{
+		Info: openapi.Info{
+			Title:       "Hospital API",
+			Description: "Doctors, appointments and schedules. Use the /api/v1 routes; the Legacy routes are deprecated.",
+			Version:     "1",
+		},
+		Tags: []openapi.Tag{
+			{Name: tagDoctors}, {Name: tagAppointments}, {Name: tagWaitlist}, {Name: tagAccount},
+			{Name: tagDoctorDesk, Description: "Routes for the authenticated doctor"},
+			{Name: tagAdmin}, {Name: tagSystem},
+		},
+		Envelope:  handlers.MobileResponse{},
+		Enveloped: middleware.EnvelopedPath,
+		Legacy: func(path string) bool {
+			return strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/api/v1/")
+		},
+		LegacyTag:  tagLegacy,
+		ErrorCodes: codes,
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("routes missing from routeDocs: %s", strings.Join(missing, ", "))
+	}
+	return doc, nil
+}
+
+// apiSpec serves the OpenAPI document of the router
+type apiSpec struct {
+	body []byte
+}
+
+// openAPISpec returns the OpenAPI document
+func (s *apiSpec) openAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", s.body)
+}
+
+// health reports that the server is up
+func health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"message": "Hospital API is running",
+	})
+}
+
+// swaggerUI serves Swagger UI on the OpenAPI document
+func swaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerPage))
+}
+
+// swaggerAssets serves the Swagger UI scripts and styles bundled with the
+// server
+func swaggerAssets(c *gin.Context) {
+	c.FileFromFS(c.Param("filepath"), swaggerFiles.HTTP)
+}
+
+// swaggerPage loads Swagger UI from /docs/assets and points it at
+// /openapi.json
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Hospital API</title>
+  <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/assets/swagger-ui-bundle.js"></script>
+  <script src="/docs/assets/swagger-ui-standalone-preset.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: "/openapi.json",
+      dom_id: "#swagger-ui",
+      presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+      layout: "StandaloneLayout",
+      persistAuthorization: true
+    });
+  </script>
+</body>
+</html>
+`
+
+// runOpenAPI implements the "openapi" subcommand, printing the OpenAPI
+// document. It exits with an error when a route is undocumented, so CI can
+// run it to keep routeDocs complete.
+func runOpenAPI() {
+	gin.SetMode(gin.ReleaseMode)
+
+	store := memory.NewStore()
+	h := handlers.New(store, handlers.Config{})
+	r, err := newRouter(store, h, time.Hour, middleware.Deprecation{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	doc, err := buildSpec(r.Routes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(out))
+}
@@ -0,0 +1,154 @@
+// Package apierror defines the errors the API returns to clients: an HTTP
+// status, a stable machine-readable code, a message for people and
+// optional per-field validation details. middleware.Errors renders them in
+// the envelope of the route.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code identifies a kind of error. Codes are part of the API: clients
+// branch on them, so existing codes must not change meaning.
+type Code string
+
+// Error codes
+const (
+	// CodeInvalidRequest: the body, a path or a query parameter is malformed
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	// CodeValidationFailed: some fields are invalid; see Fields
+	CodeValidationFailed Code = "VALIDATION_FAILED"
+	// CodeInvalidDate: a date or time could not be read or is out of range
+	CodeInvalidDate Code = "INVALID_DATE"
+	// CodeSlotUnavailable: the doctor does not work the requested slot;
+	// Details holds valid_slots when known
+	CodeSlotUnavailable Code = "SLOT_UNAVAILABLE"
+	// CodeInvalidHoldToken: hold_token is not a valid token
+	CodeInvalidHoldToken Code = "INVALID_HOLD_TOKEN"
+	// CodeHoldMismatch: the hold was issued for a different booking
+	CodeHoldMismatch Code = "HOLD_MISMATCH"
+	// CodeInvalidCursor: cursor is not a cursor of the listing
+	CodeInvalidCursor Code = "INVALID_CURSOR"
+
+	// CodeUnauthenticated: the request has no valid credentials
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	// CodeForbidden: the user's role may not use the route
+	CodeForbidden Code = "FORBIDDEN"
+
+	// CodeNotFound: the route or resource does not exist
+	CodeNotFound Code = "NOT_FOUND"
+	// CodeDoctorNotFound: the doctor does not exist or is deactivated
+	CodeDoctorNotFound Code = "DOCTOR_NOT_FOUND"
+	// CodeAppointmentNotFound: the appointment does not exist or is not the
+	// user's
+	CodeAppointmentNotFound Code = "APPOINTMENT_NOT_FOUND"
+	// CodeUserNotFound: the user does not exist
+	CodeUserNotFound Code = "USER_NOT_FOUND"
+
+	// CodeSlotTaken: another appointment overlaps the requested time
+	CodeSlotTaken Code = "SLOT_TAKEN"
+	// CodeSlotHeld: another patient holds the requested time
+	CodeSlotHeld Code = "SLOT_HELD"
+	// CodeHoldExpired: the slot hold or waitlist offer has expired or was
+	// used
+	CodeHoldExpired Code = "HOLD_EXPIRED"
+	// CodeConflict: the resource is not in a state that allows the request
+	CodeConflict Code = "CONFLICT"
+	// CodeRequestInProgress: a request with the same Idempotency-Key is
+	// still being handled
+	CodeRequestInProgress Code = "REQUEST_IN_PROGRESS"
+
+	// CodeInvalidTransition: the appointment status change is not allowed
+	CodeInvalidTransition Code = "INVALID_TRANSITION"
+	// CodeIdempotencyKeyReused: the Idempotency-Key was used for a
+	// different request
+	CodeIdempotencyKeyReused Code = "IDEMPOTENCY_KEY_REUSED"
+
+	// CodeInternal: the server failed; the request ID identifies the
+	// failure in the logs
+	CodeInternal Code = "INTERNAL_ERROR"
+)
+
+// Codes lists every error code, for documentation
+var Codes = []Code{
+	CodeInvalidRequest, CodeValidationFailed, CodeInvalidDate, CodeSlotUnavailable,
+	CodeInvalidHoldToken, CodeHoldMismatch, CodeInvalidCursor, CodeUnauthenticated, CodeForbidden,
+	CodeNotFound, CodeDoctorNotFound, CodeAppointmentNotFound, CodeUserNotFound,
+	CodeSlotTaken, CodeSlotHeld, CodeHoldExpired, CodeConflict, CodeRequestInProgress,
+	CodeInvalidTransition, CodeIdempotencyKeyReused, CodeInternal,
+}
+
+// Error is an error response
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	// Fields maps invalid request fields to what is wrong with them
+	Fields map[string]string
+	// Details holds extra values rendered with the error, such as
+	// valid_slots
+	Details map[string]interface{}
+	// Cause is logged for server errors and never shown to clients
+	Cause error
+}
+
+// New returns an error with status, code and message
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Invalid returns a CodeValidationFailed error for the given fields
+func Invalid(fields map[string]string) *Error {
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: "Invalid request data",
+		Fields:  fields,
+	}
+}
+
+// Internal returns a CodeInternal error with message, logging cause
+func Internal(message string, cause error) *Error {
+	return &Error{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: message,
+		Cause:   cause,
+	}
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithDetail returns a copy of e that also renders key with value
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	copied := *e
+	copied.Details = map[string]interface{}{key: value}
+	for k, v := range e.Details {
+		if k != key {
+			copied.Details[k] = v
+		}
+	}
+	return &copied
+}
+
+// From returns err as an *Error. Errors that are not one become an
+// internal error with err as the cause.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal("Internal server error", err)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"hospital-backend/database"
+	"hospital-backend/handlers"
+	"hospital-backend/models"
+	"hospital-backend/repository/postgres"
+
+	"github.com/google/uuid"
+)
+
+// testDatabase connects to the PostgreSQL database named by the lib/pq
+// connection string in TEST_DATABASE_URL and migrates it, skipping the test
+// when it is unset. Tests add rows under fresh IDs and leave them behind,
+// so the database should be a disposable one.
+func testDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	if _, err := database.MigrateUp(context.Background(), db); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// TestConcurrentBookingPostgres books one slot from many patients at once
+// and checks that the unique slot index lets exactly one booking through
+func TestConcurrentBookingPostgres(t *testing.T) {
+	const patients = 20
+
+	db := testDatabase(t)
+	api := newTestAPI(t, postgres.NewStore(db), handlers.Config{Location: time.UTC})
+	ctx := context.Background()
+
+	run := uuid.NewString()
+	var doctorUserID, doctorID uuid.UUID
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO users (firebase_uid, name, email, role)
+		VALUES ($1, 'Dr. Concurrency', $2, 'doctor')
+		RETURNING id
+	`, "concurrency-doctor-"+run, "concurrency-"+run+"@hospital.com").Scan(&doctorUserID)
+	if err != nil {
+		t.Fatalf("creating doctor user: %v", err)
+	}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO doctors (user_id, specialization, available_slots)
+		VALUES ($1, 'General Practice', '{"Monday": ["09:00"]}')
+		RETURNING id
+	`, doctorUserID).Scan(&doctorID)
+	if err != nil {
+		t.Fatalf("creating doctor: %v", err)
+	}
+	doctor, err := api.store.Doctors.Get(ctx, doctorID)
+	if err != nil {
+		t.Fatalf("loading doctor: %v", err)
+	}
+
+	// Provision every patient first, so only the bookings race
+	tokens := make([]string, patients)
+	for i := range tokens {
+		tokens[i] = api.token("concurrency-patient-"+run+"-"+strconv.Itoa(i), models.RolePatient)
+		if response := api.do(http.MethodGet, "/api/v1/me", tokens[i], nil); response.Status != http.StatusOK {
+			t.Fatalf("provisioning patient %d: status = %d (%s)", i, response.Status, response.Error)
+		}
+	}
+
+	monday := nextWeekday(time.Monday)
+	statuses := make([]int, patients)
+	errs := make([]error, patients)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			var response testResponse
+			response, errs[i] = api.send(http.MethodPost, "/api/v1/appointments", tokens[i], bookingRequest(doctor, monday, "09:00"))
+			statuses[i] = response.Status
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	created, taken := 0, 0
+	for i, status := range statuses {
+		switch {
+		case errs[i] != nil:
+			t.Errorf("booking %d: %v", i, errs[i])
+		case status == http.StatusCreated:
+			created++
+		case status == http.StatusConflict:
+			taken++
+		default:
+			t.Errorf("booking %d: status = %d, want 201 or 409", i, status)
+		}
+	}
+	if created != 1 || taken != patients-1 {
+		t.Errorf("got %d bookings created and %d conflicts, want 1 and %d", created, taken, patients-1)
+	}
+
+	var stored int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM appointments WHERE doctor_id = $1`, doctorID).Scan(&stored)
+	if err != nil {
+		t.Fatalf("counting appointments: %v", err)
+	}
+	if stored != 1 {
+		t.Errorf("stored %d appointments for the slot, want 1", stored)
+	}
+}
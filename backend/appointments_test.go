@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/handlers"
+	"hospital-backend/middleware"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+	"hospital-backend/repository/memory"
+	"hospital-backend/workers"
+
+	"github.com/google/uuid"
+)
+
+// Sample doctors of memory.NewSampleStore, both working on Mondays
+const (
+	sampleCardiologist  = "sample_firebase_uid_1"
+	sampleDermatologist = "sample_firebase_uid_2"
+)
+
+// bookedAppointment is the data of a booking response
+type bookedAppointment struct {
+	Appointment models.Appointment `json:"appointment"`
+}
+
+// book books slot on date with doctor for the user of token and returns
+// the response
+func (api *testAPI) book(token string, doctor *models.Doctor, date, slot string) testResponse {
+	api.t.Helper()
+	return api.do(http.MethodPost, "/api/v1/appointments", token, bookingRequest(doctor, date, slot))
+}
+
+// mustBook books like book and fails the test unless the booking is created
+func (api *testAPI) mustBook(token string, doctor *models.Doctor, date, slot string) models.Appointment {
+	api.t.Helper()
+	response := api.book(token, doctor, date, slot)
+	if response.Status != http.StatusCreated {
+		api.t.Fatalf("booking %s %s: status = %d (%s), want %d", date, slot, response.Status, response.Code, http.StatusCreated)
+	}
+	var booked bookedAppointment
+	response.decode(api.t, &booked)
+	return booked.Appointment
+}
+
+// appointment fetches the appointment with id as the user of token
+func (api *testAPI) appointment(token string, id uuid.UUID) models.Appointment {
+	api.t.Helper()
+	response := api.do(http.MethodGet, "/api/v1/appointments/"+id.String(), token, nil)
+	if response.Status != http.StatusOK {
+		api.t.Fatalf("fetching appointment %s: status = %d (%s)", id, response.Status, response.Code)
+	}
+	var fetched bookedAppointment
+	response.decode(api.t, &fetched)
+	return fetched.Appointment
+}
+
+// expectError fails the test unless response failed with status and code
+func expectError(t *testing.T, response testResponse, status int, code apierror.Code) {
+	t.Helper()
+	if response.Status != status || response.Code != string(code) {
+		t.Errorf("response = %d %s (%s), want %d %s", response.Status, response.Code, response.Error, status, code)
+	}
+}
+
+func TestBookAppointment(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	booked := api.mustBook(alice, doctor, monday, "09:00")
+	if booked.Status != models.StatusScheduled {
+		t.Errorf("status = %q, want %q", booked.Status, models.StatusScheduled)
+	}
+	if booked.DoctorID != doctor.ID || booked.Slot != "09:00" {
+		t.Errorf("booked doctor %s slot %s, want doctor %s slot 09:00", booked.DoctorID, booked.Slot, doctor.ID)
+	}
+	if got := booked.AppointmentDate.UTC().Format("2006-01-02 15:04"); got != monday+" 09:00" {
+		t.Errorf("appointment_date = %s, want %s 09:00", got, monday)
+	}
+
+	// Other patients don't see the appointment
+	bob := api.token("bob", models.RolePatient)
+	response := api.do(http.MethodDelete, "/api/v1/appointments/"+booked.ID.String(), bob, nil)
+	expectError(t, response, http.StatusNotFound, apierror.CodeAppointmentNotFound)
+}
+
+func TestBookTakenSlot(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	cardiologist := sampleDoctor(t, store, sampleCardiologist)
+	dermatologist := sampleDoctor(t, store, sampleDermatologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	api.mustBook(alice, cardiologist, monday, "10:00")
+	expectError(t, api.book(bob, cardiologist, monday, "10:00"), http.StatusConflict, apierror.CodeSlotTaken)
+
+	// The same time with another doctor is free
+	api.mustBook(bob, dermatologist, monday, "10:00")
+}
+
+// TestConcurrentBooking books one slot from many patients at once and
+// checks that exactly one booking gets through
+func TestConcurrentBooking(t *testing.T) {
+	const patients = 20
+
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	monday := nextWeekday(time.Monday)
+
+	statuses := make([]int, patients)
+	errs := make([]error, patients)
+	tokens := make([]string, patients)
+	for i := range tokens {
+		tokens[i] = api.token("patient-"+uuid.NewString(), models.RolePatient)
+	}
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			var response testResponse
+			response, errs[i] = api.send(http.MethodPost, "/api/v1/appointments", tokens[i], bookingRequest(doctor, monday, "09:00"))
+			statuses[i] = response.Status
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	created, taken := 0, 0
+	for i, status := range statuses {
+		switch {
+		case errs[i] != nil:
+			t.Errorf("booking %d: %v", i, errs[i])
+		case status == http.StatusCreated:
+			created++
+		case status == http.StatusConflict:
+			taken++
+		default:
+			t.Errorf("booking %d: status = %d, want 201 or 409", i, status)
+		}
+	}
+	if created != 1 || taken != patients-1 {
+		t.Errorf("got %d bookings created and %d conflicts, want 1 and %d", created, taken, patients-1)
+	}
+}
+
+func TestBookUnavailableSlot(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+
+	// The cardiologist works Mondays 09:00-11:00 and not at all on Thursdays
+	expectError(t, api.book(alice, doctor, nextWeekday(time.Monday), "12:00"), http.StatusBadRequest, apierror.CodeSlotUnavailable)
+	expectError(t, api.book(alice, doctor, nextWeekday(time.Thursday), "09:00"), http.StatusBadRequest, apierror.CodeSlotUnavailable)
+
+	// Slots that already started can't be booked
+	lastMonday := mustAddDays(t, nextWeekday(time.Monday), -7)
+	expectError(t, api.book(alice, doctor, lastMonday, "09:00"), http.StatusBadRequest, apierror.CodeSlotUnavailable)
+
+	response := api.do(http.MethodPost, "/api/v1/appointments", alice, models.CreateAppointmentRequest{
+		DoctorID: doctor.ID, AppointmentDate: "19-10-2026", Slot: "09:00",
+	})
+	expectError(t, response, http.StatusBadRequest, apierror.CodeInvalidDate)
+}
+
+func TestAppointmentStatusTransitions(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	drSmith := api.token(sampleCardiologist, models.RoleDoctor)
+	monday := nextWeekday(time.Monday)
+
+	visit := api.mustBook(alice, doctor, monday, "09:00")
+	path := "/api/v1/appointments/" + visit.ID.String()
+
+	// Patients can't complete their own visit
+	response := api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{Status: models.StatusCompleted})
+	expectError(t, response, http.StatusUnprocessableEntity, apierror.CodeInvalidTransition)
+
+	response = api.do(http.MethodPut, path, drSmith, models.UpdateAppointmentRequest{Status: models.StatusCompleted})
+	if response.Status != http.StatusOK {
+		t.Fatalf("completing visit: status = %d (%s)", response.Status, response.Error)
+	}
+	if status := api.appointment(alice, visit.ID).Status; status != models.StatusCompleted {
+		t.Errorf("status = %q, want %q", status, models.StatusCompleted)
+	}
+
+	// Completed is final
+	response = api.do(http.MethodDelete, path, alice, nil)
+	expectError(t, response, http.StatusUnprocessableEntity, apierror.CodeInvalidTransition)
+	response = api.do(http.MethodPut, path, drSmith, models.UpdateAppointmentRequest{Status: models.StatusNoShow})
+	expectError(t, response, http.StatusUnprocessableEntity, apierror.CodeInvalidTransition)
+
+	// Cancelling frees the slot
+	cancelled := api.mustBook(alice, doctor, monday, "10:00")
+	response = api.do(http.MethodDelete, "/api/v1/appointments/"+cancelled.ID.String(), alice, nil)
+	if response.Status != http.StatusOK {
+		t.Fatalf("cancelling: status = %d (%s)", response.Status, response.Error)
+	}
+	if status := api.appointment(alice, cancelled.ID).Status; status != models.StatusCancelled {
+		t.Errorf("status = %q, want %q", status, models.StatusCancelled)
+	}
+	api.mustBook(api.token("bob", models.RolePatient), doctor, monday, "10:00")
+}
+
+func TestRescheduleAppointment(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	moved := api.mustBook(alice, doctor, monday, "09:00")
+	api.mustBook(bob, doctor, monday, "11:00")
+	path := "/api/v1/appointments/" + moved.ID.String()
+
+	// A reschedule needs a new time, on a free slot the doctor works
+	response := api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{Status: models.StatusRescheduled})
+	expectError(t, response, http.StatusUnprocessableEntity, apierror.CodeInvalidTransition)
+	response = api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{Slot: "11:00"})
+	expectError(t, response, http.StatusConflict, apierror.CodeSlotTaken)
+	response = api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{Slot: "13:00"})
+	expectError(t, response, http.StatusBadRequest, apierror.CodeSlotUnavailable)
+
+	nextMonday := mustAddDays(t, monday, 7)
+	response = api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{
+		AppointmentDate: nextMonday, Slot: "10:00",
+	})
+	if response.Status != http.StatusOK {
+		t.Fatalf("rescheduling: status = %d (%s)", response.Status, response.Error)
+	}
+
+	rescheduled := api.appointment(alice, moved.ID)
+	if rescheduled.Status != models.StatusRescheduled {
+		t.Errorf("status = %q, want %q", rescheduled.Status, models.StatusRescheduled)
+	}
+	if got := rescheduled.AppointmentDate.UTC().Format("2006-01-02 15:04"); got != nextMonday+" 10:00" {
+		t.Errorf("appointment_date = %s, want %s 10:00", got, nextMonday)
+	}
+
+	// The old slot is free again, and the moved appointment can move again
+	api.mustBook(bob, doctor, monday, "09:00")
+	response = api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{Slot: "11:00"})
+	if response.Status != http.StatusOK {
+		t.Errorf("rescheduling again: status = %d (%s)", response.Status, response.Error)
+	}
+}
+
+// mustAddDays returns the YYYY-MM-DD date days after date
+func mustAddDays(t *testing.T, date string, days int) string {
+	t.Helper()
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", date, err)
+	}
+	return day.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// hold holds slot on date with doctor for the user of token and returns
+// the response
+func (api *testAPI) hold(token string, doctor *models.Doctor, date, slot string) testResponse {
+	api.t.Helper()
+	return api.do(http.MethodPost, "/api/v1/slots/hold", token, models.HoldSlotRequest{
+		DoctorID: doctor.ID, AppointmentDate: date, Slot: slot,
+	})
+}
+
+// mustHold holds like hold and returns the hold token, failing the test
+// unless the hold is created
+func (api *testAPI) mustHold(token string, doctor *models.Doctor, date, slot string) string {
+	api.t.Helper()
+	response := api.hold(token, doctor, date, slot)
+	if response.Status != http.StatusCreated {
+		api.t.Fatalf("holding %s %s: status = %d (%s), want %d", date, slot, response.Status, response.Error, http.StatusCreated)
+	}
+	var held struct {
+		HoldToken string `json:"hold_token"`
+	}
+	response.decode(api.t, &held)
+	return held.HoldToken
+}
+
+// freeSlots returns the doctor's free slots on date, as seen by the holder
+// of holdToken when it is not empty
+func (api *testAPI) freeSlots(doctor *models.Doctor, date, holdToken string) []string {
+	api.t.Helper()
+	path := "/api/v1/doctors/" + doctor.ID.String() + "/availability?from=" + date + "&to=" + date
+	if holdToken != "" {
+		path += "&hold_token=" + holdToken
+	}
+	response := api.do(http.MethodGet, path, "", nil)
+	if response.Status != http.StatusOK {
+		api.t.Fatalf("fetching availability: status = %d (%s)", response.Status, response.Error)
+	}
+	var body struct {
+		Availability []models.DayAvailability `json:"availability"`
+	}
+	response.decode(api.t, &body)
+	if len(body.Availability) != 1 {
+		api.t.Fatalf("availability has %d days, want 1", len(body.Availability))
+	}
+	return body.Availability[0].Slots
+}
+
+func TestSlotHold(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	token := api.mustHold(alice, doctor, monday, "09:00")
+
+	// The held slot is busy for everyone but the holder
+	if got, want := api.freeSlots(doctor, monday, ""), []string{"10:00", "11:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots = %v, want %v", got, want)
+	}
+	if got, want := api.freeSlots(doctor, monday, token), []string{"09:00", "10:00", "11:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots for the holder = %v, want %v", got, want)
+	}
+	expectError(t, api.book(bob, doctor, monday, "09:00"), http.StatusConflict, apierror.CodeSlotHeld)
+	expectError(t, api.hold(bob, doctor, monday, "09:00"), http.StatusConflict, apierror.CodeSlotHeld)
+
+	// Booking with the token consumes the hold
+	request := bookingRequest(doctor, monday, "09:00")
+	request.HoldToken = token
+	response := api.do(http.MethodPost, "/api/v1/appointments", alice, request)
+	if response.Status != http.StatusCreated {
+		t.Fatalf("booking held slot: status = %d (%s)", response.Status, response.Error)
+	}
+	response = api.do(http.MethodDelete, "/api/v1/slots/hold/"+token, alice, nil)
+	expectError(t, response, http.StatusNotFound, apierror.CodeNotFound)
+
+	request.Slot = "10:00"
+	response = api.do(http.MethodPost, "/api/v1/appointments", alice, request)
+	expectError(t, response, http.StatusConflict, apierror.CodeHoldExpired)
+}
+
+// TestSlotHoldReplacement checks that a new hold replaces the patient's
+// previous hold with the doctor, but only once it succeeds
+func TestSlotHoldReplacement(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	api.mustHold(alice, doctor, monday, "09:00")
+	api.mustBook(bob, doctor, monday, "10:00")
+
+	// A failed hold leaves the previous one in place
+	expectError(t, api.hold(alice, doctor, monday, "10:00"), http.StatusConflict, apierror.CodeSlotTaken)
+	if got, want := api.freeSlots(doctor, monday, ""), []string{"11:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots after a failed hold = %v, want %v", got, want)
+	}
+
+	api.mustHold(alice, doctor, monday, "11:00")
+	if got, want := api.freeSlots(doctor, monday, ""), []string{"09:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots after a new hold = %v, want %v", got, want)
+	}
+}
+
+func TestSlotHoldExpiry(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{HoldTTL: ttl})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	token := api.mustHold(alice, doctor, monday, "09:00")
+	expectError(t, api.book(bob, doctor, monday, "09:00"), http.StatusConflict, apierror.CodeSlotHeld)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go workers.SweepHolds(ctx, store.Holds, ttl/5)
+	time.Sleep(2 * ttl)
+
+	// The sweeper already removed the expired hold
+	if count, err := store.Holds.DeleteExpired(ctx, time.Now()); err != nil || count != 0 {
+		t.Errorf("DeleteExpired after the sweep = %d, %v, want 0", count, err)
+	}
+
+	request := bookingRequest(doctor, monday, "09:00")
+	request.HoldToken = token
+	response := api.do(http.MethodPost, "/api/v1/appointments", alice, request)
+	expectError(t, response, http.StatusConflict, apierror.CodeHoldExpired)
+	api.mustBook(bob, doctor, monday, "09:00")
+}
+
+// TestIdempotentBooking checks that a booking retried with its
+// Idempotency-Key is answered from the first response without booking
+// again
+func TestIdempotentBooking(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+
+	body, err := json.Marshal(bookingRequest(doctor, nextWeekday(time.Monday), "09:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/appointments", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+alice)
+		req.Header.Set(middleware.IdempotencyKeyHeader, "booking-1")
+		w := httptest.NewRecorder()
+		api.router.ServeHTTP(w, req)
+		return w
+	}
+
+	first, retry := post(), post()
+	if first.Code != http.StatusCreated {
+		t.Fatalf("booking: status = %d (%s)", first.Code, first.Body)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want the first response", retry.Code, retry.Body)
+	}
+
+	count, err := store.Appointments.Count(context.Background(), repository.AppointmentFilter{DoctorID: doctor.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d appointments booked, want 1", count)
+	}
+}
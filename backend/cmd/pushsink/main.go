@@ -0,0 +1,34 @@
+// Command pushsink is a local stand-in for the push service. It records
+// the notifications the server sends with PUSH_PROVIDER=http and lists
+// them for inspection.
+//
+//	go run ./cmd/pushsink -addr :8090 -stale bad-token
+//	PUSH_PROVIDER=http PUSH_HTTP_URL=http://localhost:8090/send go run .
+//	curl localhost:8090/send
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"hospital-backend/push"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "address to listen on")
+	stale := flag.String("stale", "", "comma-separated tokens to report as no longer registered")
+	flag.Parse()
+
+	fake := &push.Fake{Stale: map[string]bool{}}
+	for _, token := range strings.Split(*stale, ",") {
+		if token != "" {
+			fake.Stale[token] = true
+		}
+	}
+
+	http.Handle("/send", fake)
+	log.Printf("Push sink listening on %s; POST /send to record, GET /send to list, DELETE /send to clear", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -1,16 +1,40 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var DB *sql.DB
 
+// InitDB connects to the database and, when DB_AUTO_MIGRATE is "true",
+// applies pending migrations
 func InitDB() error {
+	if err := Connect(); err != nil {
+		return err
+	}
+
+	if os.Getenv("DB_AUTO_MIGRATE") != "true" {
+		return nil
+	}
+
+	applied, err := MigrateUp(context.Background(), DB)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+// Connect opens and pings the database connection
+func Connect() error {
 	// Database connection parameters
 	host := getEnv("DB_HOST", "localhost")
 	port := getEnv("DB_PORT", "5432")
@@ -45,4 +69,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// SQLSTATE codes of the constraint violations callers translate
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	exclusionViolation  = "23P01"
+)
+
+// IsUniqueViolation reports whether err was caused by the unique index or
+// constraint named constraint. An empty constraint matches any of them.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return false
+	}
+	return constraint == "" || pqErr.Constraint == constraint
+}
+
+// IsForeignKeyViolation reports whether err was caused by a foreign key
+// constraint
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
 
+// IsExclusionViolation reports whether err was caused by the exclusion
+// constraint named constraint
+func IsExclusionViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == exclusionViolation && pqErr.Constraint == constraint
+}
@@ -0,0 +1,273 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey identifies the advisory lock that serializes migration
+// runs across server instances
+const migrationLockKey = 7326400112
+
+// Migration is a numbered schema change with its up and down SQL
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState reports whether a migration has been applied
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations returns the embedded migrations ordered by version. Files
+// are named NNNN_name.up.sql and NNNN_name.down.sql.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := splitMigrationName(fileName)
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		versionText, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q", fileName)
+		}
+
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, name)
+		}
+
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func splitMigrationName(fileName string) (string, string, bool) {
+	for _, direction := range []string{"up", "down"} {
+		suffix := "." + direction + ".sql"
+		if strings.HasSuffix(fileName, suffix) {
+			return strings.TrimSuffix(fileName, suffix), direction, true
+		}
+	}
+	return "", "", false
+}
+
+// MigrateUp applies all pending migrations in order and returns the ones it
+// applied. Each migration runs in its own transaction.
+func MigrateUp(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	var applied []Migration
+	err := withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		migrations, err := LoadMigrations()
+		if err != nil {
+			return err
+		}
+
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			err := runMigration(ctx, conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+				migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations and returns the
+// ones it reverted
+func MigrateDown(ctx context.Context, db *sql.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		migrations, err := LoadMigrations()
+		if err != nil {
+			return err
+		}
+
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			err := runMigration(ctx, conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1",
+				migration.Version)
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// MigrationStatus lists every known migration and when it was applied
+func MigrationStatus(ctx context.Context, db *sql.DB) ([]MigrationState, error) {
+	var states []MigrationState
+	err := withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		migrations, err := LoadMigrations()
+		if err != nil {
+			return err
+		}
+
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			state := MigrationState{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				state.AppliedAt = &appliedAt
+			}
+			states = append(states, state)
+		}
+		return nil
+	})
+
+	return states, err
+}
+
+// withMigrationLock runs fn on a single connection holding the migration
+// advisory lock, creating schema_migrations first if needed. The
+// connection's app.hospital_timezone setting holds HOSPITAL_TIMEZONE.
+func withMigrationLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	// Migrations that convert local times read the hospital timezone
+	_, err = conn.ExecContext(ctx, "SELECT set_config('app.hospital_timezone', $1, false)",
+		getEnv("HOSPITAL_TIMEZONE", "UTC"))
+	if err != nil {
+		return fmt.Errorf("failed to set hospital timezone: %w", err)
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// runMigration executes a migration script and its bookkeeping statement in
+// one transaction
+func runMigration(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,4 +1,5 @@
--- Initialize database schema
+-- Initial schema, as previously created by init.sql. Statements are
+-- idempotent so databases initialized from init.sql can adopt migrations.
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 -- Users table
@@ -43,17 +44,3 @@ CREATE INDEX IF NOT EXISTS idx_doctors_user_id ON doctors(user_id);
 CREATE INDEX IF NOT EXISTS idx_appointments_doctor_id ON appointments(doctor_id);
 CREATE INDEX IF NOT EXISTS idx_appointments_patient_id ON appointments(patient_id);
 CREATE INDEX IF NOT EXISTS idx_appointments_date ON appointments(appointment_date);
-
--- Insert sample data
-INSERT INTO users (firebase_uid, name, email, role) VALUES 
-('sample_firebase_uid_1', 'Dr. John Smith', 'john.smith@hospital.com', 'doctor'),
-('sample_firebase_uid_2', 'Dr. Sarah Johnson', 'sarah.johnson@hospital.com', 'doctor'),
-('sample_firebase_uid_3', 'Patient User', 'patient@example.com', 'patient')
-ON CONFLICT (firebase_uid) DO NOTHING;
-
-INSERT INTO doctors (user_id, specialization, experience, phone, available_slots) VALUES 
-((SELECT id FROM users WHERE firebase_uid = 'sample_firebase_uid_1'), 'Cardiologist', 10, '+1234567890', '{"Monday": ["09:00", "10:00", "11:00"], "Tuesday": ["09:00", "10:00"], "Wednesday": ["14:00", "15:00"]}'),
-((SELECT id FROM users WHERE firebase_uid = 'sample_firebase_uid_2'), 'Dermatologist', 8, '+1234567891', '{"Monday": ["10:00", "11:00"], "Thursday": ["09:00", "10:00"], "Friday": ["14:00", "15:00"]}')
-ON CONFLICT DO NOTHING;
-
-
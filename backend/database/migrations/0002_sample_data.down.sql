@@ -0,0 +1,3 @@
+-- Doctors and appointments of the sample users are removed by ON DELETE CASCADE
+DELETE FROM users
+WHERE firebase_uid IN ('sample_firebase_uid_1', 'sample_firebase_uid_2', 'sample_firebase_uid_3');
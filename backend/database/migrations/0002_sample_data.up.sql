@@ -0,0 +1,15 @@
+-- Insert sample data
+INSERT INTO users (firebase_uid, name, email, role) VALUES 
+('sample_firebase_uid_1', 'Dr. John Smith', 'john.smith@hospital.com', 'doctor'),
+('sample_firebase_uid_2', 'Dr. Sarah Johnson', 'sarah.johnson@hospital.com', 'doctor'),
+('sample_firebase_uid_3', 'Patient User', 'patient@example.com', 'patient')
+ON CONFLICT (firebase_uid) DO NOTHING;
+
+INSERT INTO doctors (user_id, specialization, experience, phone, available_slots)
+SELECT u.id, v.specialization, v.experience, v.phone, v.available_slots::jsonb
+FROM (VALUES
+    ('sample_firebase_uid_1', 'Cardiologist', 10, '+1234567890', '{"Monday": ["09:00", "10:00", "11:00"], "Tuesday": ["09:00", "10:00"], "Wednesday": ["14:00", "15:00"]}'),
+    ('sample_firebase_uid_2', 'Dermatologist', 8, '+1234567891', '{"Monday": ["10:00", "11:00"], "Thursday": ["09:00", "10:00"], "Friday": ["14:00", "15:00"]}')
+) AS v(firebase_uid, specialization, experience, phone, available_slots)
+JOIN users u ON u.firebase_uid = v.firebase_uid
+WHERE NOT EXISTS (SELECT 1 FROM doctors d WHERE d.user_id = u.id);
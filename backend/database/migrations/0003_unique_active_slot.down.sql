@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_appointments_active_slot;
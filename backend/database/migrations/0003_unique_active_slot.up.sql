@@ -0,0 +1,5 @@
+-- At most one active booking per doctor, date and slot. Booking relies on this
+-- index instead of a check-then-insert, so concurrent requests cannot double-book.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_active_slot
+    ON appointments(doctor_id, appointment_date, slot)
+    WHERE status != 'cancelled';
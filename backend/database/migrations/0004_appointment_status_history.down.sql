@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS appointment_status_history;
+
+-- no_show appointments may still exist, so the old constraint only applies to new rows
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS appointments_status_check;
+ALTER TABLE appointments ADD CONSTRAINT appointments_status_check
+    CHECK (status IN ('scheduled', 'completed', 'cancelled', 'rescheduled')) NOT VALID;
@@ -0,0 +1,3 @@
+-- Notes written by the doctor during or after the visit, kept apart from the
+-- patient's booking notes
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS clinical_notes TEXT;
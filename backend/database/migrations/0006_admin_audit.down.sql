@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_log;
+DROP INDEX IF EXISTS idx_doctors_user_id_unique;
+ALTER TABLE doctors DROP COLUMN IF EXISTS active;
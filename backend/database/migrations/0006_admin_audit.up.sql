@@ -0,0 +1,20 @@
+-- Deactivated doctors keep their profile and appointments but are hidden
+-- from listings and cannot be booked
+ALTER TABLE doctors ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- One doctor profile per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_doctors_user_id_unique ON doctors(user_id);
+
+-- Audit trail of administrative changes to users and doctors
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE appointments DROP COLUMN IF EXISTS reschedule_required;
+DROP TABLE IF EXISTS holidays;
+DROP TABLE IF EXISTS schedule_exceptions;
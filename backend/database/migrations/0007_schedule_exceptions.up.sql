@@ -0,0 +1,28 @@
+-- One-off changes to a doctor's weekly slots: blocks remove slots (the whole
+-- day when start_time and end_time are NULL), extras add slots
+CREATE TABLE IF NOT EXISTS schedule_exceptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    exception_date DATE NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('block', 'extra')),
+    start_time TIME,
+    end_time TIME,
+    slots JSONB NOT NULL DEFAULT '[]',
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK ((start_time IS NULL) = (end_time IS NULL)),
+    CHECK (start_time IS NULL OR start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_exceptions_doctor_date ON schedule_exceptions(doctor_id, exception_date);
+
+-- Hospital-wide holidays, on which no weekly slots are offered
+CREATE TABLE IF NOT EXISTS holidays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    holiday_date DATE NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Set on appointments whose slot was removed by leave or a holiday
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS reschedule_required BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,30 @@
+-- Back to the booked date at UTC midnight
+UPDATE appointments a
+SET appointment_date = ((a.appointment_date
+    AT TIME ZONE COALESCE(d.timezone, NULLIF(current_setting('app.hospital_timezone', true), ''), 'UTC'))::date)::timestamp
+    AT TIME ZONE 'UTC'
+FROM doctors d
+WHERE a.doctor_id = d.id;
+
+ALTER TABLE appointments
+    ALTER COLUMN appointment_date TYPE TIMESTAMP USING appointment_date AT TIME ZONE 'UTC';
+
+ALTER TABLE holidays
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE schedule_exceptions
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE audit_log
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE appointment_status_history
+    ALTER COLUMN changed_at TYPE TIMESTAMP USING changed_at AT TIME ZONE 'UTC';
+ALTER TABLE appointments
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE doctors
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE doctors DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,33 @@
+-- Doctors may override the hospital timezone (HOSPITAL_TIMEZONE)
+ALTER TABLE doctors ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+
+-- Timestamps written by NOW() were stored in a UTC session
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE doctors
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE appointments
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE appointment_status_history
+    ALTER COLUMN changed_at TYPE TIMESTAMPTZ USING changed_at AT TIME ZONE 'UTC';
+ALTER TABLE audit_log
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE schedule_exceptions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE holidays
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+-- appointment_date held the booked date at UTC midnight. It now holds the
+-- instant the slot starts, with the slot read in the doctor's timezone.
+-- The migration runner sets app.hospital_timezone from HOSPITAL_TIMEZONE.
+ALTER TABLE appointments
+    ALTER COLUMN appointment_date TYPE TIMESTAMPTZ USING appointment_date AT TIME ZONE 'UTC';
+
+UPDATE appointments a
+SET appointment_date = ((a.appointment_date AT TIME ZONE 'UTC')::date + a.slot::time)
+    AT TIME ZONE COALESCE(d.timezone, NULLIF(current_setting('app.hospital_timezone', true), ''), 'UTC')
+FROM doctors d
+WHERE a.doctor_id = d.id AND a.slot ~ '^[0-9]{2}:[0-9]{2}$';
@@ -0,0 +1,14 @@
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS appointments_no_overlap;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_active_slot
+    ON appointments(doctor_id, appointment_date, slot)
+    WHERE status != 'cancelled';
+
+ALTER TABLE appointments
+    DROP COLUMN IF EXISTS occupied_until,
+    DROP COLUMN IF EXISTS duration_minutes,
+    DROP COLUMN IF EXISTS appointment_type;
+
+ALTER TABLE doctors
+    DROP COLUMN IF EXISTS buffer_minutes,
+    DROP COLUMN IF EXISTS slot_duration,
+    DROP COLUMN IF EXISTS working_hours;
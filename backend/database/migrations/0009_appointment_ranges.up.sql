@@ -0,0 +1,32 @@
+-- btree_gist lets the exclusion constraint below compare doctor_id with =
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Slot settings: working_hours, when set, generates available_slots
+ALTER TABLE doctors
+    ADD COLUMN IF NOT EXISTS working_hours JSONB,
+    ADD COLUMN IF NOT EXISTS slot_duration INTEGER NOT NULL DEFAULT 15
+        CHECK (slot_duration BETWEEN 5 AND 240),
+    ADD COLUMN IF NOT EXISTS buffer_minutes INTEGER NOT NULL DEFAULT 0
+        CHECK (buffer_minutes BETWEEN 0 AND 120);
+
+-- Each appointment occupies its doctor from appointment_date until
+-- occupied_until: its duration plus the doctor's buffer
+ALTER TABLE appointments
+    ADD COLUMN IF NOT EXISTS appointment_type VARCHAR(20) NOT NULL DEFAULT 'consultation'
+        CHECK (appointment_type IN ('consultation', 'follow_up', 'procedure')),
+    ADD COLUMN IF NOT EXISTS duration_minutes INTEGER NOT NULL DEFAULT 15,
+    ADD COLUMN IF NOT EXISTS occupied_until TIMESTAMPTZ;
+
+UPDATE appointments a
+SET occupied_until = a.appointment_date + make_interval(mins => a.duration_minutes + d.buffer_minutes)
+FROM doctors d
+WHERE a.doctor_id = d.id AND a.occupied_until IS NULL;
+
+ALTER TABLE appointments ALTER COLUMN occupied_until SET NOT NULL;
+
+-- Active appointments of a doctor may not overlap. This replaces the
+-- one-booking-per-slot index, which only caught identical start times.
+DROP INDEX IF EXISTS idx_appointments_active_slot;
+ALTER TABLE appointments ADD CONSTRAINT appointments_no_overlap
+    EXCLUDE USING gist (doctor_id WITH =, tstzrange(appointment_date, occupied_until) WITH &&)
+    WHERE (status != 'cancelled');
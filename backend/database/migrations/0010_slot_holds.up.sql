@@ -0,0 +1,18 @@
+-- Temporary reservations of a doctor's time while a patient completes a
+-- booking. Rows past expires_at are ignored and swept by the server.
+CREATE TABLE IF NOT EXISTS slot_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    occupied_until TIMESTAMPTZ NOT NULL,
+    slot VARCHAR(50) NOT NULL,
+    appointment_type VARCHAR(20) NOT NULL
+        CHECK (appointment_type IN ('consultation', 'follow_up', 'procedure')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (starts_at < occupied_until)
+);
+
+CREATE INDEX IF NOT EXISTS idx_slot_holds_doctor_start ON slot_holds(doctor_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_expires ON slot_holds(expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE slot_holds DROP COLUMN IF EXISTS kind;
+DROP TABLE IF EXISTS waitlist_offers;
+DROP TABLE IF EXISTS waitlist_entries;
@@ -0,0 +1,47 @@
+-- Patients waiting for a slot of a doctor between two dates. An empty
+-- preferred_slots accepts any slot.
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    patient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    from_date DATE NOT NULL,
+    to_date DATE NOT NULL,
+    preferred_slots JSONB NOT NULL DEFAULT '[]',
+    appointment_type VARCHAR(20) NOT NULL DEFAULT 'consultation'
+        CHECK (appointment_type IN ('consultation', 'follow_up', 'procedure')),
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'offered', 'booked', 'cancelled')),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (from_date <= to_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_doctor_status ON waitlist_entries(doctor_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_patient ON waitlist_entries(patient_id);
+
+-- Freed slots offered to waitlisted patients until expires_at. hold_id is
+-- the slot hold reserving the slot; holds are swept, so it is not a
+-- foreign key.
+CREATE TABLE IF NOT EXISTS waitlist_offers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entry_id UUID NOT NULL REFERENCES waitlist_entries(id) ON DELETE CASCADE,
+    hold_id UUID NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    slot VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'declined', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    responded_at TIMESTAMPTZ
+);
+
+-- An entry has at most one pending offer
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_offers_pending_entry
+    ON waitlist_offers(entry_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_waitlist_offers_pending_expiry
+    ON waitlist_offers(expires_at) WHERE status = 'pending';
+
+-- Offer holds reserve the slot of a waitlist offer; unlike checkout holds,
+-- a patient's new hold on the doctor does not replace them
+ALTER TABLE slot_holds ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'checkout'
+    CHECK (kind IN ('checkout', 'offer'));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS appointment_reminders;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,21 @@
+-- Phone number for SMS notifications, set by the user
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(20);
+
+-- Delivery state of appointment reminders, one row per appointment start
+-- and offset. A row is claimed as 'sending' before delivery, so reminders
+-- are sent at most once even if the server restarts mid-delivery.
+CREATE TABLE IF NOT EXISTS appointment_reminders (
+    appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    offset_minutes INTEGER NOT NULL CHECK (offset_minutes > 0),
+    status VARCHAR(20) NOT NULL
+        CHECK (status IN ('sending', 'sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (appointment_id, starts_at, offset_minutes)
+);
+
+CREATE INDEX IF NOT EXISTS idx_appointment_reminders_status ON appointment_reminders(status, updated_at);
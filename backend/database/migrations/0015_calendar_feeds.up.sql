@@ -0,0 +1,3 @@
+-- SHA-256 of the secret token in a user's calendar feed URL. The token
+-- itself is only shown to the user when it is created.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token_hash CHAR(64) UNIQUE;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_name;
+DROP INDEX IF EXISTS idx_appointments_patient_date_id;
@@ -0,0 +1,4 @@
+-- Keyset paging reads a patient's appointments from an
+-- (appointment_date, id) key and doctors from a (name, id) key
+CREATE INDEX IF NOT EXISTS idx_appointments_patient_date_id ON appointments(patient_id, appointment_date, id);
+CREATE INDEX IF NOT EXISTS idx_users_name ON users(name);
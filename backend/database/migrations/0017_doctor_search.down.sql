@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS users_refresh_doctor_search ON users;
+DROP TRIGGER IF EXISTS doctors_refresh_search ON doctors;
+DROP FUNCTION IF EXISTS users_refresh_doctor_search();
+DROP FUNCTION IF EXISTS doctors_refresh_search();
+
+DROP INDEX IF EXISTS idx_doctors_search_text_trgm;
+DROP INDEX IF EXISTS idx_doctors_search_vector;
+
+ALTER TABLE doctors
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS search_text,
+    DROP COLUMN IF EXISTS clinic,
+    DROP COLUMN IF EXISTS languages;
+
+DROP FUNCTION IF EXISTS doctor_search_vector(TEXT, TEXT, TEXT[], TEXT);
+DROP FUNCTION IF EXISTS doctor_search_text(TEXT, TEXT, TEXT[], TEXT);
@@ -0,0 +1,70 @@
+-- pg_trgm matches misspelled search queries by trigram similarity
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Languages a doctor consults in and the clinic they work at
+ALTER TABLE doctors
+    ADD COLUMN IF NOT EXISTS languages TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS clinic VARCHAR(255) NOT NULL DEFAULT '';
+
+-- The searched text of a doctor: name, specialization, languages and
+-- clinic. search_text is matched by trigrams and search_vector by full-text
+-- search, with the name and specialization weighted highest.
+CREATE OR REPLACE FUNCTION doctor_search_text(doctor_name TEXT, specialization TEXT, languages TEXT[], clinic TEXT)
+RETURNS TEXT LANGUAGE sql IMMUTABLE AS $$
+    SELECT concat_ws(' ', doctor_name, specialization, array_to_string(languages, ' '), clinic)
+$$;
+
+CREATE OR REPLACE FUNCTION doctor_search_vector(doctor_name TEXT, specialization TEXT, languages TEXT[], clinic TEXT)
+RETURNS TSVECTOR LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(doctor_name, '')), 'A')
+        || setweight(to_tsvector('simple', coalesce(specialization, '')), 'A')
+        || setweight(to_tsvector('simple', coalesce(array_to_string(languages, ' '), '')), 'B')
+        || setweight(to_tsvector('simple', coalesce(clinic, '')), 'C')
+$$;
+
+ALTER TABLE doctors
+    ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR NOT NULL DEFAULT ''::tsvector;
+
+-- The name lives in users, so triggers on both tables keep the searched
+-- text up to date
+CREATE OR REPLACE FUNCTION doctors_refresh_search() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+    SELECT doctor_search_text(u.name, NEW.specialization, NEW.languages, NEW.clinic),
+           doctor_search_vector(u.name, NEW.specialization, NEW.languages, NEW.clinic)
+    INTO NEW.search_text, NEW.search_vector
+    FROM users u
+    WHERE u.id = NEW.user_id;
+    RETURN NEW;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION users_refresh_doctor_search() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE doctors d
+    SET search_text = doctor_search_text(NEW.name, d.specialization, d.languages, d.clinic),
+        search_vector = doctor_search_vector(NEW.name, d.specialization, d.languages, d.clinic)
+    WHERE d.user_id = NEW.id;
+    RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS doctors_refresh_search ON doctors;
+CREATE TRIGGER doctors_refresh_search
+    BEFORE INSERT OR UPDATE OF user_id, specialization, languages, clinic ON doctors
+    FOR EACH ROW EXECUTE FUNCTION doctors_refresh_search();
+
+DROP TRIGGER IF EXISTS users_refresh_doctor_search ON users;
+CREATE TRIGGER users_refresh_doctor_search
+    AFTER UPDATE OF name ON users
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION users_refresh_doctor_search();
+
+UPDATE doctors d
+SET search_text = doctor_search_text(u.name, d.specialization, d.languages, d.clinic),
+    search_vector = doctor_search_vector(u.name, d.specialization, d.languages, d.clinic)
+FROM users u
+WHERE u.id = d.user_id;
+
+CREATE INDEX IF NOT EXISTS idx_doctors_search_vector ON doctors USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_doctors_search_text_trgm ON doctors USING GIN (search_text gin_trgm_ops);
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/handlers"
+	"hospital-backend/models"
+	"hospital-backend/repository/memory"
+)
+
+func TestUpdateAvailableSlots(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	drSmith := api.token(sampleCardiologist, models.RoleDoctor)
+
+	response := api.do(http.MethodPut, "/api/v1/doctor/slots", drSmith, models.UpdateAvailableSlotsRequest{
+		AvailableSlots: map[string][]string{"monday": {"10:00", "09:00", "10:00"}, "Friday": {"14:00"}},
+	})
+	if response.Status != http.StatusOK {
+		t.Fatalf("updating slots: status = %d (%s)", response.Status, response.Error)
+	}
+	var updated struct {
+		Doctor models.Doctor `json:"doctor"`
+	}
+	response.decode(t, &updated)
+	want := map[string][]string{"Monday": {"09:00", "10:00"}, "Friday": {"14:00"}}
+	if !reflect.DeepEqual(updated.Doctor.AvailableSlots, want) {
+		t.Errorf("available_slots = %v, want %v", updated.Doctor.AvailableSlots, want)
+	}
+
+	response = api.do(http.MethodPut, "/api/v1/doctor/slots", drSmith, models.UpdateAvailableSlotsRequest{
+		AvailableSlots: map[string][]string{"Funday": {"09:00"}},
+	})
+	if response.Status != http.StatusBadRequest || response.Error != `Invalid weekday "Funday"` {
+		t.Errorf("response = %d (%s), want 400 Invalid weekday", response.Status, response.Error)
+	}
+
+	alice := api.token("alice", models.RolePatient)
+	response = api.do(http.MethodPut, "/api/v1/doctor/slots", alice, models.UpdateAvailableSlotsRequest{
+		AvailableSlots: map[string][]string{"Monday": {"09:00"}},
+	})
+	expectError(t, response, http.StatusForbidden, apierror.CodeForbidden)
+}
+
+// TestAvailabilitySkipsStartedSlots publishes a midnight slot on every
+// weekday and checks that only today's, which has begun, is not offered
+func TestAvailabilitySkipsStartedSlots(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	drSmith := api.token(sampleCardiologist, models.RoleDoctor)
+
+	everyDay := make(map[string][]string, 7)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		everyDay[day.String()] = []string{"00:00"}
+	}
+	response := api.do(http.MethodPut, "/api/v1/doctor/slots", drSmith, models.UpdateAvailableSlotsRequest{AvailableSlots: everyDay})
+	if response.Status != http.StatusOK {
+		t.Fatalf("updating slots: status = %d (%s)", response.Status, response.Error)
+	}
+
+	response = api.do(http.MethodGet, "/api/v1/doctors/"+doctor.ID.String()+"/availability", "", nil)
+	if response.Status != http.StatusOK {
+		t.Fatalf("fetching availability: status = %d (%s)", response.Status, response.Error)
+	}
+	var availability struct {
+		Availability []models.DayAvailability `json:"availability"`
+	}
+	response.decode(t, &availability)
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if len(availability.Availability) != 7 {
+		t.Fatalf("got %d days, want 7", len(availability.Availability))
+	}
+	for _, day := range availability.Availability {
+		want := []string{"00:00"}
+		if day.Date == today {
+			want = []string{}
+		}
+		if !reflect.DeepEqual(day.Slots, want) {
+			t.Errorf("%s: slots = %v, want %v", day.Date, day.Slots, want)
+		}
+	}
+}
+
+func TestWorkingHoursAndAppointmentTypes(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	drSmith := api.token(sampleCardiologist, models.RoleDoctor)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	response := api.do(http.MethodPut, "/api/v1/doctor/working-hours", drSmith, models.UpdateWorkingHoursRequest{
+		WorkingHours: map[string][]models.WorkingPeriod{"Monday": {{Start: "10:00", End: "09:00"}}},
+		SlotDuration: 15,
+	})
+	expectError(t, response, http.StatusBadRequest, apierror.CodeValidationFailed)
+
+	response = api.do(http.MethodPut, "/api/v1/doctor/working-hours", drSmith, models.UpdateWorkingHoursRequest{
+		WorkingHours: map[string][]models.WorkingPeriod{"Monday": {{Start: "09:00", End: "10:00"}}},
+		SlotDuration: 15,
+	})
+	if response.Status != http.StatusOK {
+		t.Fatalf("updating working hours: status = %d (%s)", response.Status, response.Error)
+	}
+
+	// availability returns the start slots on monday for kind
+	availability := func(kind string) []string {
+		t.Helper()
+		path := "/api/v1/doctors/" + doctor.ID.String() + "/availability?from=" + monday + "&to=" + monday + "&appointment_type=" + kind
+		response := api.do(http.MethodGet, path, "", nil)
+		if response.Status != http.StatusOK {
+			t.Fatalf("fetching %s availability: status = %d (%s)", kind, response.Status, response.Error)
+		}
+		var body struct {
+			Availability []models.DayAvailability `json:"availability"`
+		}
+		response.decode(t, &body)
+		if len(body.Availability) != 1 {
+			t.Fatalf("%s availability has %d days, want 1", kind, len(body.Availability))
+		}
+		return body.Availability[0].Slots
+	}
+
+	if got, want := availability(models.TypeProcedure), []string{"09:00", "09:15"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("procedure starts = %v, want %v", got, want)
+	}
+
+	// A procedure occupies three slots, and consultations can't overlap it
+	procedure := bookingRequest(doctor, monday, "09:00")
+	procedure.Type = models.TypeProcedure
+	response = api.do(http.MethodPost, "/api/v1/appointments", alice, procedure)
+	if response.Status != http.StatusCreated {
+		t.Fatalf("booking procedure: status = %d (%s)", response.Status, response.Error)
+	}
+	if got, want := availability(models.TypeConsultation), []string{"09:45"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("consultation starts = %v, want %v", got, want)
+	}
+	expectError(t, api.book(bob, doctor, monday, "09:30"), http.StatusConflict, apierror.CodeSlotTaken)
+	api.mustBook(bob, doctor, monday, "09:45")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/handlers"
+	"hospital-backend/models"
+	"hospital-backend/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+// flaggedResponse is the body of a response that flags appointments
+type flaggedResponse struct {
+	Flagged []uuid.UUID `json:"flagged_appointments"`
+}
+
+func TestLeaveFlagsAppointments(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	drSmith := api.token(sampleCardiologist, models.RoleDoctor)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	affected := api.mustBook(alice, doctor, monday, "09:00")
+	outside := api.mustBook(alice, doctor, monday, "11:00")
+	completed := api.mustBook(bob, doctor, monday, "10:00")
+	response := api.do(http.MethodPut, "/api/v1/appointments/"+completed.ID.String(), drSmith,
+		models.UpdateAppointmentRequest{Status: models.StatusCompleted})
+	if response.Status != http.StatusOK {
+		t.Fatalf("completing visit: status = %d (%s)", response.Status, response.Error)
+	}
+
+	response = api.do(http.MethodPost, "/api/v1/doctor/exceptions", drSmith, models.CreateScheduleExceptionRequest{
+		Date: monday, Kind: models.ExceptionBlock, StartTime: "09:00", EndTime: "10:30", Reason: "clinic meeting",
+	})
+	if response.Status != http.StatusCreated {
+		t.Fatalf("blocking time: status = %d (%s)", response.Status, response.Error)
+	}
+	var created flaggedResponse
+	response.decode(t, &created)
+	if len(created.Flagged) != 1 || created.Flagged[0] != affected.ID {
+		t.Errorf("flagged %v, want only %s", created.Flagged, affected.ID)
+	}
+	if !api.appointment(alice, affected.ID).RescheduleRequired {
+		t.Error("appointment in the block is not flagged")
+	}
+	if api.appointment(alice, outside.ID).RescheduleRequired {
+		t.Error("appointment outside the block is flagged")
+	}
+	if api.appointment(bob, completed.ID).RescheduleRequired {
+		t.Error("completed appointment is flagged")
+	}
+
+	// Blocked slots can't be booked
+	carol := api.token("carol", models.RolePatient)
+	expectError(t, api.book(carol, doctor, monday, "10:00"), http.StatusBadRequest, apierror.CodeSlotUnavailable)
+}
+
+func TestHolidayFlagsAppointments(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	cardiologist := sampleDoctor(t, store, sampleCardiologist)
+	dermatologist := sampleDoctor(t, store, sampleDermatologist)
+	admin := api.token("admin", models.RoleAdmin)
+	alice := api.token("alice", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	first := api.mustBook(alice, cardiologist, monday, "09:00")
+	second := api.mustBook(alice, dermatologist, monday, "10:00")
+	cancelled := api.mustBook(alice, cardiologist, monday, "10:00")
+	response := api.do(http.MethodDelete, "/api/v1/appointments/"+cancelled.ID.String(), alice, nil)
+	if response.Status != http.StatusOK {
+		t.Fatalf("cancelling: status = %d (%s)", response.Status, response.Error)
+	}
+
+	response = api.do(http.MethodPost, "/api/v1/admin/holidays", admin, models.CreateHolidayRequest{Date: monday, Name: "Founders' Day"})
+	if response.Status != http.StatusCreated {
+		t.Fatalf("creating holiday: status = %d (%s)", response.Status, response.Error)
+	}
+	var created flaggedResponse
+	response.decode(t, &created)
+	if len(created.Flagged) != 2 {
+		t.Errorf("flagged %v, want %s and %s", created.Flagged, first.ID, second.ID)
+	}
+	for _, id := range []uuid.UUID{first.ID, second.ID} {
+		if !api.appointment(alice, id).RescheduleRequired {
+			t.Errorf("appointment %s is not flagged", id)
+		}
+	}
+	if api.appointment(alice, cancelled.ID).RescheduleRequired {
+		t.Error("cancelled appointment is flagged")
+	}
+
+	response = api.do(http.MethodPost, "/api/v1/admin/holidays", admin, models.CreateHolidayRequest{Date: monday, Name: "Again"})
+	expectError(t, response, http.StatusConflict, apierror.CodeConflict)
+
+	// An extra slot opens the holiday to bookings with that doctor only
+	drSmith := api.token(sampleCardiologist, models.RoleDoctor)
+	response = api.do(http.MethodPost, "/api/v1/doctor/exceptions", drSmith, models.CreateScheduleExceptionRequest{
+		Date: monday, Kind: models.ExceptionExtra, Slots: []string{"13:00"},
+	})
+	if response.Status != http.StatusCreated {
+		t.Fatalf("adding an extra slot: status = %d (%s)", response.Status, response.Error)
+	}
+	bob := api.token("bob", models.RolePatient)
+	api.mustBook(bob, cardiologist, monday, "13:00")
+	expectError(t, api.book(bob, cardiologist, monday, "11:00"), http.StatusBadRequest, apierror.CodeSlotUnavailable)
+	expectError(t, api.book(bob, dermatologist, monday, "13:00"), http.StatusBadRequest, apierror.CodeSlotUnavailable)
+}
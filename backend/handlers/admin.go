@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetUser returns a user and, for doctors, their doctor profile (admin only)
+func (h *Handler) GetUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid user ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.Get(ctx, userID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch user", err))
+		return
+	}
+
+	response := gin.H{"user": user}
+	if doctor, err := h.Doctors.GetByUserID(ctx, userID); err == nil {
+		response["doctor"] = doctor
+	}
+	respond(c, http.StatusOK, "User fetched successfully", response)
+}
+
+// CreateUser creates a user ahead of their first sign-in (admin only)
+func (h *Handler) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	actorID, _, _ := authenticatedUser(c)
+	user := &models.User{
+		FirebaseUID: req.FirebaseUID,
+		Name:        req.Name,
+		Email:       req.Email,
+		Role:        req.Role,
+	}
+	err := h.Users.Create(c.Request.Context(), user, actorID)
+	if err == repository.ErrConflict {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "A user with this firebase_uid already exists"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to create user", err))
+		return
+	}
+
+	respond(c, http.StatusCreated, "User created successfully", gin.H{
+		"user": user,
+	})
+}
+
+// UpdateUserRole changes the role of a user (admin only). Admins cannot
+// change their own role, and doctors keep the doctor role while their
+// profile is active.
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid user ID"))
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	actorID, _, _ := authenticatedUser(c)
+	if userID == actorID {
+		respondError(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidRequest, "Admins cannot change their own role"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.Role != models.RoleDoctor {
+		doctor, err := h.Doctors.GetByUserID(ctx, userID)
+		if err == nil && doctor.Active {
+			respondError(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidRequest, "Deactivate the user's doctor profile before changing their role"))
+			return
+		}
+	}
+
+	user, err := h.Users.UpdateRole(ctx, userID, req.Role, actorID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to update user role", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "User role updated successfully", gin.H{
+		"user": user,
+	})
+}
+
+// ListDoctorsAdmin returns all doctors, including deactivated ones (admin
+// only)
+func (h *Handler) ListDoctorsAdmin(c *gin.Context) {
+	limit, offset := parseLimitOffset(c)
+	filter := repository.DoctorFilter{
+		Specialization:  c.Query("specialization"),
+		IncludeInactive: true,
+		Limit:           limit,
+		Offset:          offset,
+	}
+
+	ctx := c.Request.Context()
+	total, err := h.Doctors.Count(ctx, filter)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to count doctors", err))
+		return
+	}
+
+	doctors, err := h.Doctors.List(ctx, filter)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch doctors", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Doctors fetched successfully", gin.H{
+		"doctors": doctors,
+		"total":   total,
+	})
+}
+
+// CreateDoctor creates the doctor profile of a user with the doctor role
+// (admin only)
+func (h *Handler) CreateDoctor(c *gin.Context) {
+	var req models.CreateDoctorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	if req.Phone != "" && !phonePattern.MatchString(req.Phone) {
+		respondInvalidFields(c, map[string]string{"phone": "must be a phone number such as +1234567890"})
+		return
+	}
+
+	if req.Timezone != "" && !validTimezone(req.Timezone) {
+		respondInvalidFields(c, map[string]string{"timezone": timezoneRule})
+		return
+	}
+
+	slots, err := normalizeSlots(req.AvailableSlots)
+	if err != nil {
+		respondInvalidFields(c, map[string]string{"available_slots": err.Error()})
+		return
+	}
+
+	hours, err := normalizeWorkingHours(req.WorkingHours)
+	if err != nil {
+		respondInvalidFields(c, map[string]string{"working_hours": err.Error()})
+		return
+	}
+	if hours != nil && len(slots) > 0 {
+		respondInvalidFields(c, map[string]string{"available_slots": workingHoursRule})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.Get(ctx, req.UserID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch user", err))
+		return
+	}
+	if user.Role != models.RoleDoctor {
+		respondError(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidRequest, "User must have the doctor role"))
+		return
+	}
+
+	actorID, _, _ := authenticatedUser(c)
+	doctor := &models.Doctor{
+		UserID:         req.UserID,
+		Specialization: req.Specialization,
+		Experience:     req.Experience,
+		Phone:          req.Phone,
+		AvailableSlots: slots,
+		Timezone:       req.Timezone,
+		WorkingHours:   hours,
+		SlotDuration:   req.SlotDuration,
+		BufferMinutes:  req.BufferMinutes,
+		Languages:      languageList(req.Languages),
+		Clinic:         req.Clinic,
+	}
+	if doctor.SlotDuration == 0 {
+		doctor.SlotDuration = models.DefaultSlotDuration
+	}
+	applyWorkingHours(doctor)
+	err = h.Doctors.Create(ctx, doctor, actorID)
+	if err == repository.ErrConflict {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "User already has a doctor profile"))
+		return
+	}
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to create doctor", err))
+		return
+	}
+
+	doctor, err = h.Doctors.Get(ctx, doctor.ID)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch doctor", err))
+		return
+	}
+
+	respond(c, http.StatusCreated, "Doctor created successfully", gin.H{
+		"doctor": doctor,
+	})
+}
+
+// UpdateDoctor changes the fields of a doctor profile present in the
+// request (admin only)
+func (h *Handler) UpdateDoctor(c *gin.Context) {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid doctor ID"))
+		return
+	}
+
+	var req models.UpdateDoctorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	if req.Phone != nil && *req.Phone != "" && !phonePattern.MatchString(*req.Phone) {
+		respondInvalidFields(c, map[string]string{"phone": "must be a phone number such as +1234567890"})
+		return
+	}
+
+	// An empty timezone falls back to the hospital timezone
+	if req.Timezone != nil && *req.Timezone != "" && !validTimezone(*req.Timezone) {
+		respondInvalidFields(c, map[string]string{"timezone": timezoneRule})
+		return
+	}
+
+	var slots map[string][]string
+	if req.AvailableSlots != nil {
+		if slots, err = normalizeSlots(req.AvailableSlots); err != nil {
+			respondInvalidFields(c, map[string]string{"available_slots": err.Error()})
+			return
+		}
+	}
+
+	hours, err := normalizeWorkingHours(req.WorkingHours)
+	if err != nil {
+		respondInvalidFields(c, map[string]string{"working_hours": err.Error()})
+		return
+	}
+	if hours != nil && slots != nil {
+		respondInvalidFields(c, map[string]string{"available_slots": workingHoursRule})
+		return
+	}
+
+	actorID, _, _ := authenticatedUser(c)
+	doctor, err := h.Doctors.Update(c.Request.Context(), doctorID, actorID, func(doctor *models.Doctor) error {
+		if req.Specialization != nil {
+			doctor.Specialization = *req.Specialization
+		}
+		if req.Experience != nil {
+			doctor.Experience = *req.Experience
+		}
+		if req.Phone != nil {
+			doctor.Phone = *req.Phone
+		}
+		if req.Languages != nil {
+			doctor.Languages = languageList(req.Languages)
+		}
+		if req.Clinic != nil {
+			doctor.Clinic = *req.Clinic
+		}
+		// An explicit slot template replaces the working hours
+		if slots != nil {
+			doctor.AvailableSlots = slots
+			doctor.WorkingHours = nil
+		}
+		if hours != nil {
+			doctor.WorkingHours = hours
+		}
+		if req.SlotDuration != nil {
+			doctor.SlotDuration = *req.SlotDuration
+		}
+		if req.BufferMinutes != nil {
+			doctor.BufferMinutes = *req.BufferMinutes
+		}
+		applyWorkingHours(doctor)
+		if req.Timezone != nil {
+			doctor.Timezone = *req.Timezone
+		}
+		if req.Active != nil {
+			doctor.Active = *req.Active
+		}
+		return nil
+	})
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeDoctorNotFound, "Doctor not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to update doctor", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Doctor updated successfully", gin.H{
+		"doctor": doctor,
+	})
+}
+
+// DeactivateDoctor hides a doctor from listings and stops new bookings
+// (admin only). Existing appointments are kept; the response counts the
+// upcoming ones so they can be rescheduled or cancelled.
+func (h *Handler) DeactivateDoctor(c *gin.Context) {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid doctor ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID, _, _ := authenticatedUser(c)
+	doctor, err := h.Doctors.Update(ctx, doctorID, actorID, func(doctor *models.Doctor) error {
+		doctor.Active = false
+		return nil
+	})
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeDoctorNotFound, "Doctor not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to deactivate doctor", err))
+		return
+	}
+
+	upcoming, err := h.Appointments.Count(ctx, repository.AppointmentFilter{
+		DoctorID:   doctorID,
+		From:       time.Now(),
+		ActiveOnly: true,
+	})
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to count upcoming appointments", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Doctor deactivated successfully", gin.H{
+		"doctor":                doctor,
+		"upcoming_appointments": upcoming,
+	})
+}
+
+// ListAuditLog returns audit entries, newest first, optionally filtered by
+// entity_type, entity_id and actor_id (admin only)
+func (h *Handler) ListAuditLog(c *gin.Context) {
+	filter := repository.AuditFilter{EntityType: c.Query("entity_type")}
+	if filter.EntityType != "" && filter.EntityType != repository.EntityUser && filter.EntityType != repository.EntityDoctor {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid entity_type"))
+		return
+	}
+
+	for param, target := range map[string]*uuid.UUID{"entity_id": &filter.EntityID, "actor_id": &filter.ActorID} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid "+param))
+			return
+		}
+		*target = id
+	}
+
+	filter.Limit, filter.Offset = parseLimitOffset(c)
+	entries, err := h.Audit.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch audit log", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Audit log fetched successfully", gin.H{
+		"entries": entries,
+	})
+}
+
+// ListReminders returns the delivery state of appointment reminders, most
+// recently updated first, optionally filtered by appointment_id and status
+// (admin only)
+func (h *Handler) ListReminders(c *gin.Context) {
+	filter := repository.ReminderFilter{Status: c.Query("status")}
+	switch filter.Status {
+	case "", models.ReminderSending, models.ReminderSent, models.ReminderFailed, models.ReminderSkipped:
+	default:
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid status"))
+		return
+	}
+
+	if value := c.Query("appointment_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid appointment_id"))
+			return
+		}
+		filter.AppointmentID = id
+	}
+
+	filter.Limit, filter.Offset = parseLimitOffset(c)
+	reminders, err := h.Reminders.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch reminders", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Reminders fetched successfully", gin.H{
+		"reminders": reminders,
+	})
+}
+
+// languageList trims the languages of a doctor profile and drops blank
+// and repeated ones; nil when none are left
+func languageList(languages []string) []string {
+	var list []string
+	for _, language := range languages {
+		language = strings.TrimSpace(language)
+		if language != "" && !slices.Contains(list, language) {
+			list = append(list, language)
+		}
+	}
+	return list
+}
+
+// parseLimitOffset reads the limit (default 50) and offset query parameters
+func parseLimitOffset(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// respondInvalidRequest rejects a request body that failed to bind, naming
+// the invalid fields when binding validation caught them
+func respondInvalidRequest(c *gin.Context, err error) {
+	if fields := fieldErrors(err); fields != nil {
+		respondInvalidFields(c, fields)
+		return
+	}
+	respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request data"))
+}
+
+// respondInvalidFields rejects a request with a description of each
+// invalid field
+func respondInvalidFields(c *gin.Context, fields map[string]string) {
+	respondError(c, apierror.Invalid(fields))
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"hospital-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// authenticatedUser returns the users.id and role set by the auth middleware
+func authenticatedUser(c *gin.Context) (uuid.UUID, string, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return uuid.Nil, "", false
+	}
+	userID, ok := value.(uuid.UUID)
+	return userID, c.GetString("user_role"), ok
+}
+
+// actorFor returns the role in which a user acts on the appointment: any
+// admin, the appointment's doctor or its patient. It returns "" for users
+// with no relation to the appointment.
+func actorFor(appointment *models.Appointment, userID uuid.UUID, role string) string {
+	switch {
+	case role == models.RoleAdmin:
+		return models.RoleAdmin
+	case appointment.Doctor != nil && appointment.Doctor.UserID == userID:
+		return models.RoleDoctor
+	case appointment.PatientID == userID:
+		return models.RolePatient
+	}
+	return ""
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// dateLayout is the format of the from/to query parameters
+	dateLayout = "2006-01-02"
+	// defaultAvailabilityDays is the range returned when "to" is omitted
+	defaultAvailabilityDays = 7
+	// maxAvailabilityDays caps the range of a single availability query
+	maxAvailabilityDays = 31
+)
+
+// GetDoctorAvailability returns a doctor's free slots per day between the
+// "from" and "to" dates (inclusive, YYYY-MM-DD) for an appointment of the
+// "appointment_type" query parameter (default consultation). Slots held by
+// patients are busy, except for the hold named by the "hold_token" query
+// parameter.
+func (h *Handler) GetDoctorAvailability(c *gin.Context) {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid doctor ID"))
+		return
+	}
+
+	from, to, err := h.parseAvailabilityRange(c)
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidDate, sentence(err)))
+		return
+	}
+
+	kind, duration, err := parseAppointmentType(c)
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, sentence(err)))
+		return
+	}
+
+	ownHold, err := parseHoldToken(c)
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidHoldToken, sentence(err)))
+		return
+	}
+
+	days, loc, err := h.computeAvailability(c.Request.Context(), doctorID, from, to, duration, ownHold)
+	if err == errDoctorNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeDoctorNotFound, "Doctor not found"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to compute availability", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Availability fetched successfully", gin.H{
+		"doctor_id":        doctorID,
+		"from":             from.Format(dateLayout),
+		"to":               to.Format(dateLayout),
+		"timezone":         loc.String(),
+		"appointment_type": kind,
+		"duration_minutes": int(duration / time.Minute),
+		"availability":     days,
+	})
+}
+
+// parseAvailabilityRange reads the from/to query parameters. "from" defaults
+// to today and "to" to a week after "from".
+func (h *Handler) parseAvailabilityRange(c *gin.Context) (time.Time, time.Time, error) {
+	return h.parseDateRange(c, defaultAvailabilityDays, maxAvailabilityDays)
+}
+
+// parseAppointmentType reads the appointment_type query parameter
+// (default consultation) and returns the type with its duration
+func parseAppointmentType(c *gin.Context) (string, time.Duration, error) {
+	kind := c.DefaultQuery("appointment_type", models.TypeConsultation)
+	duration, ok := models.AppointmentDuration(kind)
+	if !ok {
+		return "", 0, fmt.Errorf("invalid appointment_type. Use %s, %s or %s",
+			models.TypeConsultation, models.TypeFollowUp, models.TypeProcedure)
+	}
+	return kind, duration, nil
+}
+
+// parseHoldToken reads the optional hold_token query parameter
+func parseHoldToken(c *gin.Context) (uuid.UUID, error) {
+	value := c.Query("hold_token")
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	holdID, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, errInvalidHoldToken
+	}
+	return holdID, nil
+}
+
+// parseDateRange reads the inclusive from/to query parameters. "from"
+// defaults to today in the hospital timezone and "to" to defaultDays days
+// from "from"; ranges longer than maxDays are rejected.
+func (h *Handler) parseDateRange(c *gin.Context, defaultDays, maxDays int) (time.Time, time.Time, error) {
+	from := today(h.Location)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid from date. Use YYYY-MM-DD format")
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, defaultDays-1)
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to date. Use YYYY-MM-DD format")
+		}
+		to = parsed
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("to date must not be before from date")
+	}
+	if to.Sub(from) >= time.Duration(maxDays)*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must not exceed %d days", maxDays)
+	}
+
+	return from, to, nil
+}
+
+// computeAvailability expands the doctor's weekly slot template, adjusted
+// by schedule exceptions and holidays, into the dated slots between from
+// and to (inclusive) at which an appointment lasting duration fits, and
+// removes the ones already started or whose time, plus the doctor's
+// buffer, overlaps a non-cancelled appointment or a slot hold other than
+// ownHold. Days on which the doctor does not work are omitted. Dates and
+// slots are in the doctor's timezone, which is returned with the days.
+func (h *Handler) computeAvailability(ctx context.Context, doctorID uuid.UUID, from, to time.Time, duration time.Duration, ownHold uuid.UUID) ([]models.DayAvailability, *time.Location, error) {
+	doctor, err := h.Doctors.Get(ctx, doctorID)
+	if err == repository.ErrNotFound || err == nil && !doctor.Active {
+		return nil, nil, errDoctorNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Appointments starting the day before may run into the range
+	loc := h.location(doctor)
+	start, end := dayBounds(from.AddDate(0, 0, -1), to, loc)
+	appointments, err := h.Appointments.List(ctx, repository.AppointmentFilter{
+		DoctorID:   doctorID,
+		From:       start,
+		Until:      end,
+		ActiveOnly: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	holds, err := h.Holds.List(ctx, doctorID, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	busy := make([]busyRange, 0, len(appointments)+len(holds))
+	for _, appointment := range appointments {
+		busy = append(busy, busyRange{appointment.AppointmentDate, appointment.OccupiedUntil})
+	}
+	for _, hold := range holds {
+		if hold.ID != ownHold {
+			busy = append(busy, busyRange{hold.StartsAt, hold.OccupiedUntil})
+		}
+	}
+
+	s, err := h.loadSchedule(ctx, doctor, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	days := []models.DayAvailability{}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		if len(s.slots(date)) == 0 {
+			continue
+		}
+
+		day := models.DayAvailability{
+			Date:    date.Format(dateLayout),
+			Weekday: date.Weekday().String(),
+			Slots:   []string{},
+		}
+		for _, slot := range s.starts(date, duration) {
+			slotBegins, err := slotStart(date, slot, loc)
+			if err == nil && slotBegins.After(now) && !overlapsAny(busy, slotBegins, slotBegins.Add(duration+s.buffer)) {
+				day.Slots = append(day.Slots, slot)
+			}
+		}
+		days = append(days, day)
+	}
+
+	return days, loc, nil
+}
+
+// busyRange is time of a doctor taken by an appointment or a slot hold
+type busyRange struct {
+	start time.Time
+	end   time.Time
+}
+
+// overlapsAny reports whether [start, end) overlaps any of busy
+func overlapsAny(busy []busyRange, start, end time.Time) bool {
+	for _, r := range busy {
+		if r.start.Before(end) && start.Before(r.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// sentence capitalises an error message for clients, since Go error
+// strings start in lower case
+func sentence(err error) string {
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+	r, size := utf8.DecodeRuneInString(message)
+	return string(unicode.ToUpper(r)) + message[size:]
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	errDoctorNotFound = errors.New("doctor not found")
+	// errInvalidHoldToken rejects a hold_token that is not a UUID
+	errInvalidHoldToken = errors.New("invalid hold_token")
+	// errHoldMismatch rejects a booking that differs from the hold it names
+	errHoldMismatch = errors.New("the hold_token was issued for a different doctor, time or appointment type")
+)
+
+// bookAppointment creates a scheduled appointment with an active doctor.
+// req.AppointmentDate selects the date (see appointmentDay) and the slot is
+// read in the doctor's timezone; the appointment stores the instant the
+// slot starts. The appointment lasts as long as its type, and the doctor
+// must work from the slot until it ends on that date, after applying
+// schedule exceptions and holidays to the weekly available_slots,
+// otherwise a *slotUnavailableError is returned. The repository guarantees
+// that two concurrent bookings of overlapping times cannot both succeed:
+// the loser gets repository.ErrSlotTaken. Time held by another patient is
+// refused with repository.ErrSlotHeld; req.HoldToken names the patient's
+// own hold on the slot, which the booking consumes.
+func (h *Handler) bookAppointment(ctx context.Context, patientID uuid.UUID, req models.CreateAppointmentRequest) (*models.Appointment, error) {
+	holdID := uuid.Nil
+	if req.HoldToken != "" {
+		parsed, err := uuid.Parse(req.HoldToken)
+		if err != nil {
+			return nil, errInvalidHoldToken
+		}
+		holdID = parsed
+	}
+
+	appointment, err := h.plannedAppointment(ctx, req.DoctorID, req.AppointmentDate, req.Slot, req.Type)
+	if err != nil {
+		return nil, err
+	}
+	appointment.PatientID = patientID
+	appointment.Notes = req.Notes
+
+	// The hold must be the patient's and cover exactly this booking
+	if holdID != uuid.Nil {
+		hold, err := h.Holds.Get(ctx, holdID)
+		if err == repository.ErrNotFound || err == nil && hold.PatientID != patientID {
+			return nil, repository.ErrHoldExpired
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hold.DoctorID != appointment.DoctorID || !hold.StartsAt.Equal(appointment.AppointmentDate) ||
+			hold.Type != appointment.Type {
+			return nil, errHoldMismatch
+		}
+	}
+
+	doctor := appointment.Doctor
+	appointment.Doctor = nil
+	if err := h.Appointments.Book(ctx, appointment, patientID, holdID); err != nil {
+		return nil, err
+	}
+	appointment.Doctor = doctor
+	h.localizeAppointment(appointment)
+	h.appointmentEvent(models.EventBooked, *appointment)
+	return appointment, nil
+}
+
+// plannedAppointment checks that the active doctor works the slot on the
+// date given by dateValue for an appointment of type kind (default
+// consultation), and returns the appointment that would occupy it with
+// Doctor populated. It fails like bookAppointment.
+func (h *Handler) plannedAppointment(ctx context.Context, doctorID uuid.UUID, dateValue, slot, kind string) (*models.Appointment, error) {
+	// Check that the doctor exists and works this slot
+	doctor, err := h.Doctors.Get(ctx, doctorID)
+	if err == repository.ErrNotFound || err == nil && !doctor.Active {
+		return nil, errDoctorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The request binding only admits known types
+	if kind == "" {
+		kind = models.TypeConsultation
+	}
+	duration, ok := models.AppointmentDuration(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown appointment type %q", kind)
+	}
+
+	loc := h.location(doctor)
+	date, err := appointmentDay(dateValue, slot, loc)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.validateSlot(ctx, doctor, date, slot, duration); err != nil {
+		return nil, err
+	}
+	start, err := slotStart(date, slot, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	_, buffer := slotSettings(doctor)
+	return &models.Appointment{
+		DoctorID:        doctorID,
+		AppointmentDate: start,
+		Slot:            slot,
+		Type:            kind,
+		DurationMinutes: int(duration / time.Minute),
+		OccupiedUntil:   start.Add(duration + buffer),
+		Doctor:          doctor,
+	}, nil
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/ical"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// icsSuffix ends the paths of iCalendar documents
+	icsSuffix = ".ics"
+	// calendarProdID identifies this server in iCalendar documents
+	calendarProdID = "-//Swaasthya//Hospital Appointments//EN"
+	// calendarUIDDomain qualifies appointment IDs into iCalendar UIDs
+	calendarUIDDomain = "appointments.swaasthya"
+	// feedHistory is how far back a calendar feed reaches, so recent
+	// visits stay in subscribers' calendars
+	feedHistory = 30 * 24 * time.Hour
+	// maxFeedEvents caps the appointments in one calendar feed. Upcoming
+	// appointments take precedence over history.
+	maxFeedEvents = 500
+	// feedRefreshInterval is how often subscribers are asked to poll
+	feedRefreshInterval = time.Hour
+)
+
+// GetAppointment returns one appointment to its patient, its doctor and
+// admins, as an iCalendar document when its ID ends in .ics
+// (/api/v1/appointments/{id}.ics)
+func (h *Handler) GetAppointment(c *gin.Context) {
+	param := c.Param("id")
+	asCalendar := strings.HasSuffix(param, icsSuffix)
+
+	appointmentID, err := uuid.Parse(strings.TrimSuffix(param, icsSuffix))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid appointment ID"))
+		return
+	}
+
+	userID, role, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	appointment, err := h.Appointments.Get(c.Request.Context(), appointmentID)
+	if err == nil && actorFor(appointment, userID, role) == "" {
+		err = repository.ErrNotFound
+	}
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeAppointmentNotFound, "Appointment not found"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch appointment", err))
+		return
+	}
+
+	if !asCalendar {
+		h.localizeAppointment(appointment)
+		respond(c, http.StatusOK, "Appointment fetched successfully", gin.H{
+			"appointment": appointment,
+		})
+		return
+	}
+
+	forDoctor := appointment.Doctor != nil && appointment.Doctor.UserID == userID
+	h.respondCalendar(c, &ical.Calendar{
+		ProdID: calendarProdID,
+		Events: []ical.Event{h.calendarEvent(appointment, forDoctor)},
+	}, "appointment-"+appointment.ID.String()+icsSuffix)
+}
+
+// CreateCalendarFeed issues the authenticated user a secret calendar feed
+// URL, replacing and so revoking any previous one. The URL is only shown
+// once.
+func (h *Handler) CreateCalendarFeed(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		respondError(c, apierror.Internal("Failed to create calendar feed", err))
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	if err := h.Users.SetCalendarToken(c.Request.Context(), userID, calendarTokenHash(token)); err != nil {
+		respondError(c, apierror.Internal("Failed to create calendar feed", err))
+		return
+	}
+
+	feedURL := publicBaseURL(c) + "/api/v1/calendar/" + token + icsSuffix
+	respond(c, http.StatusCreated, "Calendar feed created successfully", gin.H{
+		"url":        feedURL,
+		"webcal_url": "webcal" + strings.TrimPrefix(strings.TrimPrefix(feedURL, "https"), "http"),
+	})
+}
+
+// DeleteCalendarFeed revokes the authenticated user's calendar feed URL
+func (h *Handler) DeleteCalendarFeed(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	if err := h.Users.SetCalendarToken(c.Request.Context(), userID, ""); err != nil {
+		respondError(c, apierror.Internal("Failed to revoke calendar feed", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Calendar feed revoked successfully", nil)
+}
+
+// GetCalendarFeed serves the calendar feed named by a secret token, with
+// no other authentication so calendar apps can subscribe to it. Patients
+// get their own appointments; doctors get their whole agenda. The feed
+// covers appointments from feedHistory ago onwards, and cancelled ones are
+// kept with STATUS:CANCELLED so subscribers remove them. When the feed
+// holds more than maxFeedEvents, the oldest past appointments are dropped
+// first.
+func (h *Handler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), icsSuffix)
+
+	ctx := c.Request.Context()
+	user, err := h.Users.GetByCalendarToken(ctx, calendarTokenHash(token))
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Calendar feed not found"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch calendar feed", err))
+		return
+	}
+
+	now := time.Now()
+	filter := repository.AppointmentFilter{
+		PatientID: user.ID,
+		From:      now,
+		Ascending: true,
+		Limit:     maxFeedEvents,
+	}
+	name := "My appointments"
+	forDoctor := false
+	if user.Role == models.RoleDoctor {
+		doctor, err := h.Doctors.GetByUserID(ctx, user.ID)
+		if err != nil && err != repository.ErrNotFound {
+			respondError(c, apierror.Internal("Failed to fetch calendar feed", err))
+			return
+		}
+		if doctor != nil {
+			filter.PatientID = uuid.Nil
+			filter.DoctorID = doctor.ID
+			name = "Agenda of " + user.Name
+			forDoctor = true
+		}
+	}
+
+	// Upcoming appointments are read first so the cap only ever cuts
+	// history, which fills what is left of it nearest first
+	appointments, err := h.Appointments.List(ctx, filter)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch calendar feed", err))
+		return
+	}
+	if len(appointments) < maxFeedEvents {
+		history := filter
+		history.From = now.Add(-feedHistory)
+		history.Until = now
+		history.Ascending = false
+		history.Limit = maxFeedEvents - len(appointments)
+		past, err := h.Appointments.List(ctx, history)
+		if err != nil {
+			respondError(c, apierror.Internal("Failed to fetch calendar feed", err))
+			return
+		}
+		slices.Reverse(past)
+		appointments = append(past, appointments...)
+	}
+
+	events := make([]ical.Event, len(appointments))
+	for i := range appointments {
+		events[i] = h.calendarEvent(&appointments[i], forDoctor)
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	h.respondCalendar(c, &ical.Calendar{
+		ProdID:          calendarProdID,
+		Name:            name,
+		RefreshInterval: feedRefreshInterval,
+		Events:          events,
+	}, "appointments"+icsSuffix)
+}
+
+// calendarEvent describes appointment as seen by its patient, or by its
+// doctor when forDoctor is set. Clinical notes are left out.
+func (h *Handler) calendarEvent(appointment *models.Appointment, forDoctor bool) ical.Event {
+	h.localizeAppointment(appointment)
+
+	kind := appointmentTypeLabel(appointment.Type)
+	summary := kind + " with " + doctorName(appointment.Doctor)
+	if forDoctor {
+		patient := "patient"
+		if appointment.Patient != nil && appointment.Patient.Name != "" {
+			patient = appointment.Patient.Name
+		}
+		summary = kind + ": " + patient
+	}
+
+	description := fmt.Sprintf("Slot %s (%s), %d minutes\nStatus: %s",
+		appointment.Slot, appointment.Timezone, appointment.DurationMinutes, appointment.Status)
+	if appointment.Notes != "" {
+		description += "\nNotes: " + appointment.Notes
+	}
+
+	status := ical.StatusConfirmed
+	if appointment.Status == models.StatusCancelled {
+		status = ical.StatusCancelled
+	}
+
+	return ical.Event{
+		UID:          appointment.ID.String() + "@" + calendarUIDDomain,
+		Start:        appointment.AppointmentDate,
+		End:          appointment.EndsAt,
+		Summary:      summary,
+		Description:  description,
+		Status:       status,
+		Created:      appointment.CreatedAt,
+		LastModified: appointment.UpdatedAt,
+	}
+}
+
+// respondCalendar writes cal as an iCalendar attachment named filename
+func (h *Handler) respondCalendar(c *gin.Context, cal *ical.Calendar, filename string) {
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Now()); err != nil {
+		respondError(c, apierror.Internal("Failed to render calendar", err))
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Data(http.StatusOK, ical.ContentType, buf.Bytes())
+}
+
+// appointmentTypeLabel names an appointment type for people
+func appointmentTypeLabel(kind string) string {
+	switch kind {
+	case models.TypeFollowUp:
+		return "Follow-up"
+	case models.TypeProcedure:
+		return "Procedure"
+	default:
+		return "Consultation"
+	}
+}
+
+// doctorName returns the doctor's name, or a placeholder when unknown
+func doctorName(doctor *models.Doctor) string {
+	if doctor != nil && doctor.User != nil && doctor.User.Name != "" {
+		return doctor.User.Name
+	}
+	return "your doctor"
+}
+
+// calendarTokenHash is the stored form of a calendar feed token
+func calendarTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// publicBaseURL returns the scheme and host the client reached the server
+// on, honouring X-Forwarded-Proto from a TLS-terminating proxy
+func publicBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+	"hospital-backend/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestCalendarFeedCapKeepsUpcomingAppointments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewSampleStore()
+	h := New(store, Config{})
+
+	doctors, err := store.Doctors.List(ctx, repository.DoctorFilter{Limit: 1})
+	if err != nil || len(doctors) == 0 {
+		t.Fatalf("listing sample doctors: %v", err)
+	}
+	doctor := doctors[0]
+
+	patient := &models.User{FirebaseUID: "alice", Name: "Alice", Role: models.RolePatient}
+	if err := store.Users.Upsert(ctx, patient); err != nil {
+		t.Fatalf("creating patient: %v", err)
+	}
+	const token = "feed-token"
+	if err := store.Users.SetCalendarToken(ctx, patient.ID, calendarTokenHash(token)); err != nil {
+		t.Fatalf("setting calendar token: %v", err)
+	}
+
+	book := func(start time.Time) uuid.UUID {
+		appointment := &models.Appointment{
+			DoctorID:        doctor.ID,
+			PatientID:       patient.ID,
+			AppointmentDate: start,
+			Slot:            start.Format("15:04"),
+			Type:            models.TypeConsultation,
+			DurationMinutes: 30,
+			OccupiedUntil:   start.Add(30 * time.Minute),
+		}
+		if err := store.Appointments.Book(ctx, appointment, patient.ID, uuid.Nil); err != nil {
+			t.Fatalf("booking appointment at %s: %v", start, err)
+		}
+		return appointment.ID
+	}
+
+	// Fill the feed with history, so that reading from feedHistory ago
+	// would reach the cap before any upcoming appointment
+	now := time.Now().Truncate(time.Hour)
+	for i := 1; i <= maxFeedEvents; i++ {
+		book(now.Add(-time.Duration(i) * time.Hour))
+	}
+	var upcoming []uuid.UUID
+	for i := 1; i <= 3; i++ {
+		upcoming = append(upcoming, book(now.Add(time.Duration(24*i)*time.Hour)))
+	}
+
+	router := gin.New()
+	router.GET("/calendar/:token", h.GetCalendarFeed)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/calendar/"+token+icsSuffix, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	feed := w.Body.String()
+	if events := strings.Count(feed, "BEGIN:VEVENT"); events != maxFeedEvents {
+		t.Errorf("feed has %d events, want the cap of %d", events, maxFeedEvents)
+	}
+	for _, id := range upcoming {
+		if !strings.Contains(feed, id.String()+"@"+calendarUIDDomain) {
+			t.Errorf("feed lacks upcoming appointment %s", id)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cursor is the position a keyset page is read from. Clients get it as an
+// opaque string in next_cursor and prev_cursor and send it back in the
+// cursor query parameter.
+type cursor struct {
+	// Prev reads the page before the key rather than after it
+	Prev bool `json:"p,omitempty"`
+	// One key is set, of the listing the cursor belongs to
+	Doctor      *repository.DoctorKey      `json:"d,omitempty"`
+	Appointment *repository.AppointmentKey `json:"a,omitempty"`
+}
+
+// String encodes the cursor for clients
+func (cur cursor) String() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cursorParam reads the cursor query parameter, returning nil when it is
+// absent. valid reports whether a cursor belongs to the listing.
+func cursorParam(c *gin.Context, valid func(cur *cursor) bool) (*cursor, error) {
+	raw := c.Query("cursor")
+	if raw == "" {
+		return nil, nil
+	}
+
+	invalid := apierror.New(http.StatusBadRequest, apierror.CodeInvalidCursor, "Invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+	var cur cursor
+	if err := json.Unmarshal(data, &cur); err != nil || !valid(&cur) {
+		return nil, invalid
+	}
+	return &cur, nil
+}
+
+// doctorCursor returns the cursor at doctor
+func doctorCursor(doctor models.Doctor) cursor {
+	key := &repository.DoctorKey{ID: doctor.ID}
+	if doctor.User != nil {
+		key.Name = doctor.User.Name
+	}
+	return cursor{Doctor: key}
+}
+
+// appointmentCursor returns the cursor at appointment
+func appointmentCursor(appointment models.Appointment) cursor {
+	return cursor{Appointment: &repository.AppointmentKey{Date: appointment.AppointmentDate, ID: appointment.ID}}
+}
+
+// trimPage cuts a page read from a cursor with one item beyond limit back
+// to limit, and reports whether that extra item was there. Pages read
+// backward lose their first item, others their last.
+func trimPage[T any](items []T, limit int, backward bool) ([]T, bool) {
+	if len(items) <= limit {
+		return items, false
+	}
+	if backward {
+		return items[len(items)-limit:], true
+	}
+	return items[:limit], true
+}
+
+// cursorPagination describes a page of limit items read from cursor from;
+// more reports whether items lie beyond it in the direction read. Pages
+// read from a cursor are not counted, so page, total and total_pages are
+// zero.
+func cursorPagination(from *cursor, limit int, more bool) PaginationInfo {
+	return PaginationInfo{
+		Limit:   limit,
+		HasNext: from.Prev || more,
+		HasPrev: !from.Prev || more,
+	}
+}
+
+// setCursors sets the next and prev cursors of a page whose first and
+// last items are at first and last, where the page has more items
+func (p *PaginationInfo) setCursors(first, last cursor) {
+	if p.HasNext {
+		last.Prev = false
+		p.NextCursor = last.String()
+	}
+	if p.HasPrev {
+		first.Prev = true
+		p.PrevCursor = first.String()
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDevice stores the push notification token of one of the
+// authenticated user's devices. Registering a token again refreshes it, and
+// a token registered by another user moves to this one.
+func (h *Handler) RegisterDevice(c *gin.Context) {
+	var req models.RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	device := &models.DeviceToken{
+		Token:    req.Token,
+		UserID:   userID,
+		Platform: req.Platform,
+	}
+	if err := h.Devices.Register(c.Request.Context(), device); err != nil {
+		respondError(c, apierror.Internal("Failed to register device", err))
+		return
+	}
+
+	respond(c, http.StatusCreated, "Device registered successfully", gin.H{
+		"device": device,
+	})
+}
+
+// UnregisterDevice stops push notifications to one of the authenticated
+// user's devices, as on sign-out
+func (h *Handler) UnregisterDevice(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	err := h.Devices.Unregister(c.Request.Context(), userID, c.Param("token"))
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Device not found"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to unregister device", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Device unregistered successfully", nil)
+}
+
+// AppointmentEvents delivers the appointment changes patients are told
+// about by push notification, for the push worker
+func (h *Handler) AppointmentEvents() <-chan models.AppointmentEvent {
+	return h.events
+}
+
+// appointmentEvent queues a push notification of kind about appointment
+// without blocking the request. Events are dropped when push notifications
+// are off or the queue is full.
+func (h *Handler) appointmentEvent(kind string, appointment models.Appointment) {
+	if h.events == nil {
+		return
+	}
+	select {
+	case h.events <- models.AppointmentEvent{Kind: kind, Appointment: appointment}:
+	default:
+		log.Printf("Push queue is full; %s for appointment %s is not sent", kind, appointment.ID)
+	}
+}
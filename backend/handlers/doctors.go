@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// slotLayout is the format of the times in a doctor's slot template
+const slotLayout = "15:04"
+
+// errDoctorProfileNotFound is returned when the authenticated user has no
+// doctor profile
+var errDoctorProfileNotFound = errors.New("doctor profile not found")
+
+// GetDoctorAgenda returns the authenticated doctor's appointments on one
+// date (default today in the doctor's timezone). Cancelled appointments are
+// left out unless include_cancelled=true.
+func (h *Handler) GetDoctorAgenda(c *gin.Context) {
+	var date time.Time
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid date. Use YYYY-MM-DD format"))
+			return
+		}
+		date = parsed
+	}
+
+	h.respondAgenda(c, date, 1)
+}
+
+// GetDoctorWeekAgenda returns the authenticated doctor's appointments for
+// the seven days starting at start (default today in the doctor's
+// timezone), grouped by date
+func (h *Handler) GetDoctorWeekAgenda(c *gin.Context) {
+	var start time.Time
+	if value := c.Query("start"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid start date. Use YYYY-MM-DD format"))
+			return
+		}
+		start = parsed
+	}
+
+	h.respondAgenda(c, start, 7)
+}
+
+// respondAgenda writes the agenda of the authenticated doctor for days
+// dates starting at from (today when zero), read in the doctor's timezone
+func (h *Handler) respondAgenda(c *gin.Context, from time.Time, days int) {
+	ctx := c.Request.Context()
+	doctor, err := h.currentDoctor(c)
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	loc := h.location(doctor)
+	if from.IsZero() {
+		from = today(loc)
+	}
+	start, end := dayBounds(from, from.AddDate(0, 0, days-1), loc)
+	appointments, err := h.Appointments.List(ctx, repository.AppointmentFilter{
+		DoctorID:   doctor.ID,
+		From:       start,
+		Until:      end,
+		ActiveOnly: c.Query("include_cancelled") != "true",
+		Ascending:  true,
+	})
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch agenda", err))
+		return
+	}
+
+	agenda := make([]models.DayAgenda, days)
+	for i := range agenda {
+		date := from.AddDate(0, 0, i)
+		agenda[i] = models.DayAgenda{
+			Date:         date.Format(dateLayout),
+			Weekday:      date.Weekday().String(),
+			Appointments: []models.Appointment{},
+		}
+	}
+	h.localizeAppointments(appointments)
+	for _, appointment := range appointments {
+		i := int(civilDate(appointment.AppointmentDate, loc).Sub(from).Hours() / 24)
+		if i >= 0 && i < days {
+			agenda[i].Appointments = append(agenda[i].Appointments, appointment)
+		}
+	}
+
+	if days == 1 {
+		respond(c, http.StatusOK, "Agenda fetched successfully", gin.H{
+			"doctor_id":    doctor.ID,
+			"date":         agenda[0].Date,
+			"weekday":      agenda[0].Weekday,
+			"timezone":     loc.String(),
+			"appointments": agenda[0].Appointments,
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, "Agenda fetched successfully", gin.H{
+		"doctor_id": doctor.ID,
+		"from":      agenda[0].Date,
+		"to":        agenda[days-1].Date,
+		"timezone":  loc.String(),
+		"days":      agenda,
+	})
+}
+
+// UpdateVisitStatus marks one of the authenticated doctor's appointments
+// completed or no-show
+func (h *Handler) UpdateVisitStatus(c *gin.Context) {
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid appointment ID"))
+		return
+	}
+
+	var req models.UpdateAppointmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	if req.Status != models.StatusCompleted && req.Status != models.StatusNoShow {
+		respondError(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidRequest, fmt.Sprintf("Status must be %s or %s", models.StatusCompleted, models.StatusNoShow)))
+		return
+	}
+
+	userID, _, _ := authenticatedUser(c)
+	appointment, err := h.Appointments.Update(c.Request.Context(), appointmentID, userID, func(appointment *models.Appointment) error {
+		if appointment.Doctor == nil || appointment.Doctor.UserID != userID {
+			return repository.ErrNotFound
+		}
+
+		if err := models.CheckTransition(appointment.Status, req.Status, models.RoleDoctor); err != nil {
+			return err
+		}
+
+		appointment.Status = req.Status
+		return nil
+	})
+
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+	h.localizeAppointment(appointment)
+
+	respond(c, http.StatusOK, "Appointment updated successfully", gin.H{
+		"appointment": appointment,
+	})
+}
+
+// AddClinicalNotes stores the authenticated doctor's clinical notes on one
+// of their appointments. Cancelled appointments cannot be annotated.
+func (h *Handler) AddClinicalNotes(c *gin.Context) {
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid appointment ID"))
+		return
+	}
+
+	var req models.ClinicalNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.ClinicalNotes) == "" {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "clinical_notes is required"))
+		return
+	}
+
+	userID, _, _ := authenticatedUser(c)
+	appointment, err := h.Appointments.Update(c.Request.Context(), appointmentID, userID, func(appointment *models.Appointment) error {
+		if appointment.Doctor == nil || appointment.Doctor.UserID != userID {
+			return repository.ErrNotFound
+		}
+
+		if appointment.Status == models.StatusCancelled {
+			return &models.TransitionError{Reason: "Cancelled appointments cannot have clinical notes"}
+		}
+
+		appointment.ClinicalNotes = req.ClinicalNotes
+		return nil
+	})
+
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+	h.localizeAppointment(appointment)
+	h.appointmentEvent(models.EventNotesAdded, *appointment)
+
+	respond(c, http.StatusOK, "Clinical notes saved successfully", gin.H{
+		"appointment": appointment,
+	})
+}
+
+// UpdateAvailableSlots replaces the authenticated doctor's weekly slot
+// template. Weekdays are normalized ("monday" becomes "Monday") and slots
+// are deduplicated and sorted; existing bookings are left untouched. The
+// template replaces any working hours. The change is recorded in the audit
+// log.
+func (h *Handler) UpdateAvailableSlots(c *gin.Context) {
+	var req models.UpdateAvailableSlotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	slots, err := normalizeSlots(req.AvailableSlots)
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, sentence(err)))
+		return
+	}
+
+	doctor, err := h.currentDoctor(c)
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	userID, _, _ := authenticatedUser(c)
+	doctor, err = h.Doctors.Update(c.Request.Context(), doctor.ID, userID, func(doctor *models.Doctor) error {
+		doctor.AvailableSlots = slots
+		doctor.WorkingHours = nil
+		return nil
+	})
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, "Available slots updated successfully", gin.H{
+		"doctor": doctor,
+	})
+}
+
+// UpdateWorkingHours replaces the authenticated doctor's working hours,
+// slot duration and buffer. Working hours regenerate the weekly
+// available_slots; an empty working_hours keeps the current slots and only
+// changes the slot settings. Existing bookings are left untouched.
+func (h *Handler) UpdateWorkingHours(c *gin.Context) {
+	var req models.UpdateWorkingHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	hours, err := normalizeWorkingHours(req.WorkingHours)
+	if err != nil {
+		respondInvalidFields(c, map[string]string{"working_hours": err.Error()})
+		return
+	}
+
+	doctor, err := h.currentDoctor(c)
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	userID, _, _ := authenticatedUser(c)
+	doctor, err = h.Doctors.Update(c.Request.Context(), doctor.ID, userID, func(doctor *models.Doctor) error {
+		doctor.SlotDuration = req.SlotDuration
+		doctor.BufferMinutes = req.BufferMinutes
+		if hours != nil {
+			doctor.WorkingHours = hours
+		}
+		applyWorkingHours(doctor)
+		return nil
+	})
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, "Working hours updated successfully", gin.H{
+		"doctor": doctor,
+	})
+}
+
+// currentDoctor returns the doctor profile of the authenticated user
+func (h *Handler) currentDoctor(c *gin.Context) (*models.Doctor, error) {
+	userID, _, _ := authenticatedUser(c)
+	doctor, err := h.Doctors.GetByUserID(c.Request.Context(), userID)
+	if err == repository.ErrNotFound {
+		return nil, errDoctorProfileNotFound
+	}
+	return doctor, err
+}
+
+// respondDoctorError maps the errors of the doctor endpoints to responses
+func respondDoctorError(c *gin.Context, err error) {
+	var transitionErr *models.TransitionError
+	switch {
+	case err == errDoctorProfileNotFound:
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeDoctorNotFound, sentence(err)))
+	case err == repository.ErrNotFound:
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeAppointmentNotFound, "Appointment not found"))
+	case errors.As(err, &transitionErr):
+		respondError(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidTransition, transitionErr.Error()))
+	default:
+		respondError(c, apierror.Internal("Failed to process request", err))
+	}
+}
+
+// canonicalWeekday returns the weekday named day ("monday", " Monday") as
+// time.Weekday.String spells it
+func canonicalWeekday(day string) (string, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+			return weekday.String(), true
+		}
+	}
+	return "", false
+}
+
+// normalizeSlots validates a weekly slot template and returns it with
+// canonical weekday names and sorted, unique "HH:MM" slots
+func normalizeSlots(template map[string][]string) (map[string][]string, error) {
+	normalized := make(map[string][]string, len(template))
+	for day, slots := range template {
+		weekday, ok := canonicalWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", day)
+		}
+
+		seen := make(map[string]bool, len(slots))
+		for _, slot := range append(normalized[weekday], slots...) {
+			parsed, err := time.Parse(slotLayout, strings.TrimSpace(slot))
+			if err != nil {
+				return nil, fmt.Errorf("invalid slot %q on %s. Use HH:MM format", slot, weekday)
+			}
+			seen[parsed.Format(slotLayout)] = true
+		}
+
+		unique := make([]string, 0, len(seen))
+		for slot := range seen {
+			unique = append(unique, slot)
+		}
+		sort.Strings(unique)
+		normalized[weekday] = unique
+	}
+
+	return normalized, nil
+}
+
+// normalizeWorkingHours validates working hours and returns them with
+// canonical weekday names and "HH:MM" times, each day's periods sorted. It
+// returns nil for empty working hours.
+func normalizeWorkingHours(hours map[string][]models.WorkingPeriod) (map[string][]models.WorkingPeriod, error) {
+	if len(hours) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string][]models.WorkingPeriod, len(hours))
+	for day, periods := range hours {
+		weekday, ok := canonicalWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", day)
+		}
+
+		for _, period := range periods {
+			start, startErr := time.Parse(slotLayout, strings.TrimSpace(period.Start))
+			end, endErr := time.Parse(slotLayout, strings.TrimSpace(period.End))
+			if startErr != nil || endErr != nil {
+				return nil, fmt.Errorf("invalid period %s-%s on %s. Use HH:MM format", period.Start, period.End, weekday)
+			}
+			if !end.After(start) {
+				return nil, fmt.Errorf("period %s-%s on %s must end after it starts", period.Start, period.End, weekday)
+			}
+			normalized[weekday] = append(normalized[weekday], models.WorkingPeriod{
+				Start: start.Format(slotLayout),
+				End:   end.Format(slotLayout),
+			})
+		}
+
+		dayPeriods := normalized[weekday]
+		sort.Slice(dayPeriods, func(i, j int) bool { return dayPeriods[i].Start < dayPeriods[j].Start })
+		for i := 1; i < len(dayPeriods); i++ {
+			if dayPeriods[i].Start < dayPeriods[i-1].End {
+				return nil, fmt.Errorf("periods %s-%s and %s-%s on %s overlap", dayPeriods[i-1].Start,
+					dayPeriods[i-1].End, dayPeriods[i].Start, dayPeriods[i].End, weekday)
+			}
+		}
+	}
+
+	return normalized, nil
+}
+
+// applyWorkingHours regenerates the doctor's available_slots from its
+// working hours, if it has any: within each period a slot starts every
+// slot duration plus buffer, as long as the slot ends within the period
+func applyWorkingHours(doctor *models.Doctor) {
+	if len(doctor.WorkingHours) == 0 {
+		doctor.WorkingHours = nil
+		return
+	}
+
+	duration, buffer := slotSettings(doctor)
+	step := int((duration + buffer) / time.Minute)
+	length := int(duration / time.Minute)
+
+	slots := make(map[string][]string, len(doctor.WorkingHours))
+	for weekday, periods := range doctor.WorkingHours {
+		daySlots := []string{}
+		for _, period := range periods {
+			end := clockMinutes(period.End)
+			for start := clockMinutes(period.Start); start >= 0 && start+length <= end; start += step {
+				daySlots = append(daySlots, fmt.Sprintf("%02d:%02d", start/60, start%60))
+			}
+		}
+		slots[weekday] = daySlots
+	}
+	doctor.AvailableSlots = slots
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"hospital-backend/apierror"
+	"hospital-backend/middleware"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError answers the request with err in the envelope of the route
+// and stops the handler chain; see middleware.Errors
+func respondError(c *gin.Context, err error) {
+	middleware.AbortWithError(c, err)
+}
+
+// appointmentError maps the errors of booking, holding and changing
+// appointments to API errors. Unknown errors become internal errors with
+// message.
+func appointmentError(err error, message string) *apierror.Error {
+	var invalidDate *invalidDateError
+	var unavailable *slotUnavailableError
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.As(err, &invalidDate):
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidDate, invalidDate.Error())
+	case errors.As(err, &unavailable):
+		return apierror.New(http.StatusBadRequest, apierror.CodeSlotUnavailable, unavailable.Error()).
+			WithDetail("valid_slots", unavailable.ValidSlots)
+	case errors.As(err, &transitionErr):
+		return apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidTransition, transitionErr.Error())
+	case err == errDoctorNotFound:
+		return apierror.New(http.StatusBadRequest, apierror.CodeDoctorNotFound, "Doctor not found")
+	case err == errInvalidHoldToken:
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidHoldToken, sentence(err))
+	case err == errHoldMismatch:
+		return apierror.New(http.StatusBadRequest, apierror.CodeHoldMismatch, sentence(err))
+	case err == repository.ErrNotFound:
+		return apierror.New(http.StatusNotFound, apierror.CodeAppointmentNotFound, "Appointment not found")
+	case err == repository.ErrSlotTaken:
+		return apierror.New(http.StatusConflict, apierror.CodeSlotTaken, "Appointment slot is already booked")
+	case err == repository.ErrSlotHeld:
+		return apierror.New(http.StatusConflict, apierror.CodeSlotHeld, "Appointment slot is temporarily held by another patient")
+	case err == repository.ErrHoldExpired:
+		return apierror.New(http.StatusConflict, apierror.CodeHoldExpired, "Slot hold has expired or does not exist")
+	default:
+		return apierror.Internal(message, err)
+	}
+}
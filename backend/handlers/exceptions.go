@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultCalendarDays is the range of exception and holiday listings
+	// when "to" is omitted
+	defaultCalendarDays = 90
+	// maxCalendarDays caps the range of a single listing
+	maxCalendarDays = 366
+)
+
+// ListScheduleExceptions returns the authenticated doctor's schedule
+// exceptions between the "from" and "to" dates (inclusive)
+func (h *Handler) ListScheduleExceptions(c *gin.Context) {
+	from, to, err := h.parseDateRange(c, defaultCalendarDays, maxCalendarDays)
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, sentence(err)))
+		return
+	}
+
+	doctor, err := h.currentDoctor(c)
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	exceptions, err := h.Schedules.ListExceptions(c.Request.Context(), doctor.ID, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch schedule exceptions", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Schedule exceptions fetched successfully", gin.H{
+		"exceptions": exceptions,
+	})
+}
+
+// CreateScheduleException blocks time or adds extra slots on one date for
+// the authenticated doctor. Booked appointments whose slot a block removes
+// are flagged for rescheduling.
+func (h *Handler) CreateScheduleException(c *gin.Context) {
+	var req models.CreateScheduleExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	doctor, err := h.currentDoctor(c)
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	loc := h.location(doctor)
+	exception, date, fields := validateScheduleException(req, today(loc))
+	if fields != nil {
+		respondInvalidFields(c, fields)
+		return
+	}
+
+	ctx := c.Request.Context()
+	exception.DoctorID = doctor.ID
+	if err := h.Schedules.CreateException(ctx, exception); err != nil {
+		respondError(c, apierror.Internal("Failed to create schedule exception", err))
+		return
+	}
+
+	flagged := []uuid.UUID{}
+	if exception.Kind == models.ExceptionBlock {
+		start, end := dayBounds(date, date, loc)
+		appointments, err := h.Appointments.List(ctx, repository.AppointmentFilter{
+			DoctorID:   doctor.ID,
+			From:       start,
+			Until:      end,
+			ActiveOnly: true,
+		})
+		if err == nil {
+			userID, _, _ := authenticatedUser(c)
+			flagged, err = h.flagForReschedule(ctx, userID, appointments)
+		}
+		if err != nil {
+			respondError(c, apierror.Internal("Failed to flag affected appointments", err))
+			return
+		}
+	}
+
+	respond(c, http.StatusCreated, "Schedule exception created successfully", gin.H{
+		"exception":            exception,
+		"flagged_appointments": flagged,
+	})
+}
+
+// DeleteScheduleException removes one of the authenticated doctor's
+// schedule exceptions. Appointments already flagged for rescheduling stay
+// flagged.
+func (h *Handler) DeleteScheduleException(c *gin.Context) {
+	exceptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid exception ID"))
+		return
+	}
+
+	doctor, err := h.currentDoctor(c)
+	if err != nil {
+		respondDoctorError(c, err)
+		return
+	}
+
+	err = h.Schedules.DeleteException(c.Request.Context(), doctor.ID, exceptionID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Schedule exception not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to delete schedule exception", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Schedule exception deleted successfully", nil)
+}
+
+// ListHolidays returns the hospital holidays between the "from" and "to"
+// dates (inclusive)
+func (h *Handler) ListHolidays(c *gin.Context) {
+	from, to, err := h.parseDateRange(c, defaultCalendarDays, maxCalendarDays)
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, sentence(err)))
+		return
+	}
+
+	holidays, err := h.Schedules.ListHolidays(c.Request.Context(), from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch holidays", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Holidays fetched successfully", gin.H{
+		"holidays": holidays,
+	})
+}
+
+// CreateHoliday adds a hospital-wide holiday (admin only). Booked
+// appointments that no longer fit the affected doctors' schedules are
+// flagged for rescheduling.
+func (h *Handler) CreateHoliday(c *gin.Context) {
+	var req models.CreateHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	date, err := time.Parse(dateLayout, req.Date)
+	if err != nil {
+		respondInvalidFields(c, map[string]string{"date": "must be a date in YYYY-MM-DD format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	holiday := &models.Holiday{Date: req.Date, Name: strings.TrimSpace(req.Name)}
+	err = h.Schedules.CreateHoliday(ctx, holiday)
+	if err == repository.ErrConflict {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "A holiday already exists on this date"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to create holiday", err))
+		return
+	}
+
+	// Doctors read the date in their own timezones, so list every
+	// appointment that could fall on it in any zone and let
+	// flagForReschedule check each against its doctor's schedule
+	appointments, err := h.Appointments.List(ctx, repository.AppointmentFilter{
+		From:       date.AddDate(0, 0, -1),
+		Until:      date.AddDate(0, 0, 2),
+		ActiveOnly: true,
+	})
+	var flagged []uuid.UUID
+	if err == nil {
+		userID, _, _ := authenticatedUser(c)
+		flagged, err = h.flagForReschedule(ctx, userID, appointments)
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to flag affected appointments", err))
+		return
+	}
+
+	respond(c, http.StatusCreated, "Holiday created successfully", gin.H{
+		"holiday":              holiday,
+		"flagged_appointments": flagged,
+	})
+}
+
+// DeleteHoliday removes a hospital holiday (admin only)
+func (h *Handler) DeleteHoliday(c *gin.Context) {
+	holidayID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid holiday ID"))
+		return
+	}
+
+	err = h.Schedules.DeleteHoliday(c.Request.Context(), holidayID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Holiday not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to delete holiday", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Holiday deleted successfully", nil)
+}
+
+// validateScheduleException checks a schedule exception request and returns
+// the exception to store with its date, or a description of each invalid
+// field. today is the current date in the doctor's timezone.
+func validateScheduleException(req models.CreateScheduleExceptionRequest, today time.Time) (*models.ScheduleException, time.Time, map[string]string) {
+	fields := map[string]string{}
+
+	date, err := time.Parse(dateLayout, req.Date)
+	if err != nil {
+		fields["date"] = "must be a date in YYYY-MM-DD format"
+	} else if date.Before(today) {
+		fields["date"] = "must not be in the past"
+	}
+
+	exception := &models.ScheduleException{
+		Date:   req.Date,
+		Kind:   req.Kind,
+		Reason: strings.TrimSpace(req.Reason),
+	}
+
+	switch req.Kind {
+	case models.ExceptionBlock:
+		if len(req.Slots) > 0 {
+			fields["slots"] = "must be empty for a block"
+		}
+		if req.StartTime == "" && req.EndTime == "" {
+			break
+		}
+		start, startErr := time.Parse(slotLayout, req.StartTime)
+		end, endErr := time.Parse(slotLayout, req.EndTime)
+		switch {
+		case startErr != nil:
+			fields["start_time"] = "must be a time in HH:MM format"
+		case endErr != nil:
+			fields["end_time"] = "must be a time in HH:MM format"
+		case !end.After(start):
+			fields["end_time"] = "must be after start_time"
+		default:
+			exception.StartTime = start.Format(slotLayout)
+			exception.EndTime = end.Format(slotLayout)
+		}
+
+	case models.ExceptionExtra:
+		if req.StartTime != "" || req.EndTime != "" {
+			fields["start_time"] = "must be empty for extra slots"
+		}
+		if len(req.Slots) == 0 {
+			fields["slots"] = "is required for extra slots"
+			break
+		}
+		weekday := date.Weekday().String()
+		slots, err := normalizeSlots(map[string][]string{weekday: req.Slots})
+		if err != nil {
+			fields["slots"] = err.Error()
+			break
+		}
+		exception.Slots = slots[weekday]
+	}
+
+	if len(fields) > 0 {
+		return nil, time.Time{}, fields
+	}
+	return exception, date, nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+)
+
+const (
+	// DefaultHoldTTL is how long a slot hold lasts when Config.HoldTTL is
+	// zero
+	DefaultHoldTTL = 5 * time.Minute
+	// DefaultOfferTTL is how long a waitlist offer lasts when
+	// Config.OfferTTL is zero
+	DefaultOfferTTL = 30 * time.Minute
+)
+
+const (
+	// freedQueueSize bounds the freed slots waiting for the waitlist worker
+	freedQueueSize = 256
+	// eventQueueSize bounds the appointment events waiting for the push
+	// worker
+	eventQueueSize = 1024
+)
+
+// Config holds the settings of a Handler
+type Config struct {
+	// Location is the hospital timezone, used for doctors without their own
+	Location *time.Location
+	// HoldTTL is how long a slot hold reserves the slot
+	HoldTTL time.Duration
+	// OfferTTL is how long a waitlisted patient has to accept an offer
+	OfferTTL time.Duration
+	// Push queues appointment events for the push worker, which must then
+	// drain AppointmentEvents
+	Push bool
+}
+
+// Handler serves the API routes from the repositories it is given
+type Handler struct {
+	Doctors      repository.DoctorRepository
+	Appointments repository.AppointmentRepository
+	Users        repository.UserRepository
+	Audit        repository.AuditRepository
+	Schedules    repository.ScheduleRepository
+	Holds        repository.HoldRepository
+	Waitlist     repository.WaitlistRepository
+	Reminders    repository.ReminderRepository
+	Devices      repository.DeviceRepository
+	// Location is the hospital timezone, used for doctors without their own
+	Location *time.Location
+	// HoldTTL is how long a slot hold reserves the slot
+	HoldTTL time.Duration
+	// OfferTTL is how long a waitlisted patient has to accept an offer
+	OfferTTL time.Duration
+
+	// freed queues the slots freed by cancellations for the waitlist
+	// worker
+	freed chan models.Appointment
+	// events queues appointment changes for the push worker. It is nil
+	// when push notifications are off.
+	events chan models.AppointmentEvent
+}
+
+// New returns a Handler backed by store with the settings of config
+func New(store repository.Store, config Config) *Handler {
+	location := config.Location
+	if location == nil {
+		location = time.UTC
+	}
+	holdTTL := config.HoldTTL
+	if holdTTL <= 0 {
+		holdTTL = DefaultHoldTTL
+	}
+	offerTTL := config.OfferTTL
+	if offerTTL <= 0 {
+		offerTTL = DefaultOfferTTL
+	}
+
+	var events chan models.AppointmentEvent
+	if config.Push {
+		events = make(chan models.AppointmentEvent, eventQueueSize)
+	}
+
+	return &Handler{
+		Doctors:      store.Doctors,
+		Appointments: store.Appointments,
+		Users:        store.Users,
+		Audit:        store.Audit,
+		Schedules:    store.Schedules,
+		Holds:        store.Holds,
+		Waitlist:     store.Waitlist,
+		Reminders:    store.Reminders,
+		Devices:      store.Devices,
+		Location:     location,
+		HoldTTL:      holdTTL,
+		OfferTTL:     offerTTL,
+		freed:        make(chan models.Appointment, freedQueueSize),
+		events:       events,
+	}
+}
@@ -1,209 +1,121 @@
 package handlers
 
 import (
-	"database/sql"
-	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
 
-	"hospital-backend/database"
+	"hospital-backend/apierror"
 	"hospital-backend/models"
+	"hospital-backend/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// GetDoctors returns all doctors
-func GetDoctors(c *gin.Context) {
-	query := `
-		SELECT d.id, d.user_id, d.specialization, d.experience, d.phone, 
-		       d.available_slots, d.created_at, d.updated_at,
-		       u.name, u.email
-		FROM doctors d
-		JOIN users u ON d.user_id = u.id
-		ORDER BY u.name
-	`
-
-	rows, err := database.DB.Query(query)
+// GetDoctors returns all active doctors at once, as /api/doctors always
+// has; ListDoctors pages them
+func (h *Handler) GetDoctors(c *gin.Context) {
+	doctors, err := h.Doctors.List(c.Request.Context(), repository.DoctorFilter{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch doctors",
-		})
+		respondError(c, apierror.Internal("Failed to fetch doctors", err))
 		return
 	}
-	defer rows.Close()
-
-	var doctors []models.Doctor
-	for rows.Next() {
-		var doctor models.Doctor
-		var user models.User
-		var slotsJSON string
-
-		err := rows.Scan(
-			&doctor.ID, &doctor.UserID, &doctor.Specialization, &doctor.Experience,
-			&doctor.Phone, &slotsJSON, &doctor.CreatedAt, &doctor.UpdatedAt,
-			&user.Name, &user.Email,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to scan doctor data",
-			})
-			return
-		}
-
-		// Parse available slots JSON
-		if err := json.Unmarshal([]byte(slotsJSON), &doctor.AvailableSlots); err != nil {
-			doctor.AvailableSlots = make(map[string][]string)
-		}
-
-		user.ID = doctor.UserID
-		doctor.User = &user
-		doctors = append(doctors, doctor)
-	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, "Doctors fetched successfully", gin.H{
 		"doctors": doctors,
 	})
 }
 
-// GetDoctorByID returns a specific doctor by ID
-func GetDoctorByID(c *gin.Context) {
-	doctorID := c.Param("id")
-	if doctorID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Doctor ID is required",
-		})
+// GetDoctorByID returns a specific active doctor by ID
+func (h *Handler) GetDoctorByID(c *gin.Context) {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid doctor ID"))
 		return
 	}
 
-	query := `
-		SELECT d.id, d.user_id, d.specialization, d.experience, d.phone, 
-		       d.available_slots, d.created_at, d.updated_at,
-		       u.name, u.email
-		FROM doctors d
-		JOIN users u ON d.user_id = u.id
-		WHERE d.id = $1
-	`
-
-	var doctor models.Doctor
-	var user models.User
-	var slotsJSON string
-
-	err := database.DB.QueryRow(query, doctorID).Scan(
-		&doctor.ID, &doctor.UserID, &doctor.Specialization, &doctor.Experience,
-		&doctor.Phone, &slotsJSON, &doctor.CreatedAt, &doctor.UpdatedAt,
-		&user.Name, &user.Email,
-	)
-
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Doctor not found",
-		})
+	doctor, err := h.Doctors.Get(c.Request.Context(), doctorID)
+	if err == repository.ErrNotFound || err == nil && !doctor.Active {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeDoctorNotFound, "Doctor not found"))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch doctor",
-		})
+		respondError(c, apierror.Internal("Failed to fetch doctor", err))
 		return
 	}
 
-	// Parse available slots JSON
-	if err := json.Unmarshal([]byte(slotsJSON), &doctor.AvailableSlots); err != nil {
-		doctor.AvailableSlots = make(map[string][]string)
-	}
-
-	user.ID = doctor.UserID
-	doctor.User = &user
-
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, "Doctor fetched successfully", gin.H{
 		"doctor": doctor,
 	})
 }
 
-// CreateAppointment creates a new appointment
-func CreateAppointment(c *gin.Context) {
-	var req models.CreateAppointmentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data",
-		})
+// CreateAppointment books a slot for the authenticated patient and
+// returns the appointment
+func (h *Handler) CreateAppointment(c *gin.Context) {
+	appointment, _, ok := h.createAppointment(c)
+	if !ok {
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
-		return
-	}
+	respond(c, http.StatusCreated, "Appointment created successfully", gin.H{
+		"appointment": appointment,
+	})
+}
 
-	// Parse appointment date
-	appointmentDate, err := time.Parse("2006-01-02T15:04:05Z", req.AppointmentDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid date format. Use ISO 8601 format",
-		})
+// CreateAppointmentLegacy is CreateAppointment answering with the
+// appointment's fields beside the message, as /api/appointments always has
+func (h *Handler) CreateAppointmentLegacy(c *gin.Context) {
+	appointment, _, ok := h.createAppointment(c)
+	if !ok {
 		return
 	}
 
-	// Check if doctor exists
-	var doctorExists bool
-	err = database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM doctors WHERE id = $1)", req.DoctorID).Scan(&doctorExists)
-	if err != nil || !doctorExists {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Doctor not found",
-		})
-		return
-	}
+	respond(c, http.StatusCreated, "Appointment created successfully", gin.H{
+		"appointment_id":   appointment.ID,
+		"appointment_date": appointment.AppointmentDate,
+		"ends_at":          appointment.EndsAt,
+		"appointment_type": appointment.Type,
+		"local_time":       appointment.LocalTime,
+		"timezone":         appointment.Timezone,
+	})
+}
 
-	// Check if appointment slot is available
-	var existingAppointment string
-	err = database.DB.QueryRow(`
-		SELECT id FROM appointments 
-		WHERE doctor_id = $1 AND appointment_date = $2 AND slot = $3 AND status != 'cancelled'
-	`, req.DoctorID, appointmentDate, req.Slot).Scan(&existingAppointment)
-
-	if err != sql.ErrNoRows {
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "Appointment slot is already booked",
-		})
-		return
+// createAppointment books the slot in the request body for the
+// authenticated patient. When booking fails it answers the request and
+// returns false.
+func (h *Handler) createAppointment(c *gin.Context) (*models.Appointment, models.CreateAppointmentRequest, bool) {
+	var req models.CreateAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return nil, req, false
 	}
 
-	// Create appointment
-	appointmentID := uuid.New()
-	query := `
-		INSERT INTO appointments (id, doctor_id, patient_id, appointment_date, slot, status, notes)
-		VALUES ($1, $2, $3, $4, $5, 'scheduled', $6)
-	`
+	// Get user ID from context (set by auth middleware)
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return nil, req, false
+	}
 
-	_, err = database.DB.Exec(query, appointmentID, req.DoctorID, userID, appointmentDate, req.Slot, req.Notes)
+	// Book the slot
+	appointment, err := h.bookAppointment(c.Request.Context(), userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create appointment",
-		})
-		return
+		respondError(c, appointmentError(err, "Failed to create appointment"))
+		return nil, req, false
 	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message":     "Appointment created successfully",
-		"appointment_id": appointmentID,
-	})
+	return appointment, req, true
 }
 
-// GetUserAppointments returns appointments for the authenticated user
-func GetUserAppointments(c *gin.Context) {
+// GetUserAppointments returns appointments for the authenticated user by
+// limit and offset, as /api/appointments always has; ListAppointments
+// pages them
+func (h *Handler) GetUserAppointments(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, _, exists := authenticatedUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
 		return
 	}
 
@@ -221,202 +133,182 @@ func GetUserAppointments(c *gin.Context) {
 		offset = 0
 	}
 
-	query := `
-		SELECT a.id, a.doctor_id, a.patient_id, a.appointment_date, a.slot, 
-		       a.status, a.notes, a.created_at, a.updated_at,
-		       d.specialization, u.name as doctor_name, u.email as doctor_email
-		FROM appointments a
-		JOIN doctors d ON a.doctor_id = d.id
-		JOIN users u ON d.user_id = u.id
-		WHERE a.patient_id = $1
-		ORDER BY a.appointment_date DESC
-		LIMIT $2 OFFSET $3
-	`
-
-	rows, err := database.DB.Query(query, userID, limit, offset)
+	appointments, err := h.Appointments.List(c.Request.Context(), repository.AppointmentFilter{
+		PatientID: userID,
+		Limit:     limit,
+		Offset:    offset,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch appointments",
-		})
+		respondError(c, apierror.Internal("Failed to fetch appointments", err))
 		return
 	}
-	defer rows.Close()
-
-	var appointments []models.Appointment
-	for rows.Next() {
-		var appointment models.Appointment
-		var doctor models.Doctor
-		var user models.User
-
-		err := rows.Scan(
-			&appointment.ID, &appointment.DoctorID, &appointment.PatientID,
-			&appointment.AppointmentDate, &appointment.Slot, &appointment.Status,
-			&appointment.Notes, &appointment.CreatedAt, &appointment.UpdatedAt,
-			&doctor.Specialization, &user.Name, &user.Email,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to scan appointment data",
-			})
-			return
-		}
-
-		doctor.ID = appointment.DoctorID
-		doctor.User = &user
-		appointment.Doctor = &doctor
-		appointments = append(appointments, appointment)
-	}
+	h.localizeAppointments(appointments)
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, "Appointments fetched successfully", gin.H{
 		"appointments": appointments,
 	})
 }
 
-// UpdateAppointment updates an existing appointment
-func UpdateAppointment(c *gin.Context) {
-	appointmentID := c.Param("id")
-	if appointmentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Appointment ID is required",
-		})
+// UpdateAppointment updates an existing appointment. Status changes go
+// through the appointment state machine and are recorded in
+// appointment_status_history; changing the date or slot reschedules.
+func (h *Handler) UpdateAppointment(c *gin.Context) {
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid appointment ID"))
 		return
 	}
 
 	var req models.UpdateAppointmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data",
-		})
+		respondInvalidRequest(c, err)
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	// Get user from context
+	userID, role, exists := authenticatedUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
 		return
 	}
 
-	// Check if appointment exists and belongs to user
-	var existingAppointmentID string
-	err := database.DB.QueryRow(`
-		SELECT id FROM appointments 
-		WHERE id = $1 AND patient_id = $2
-	`, appointmentID, userID).Scan(&existingAppointmentID)
-
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Appointment not found",
-		})
-		return
-	}
+	ctx := c.Request.Context()
+	var before models.Appointment
+	updated, err := h.Appointments.Update(ctx, appointmentID, userID, func(appointment *models.Appointment) error {
+		actor := actorFor(appointment, userID, role)
+		if actor == "" {
+			return repository.ErrNotFound
+		}
+		before = *appointment
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to check appointment",
-		})
-		return
-	}
+		doctor, err := h.Doctors.Get(ctx, appointment.DoctorID)
+		if err != nil {
+			return err
+		}
+		loc := h.location(doctor)
+
+		// Dates and slots are compared in the doctor's timezone
+		newSlot := appointment.Slot
+		if req.Slot != "" {
+			newSlot = req.Slot
+		}
+		newDate := civilDate(appointment.AppointmentDate, loc)
+		if req.AppointmentDate != "" {
+			newDate, err = appointmentDay(req.AppointmentDate, newSlot, loc)
+			if err != nil {
+				return err
+			}
+		}
+		// An unparseable slot is left to validateSlot to reject
+		newStart := appointment.AppointmentDate
+		if newSlot != appointment.Slot || !newDate.Equal(civilDate(appointment.AppointmentDate, loc)) {
+			if start, err := slotStart(newDate, newSlot, loc); err == nil {
+				newStart = start
+			}
+		}
+		rescheduling := !newStart.Equal(appointment.AppointmentDate) || newSlot != appointment.Slot
 
-	// Build update query dynamically
-	query := "UPDATE appointments SET updated_at = NOW()"
-	args := []interface{}{}
-	argIndex := 1
+		// Moving the date or slot is a reschedule
+		newStatus := req.Status
+		if newStatus == "" && rescheduling {
+			newStatus = models.StatusRescheduled
+		}
 
-	if req.AppointmentDate != "" {
-		appointmentDate, err := time.Parse("2006-01-02T15:04:05Z", req.AppointmentDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid date format",
-			})
-			return
+		statusChanged := newStatus != "" && (newStatus != appointment.Status || newStatus == models.StatusRescheduled)
+		if statusChanged {
+			if err := models.CheckTransition(appointment.Status, newStatus, actor); err != nil {
+				return err
+			}
 		}
-		query += ", appointment_date = $" + strconv.Itoa(argIndex)
-		args = append(args, appointmentDate)
-		argIndex++
-	}
 
-	if req.Slot != "" {
-		query += ", slot = $" + strconv.Itoa(argIndex)
-		args = append(args, req.Slot)
-		argIndex++
-	}
+		if newStatus == models.StatusRescheduled && !rescheduling {
+			return &models.TransitionError{Reason: "Rescheduling requires a new appointment_date or slot"}
+		}
 
-	if req.Status != "" {
-		query += ", status = $" + strconv.Itoa(argIndex)
-		args = append(args, req.Status)
-		argIndex++
-	}
+		if rescheduling && newStatus != models.StatusRescheduled {
+			return &models.TransitionError{Reason: "Changing the appointment_date or slot requires status rescheduled"}
+		}
 
-	if req.Notes != "" {
-		query += ", notes = $" + strconv.Itoa(argIndex)
-		args = append(args, req.Notes)
-		argIndex++
-	}
+		// A reschedule must land on a slot the doctor works on that date
+		if rescheduling {
+			duration := time.Duration(appointment.DurationMinutes) * time.Minute
+			if err := h.validateSlot(ctx, doctor, newDate, newSlot, duration); err != nil {
+				return err
+			}
+			_, buffer := slotSettings(doctor)
+			appointment.OccupiedUntil = newStart.Add(duration + buffer)
+			appointment.RescheduleRequired = false
+		}
 
-	query += " WHERE id = $" + strconv.Itoa(argIndex)
-	args = append(args, appointmentID)
+		appointment.AppointmentDate = newStart
+		appointment.Slot = newSlot
+		if statusChanged {
+			appointment.Status = newStatus
+		}
+		if req.Notes != "" {
+			appointment.Notes = req.Notes
+		}
+		return nil
+	})
 
-	_, err = database.DB.Exec(query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to update appointment",
-		})
+		respondError(c, appointmentError(err, "Failed to update appointment"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Appointment updated successfully",
-	})
+	// Cancelled or moved time can go to the waitlist
+	if before.Status != models.StatusCancelled &&
+		(updated.Status == models.StatusCancelled || !updated.AppointmentDate.Equal(before.AppointmentDate)) {
+		h.slotFreed(before)
+	}
+
+	switch {
+	case before.Status != models.StatusCancelled && updated.Status == models.StatusCancelled:
+		h.appointmentEvent(models.EventCancelled, *updated)
+	case !updated.AppointmentDate.Equal(before.AppointmentDate):
+		h.appointmentEvent(models.EventRescheduled, *updated)
+	}
+
+	respond(c, http.StatusOK, "Appointment updated successfully", nil)
 }
 
 // CancelAppointment cancels an appointment
-func CancelAppointment(c *gin.Context) {
-	appointmentID := c.Param("id")
-	if appointmentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Appointment ID is required",
-		})
+func (h *Handler) CancelAppointment(c *gin.Context) {
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid appointment ID"))
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	// Get user from context
+	userID, role, exists := authenticatedUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
 		return
 	}
 
-	// Update appointment status to cancelled
-	query := `
-		UPDATE appointments 
-		SET status = 'cancelled', updated_at = NOW()
-		WHERE id = $1 AND patient_id = $2
-	`
+	cancelled, err := h.Appointments.Update(c.Request.Context(), appointmentID, userID, func(appointment *models.Appointment) error {
+		actor := actorFor(appointment, userID, role)
+		if actor == "" {
+			return repository.ErrNotFound
+		}
+
+		if err := models.CheckTransition(appointment.Status, models.StatusCancelled, actor); err != nil {
+			return err
+		}
+
+		appointment.Status = models.StatusCancelled
+		return nil
+	})
 
-	result, err := database.DB.Exec(query, appointmentID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to cancel appointment",
-		})
+		respondError(c, appointmentError(err, "Failed to cancel appointment"))
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Appointment not found",
-		})
-		return
-	}
+	// Offer the freed slot to the waitlist
+	h.slotFreed(*cancelled)
+	h.appointmentEvent(models.EventCancelled, *cancelled)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Appointment cancelled successfully",
-	})
+	respond(c, http.StatusOK, "Appointment cancelled successfully", nil)
 }
-
-
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HoldSlot reserves a slot for the patient for HoldTTL while they complete
+// the booking. The returned hold_token is passed to
+// POST /api/mobile/appointments, which consumes it. A new hold on the same
+// doctor replaces the patient's previous one, but not the hold of a pending
+// waitlist offer.
+func (h *Handler) HoldSlot(c *gin.Context) {
+	var req models.HoldSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	appointment, err := h.plannedAppointment(ctx, req.DoctorID, req.AppointmentDate, req.Slot, req.Type)
+	if err != nil {
+		respondError(c, appointmentError(err, "Failed to hold slot"))
+		return
+	}
+
+	hold := &models.SlotHold{
+		DoctorID:      appointment.DoctorID,
+		PatientID:     userID,
+		StartsAt:      appointment.AppointmentDate,
+		OccupiedUntil: appointment.OccupiedUntil,
+		Slot:          appointment.Slot,
+		Type:          appointment.Type,
+		Kind:          models.HoldCheckout,
+		ExpiresAt:     time.Now().Add(h.HoldTTL),
+	}
+	err = h.Holds.Create(ctx, hold)
+	if err == repository.ErrNotFound {
+		// The doctor was removed since plannedAppointment read it
+		err = errDoctorNotFound
+	}
+	if err != nil {
+		respondError(c, appointmentError(err, "Failed to hold slot"))
+		return
+	}
+
+	// Date and time are reported in the doctor's timezone
+	local := hold.StartsAt.In(h.location(appointment.Doctor))
+	respond(c, http.StatusCreated, "Slot held successfully", gin.H{
+		"hold_token":       hold.ID,
+		"doctor_id":        hold.DoctorID,
+		"appointment_date": local.Format(dateLayout),
+		"slot":             hold.Slot,
+		"appointment_type": hold.Type,
+		"start_time_utc":   hold.StartsAt.UTC(),
+		"expires_at":       hold.ExpiresAt.UTC(),
+		"ttl_seconds":      int(h.HoldTTL / time.Second),
+	})
+}
+
+// ReleaseSlotHold gives up the patient's hold before it expires
+func (h *Handler) ReleaseSlotHold(c *gin.Context) {
+	holdID, err := uuid.Parse(c.Param("token"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid hold token"))
+		return
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	err = h.Holds.Release(c.Request.Context(), holdID, userID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Slot hold not found or already expired"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to release slot hold", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Slot hold released", nil)
+}
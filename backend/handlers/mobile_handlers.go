@@ -1,437 +1,294 @@
 package handlers
 
 import (
-	"database/sql"
-	"encoding/json"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
-	"hospital-backend/database"
+	"hospital-backend/apierror"
 	"hospital-backend/models"
+	"hospital-backend/repository"
+	"hospital-backend/textsearch"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
-// Mobile-optimized response structure
+// MobileResponse is the envelope of every /api/v1 and /api/mobile
+// response
 type MobileResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// Failed responses, rendered by middleware.Errors, also carry the
+	// error code, the request ID and any invalid fields
+	Code      string            `json:"code,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
 }
 
+// PaginationInfo describes one page of a paged listing. Pages requested by
+// page number are counted; NextCursor and PrevCursor read the neighbouring
+// pages by keyset, which stay in place as items are added.
 type PaginationInfo struct {
-	Page       int  `json:"page"`
-	Limit      int  `json:"limit"`
-	Total      int  `json:"total"`
-	TotalPages int  `json:"total_pages"`
-	HasNext    bool `json:"has_next"`
-	HasPrev    bool `json:"has_prev"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
-// Enhanced GetDoctors with pagination and mobile optimization
-func GetDoctorsMobile(c *gin.Context) {
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	specialization := c.Query("specialization")
-	
-	// Validate pagination
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 20
-	}
-	
-	offset := (page - 1) * limit
-
-	// Build query with optional specialization filter
-	query := `
-		SELECT d.id, d.user_id, d.specialization, d.experience, d.phone, 
-		       d.available_slots, d.created_at, d.updated_at,
-		       u.name, u.email
-		FROM doctors d
-		JOIN users u ON d.user_id = u.id
-	`
-	
-	args := []interface{}{}
-	if specialization != "" {
-		query += " WHERE d.specialization = $1"
-		args = append(args, specialization)
-		query += " ORDER BY u.name LIMIT $2 OFFSET $3"
-		args = append(args, limit, offset)
-	} else {
-		query += " ORDER BY u.name LIMIT $1 OFFSET $2"
-		args = append(args, limit, offset)
-	}
+// ListDoctors returns one page of active doctors ordered by name,
+// optionally of one specialization. The page is read from the cursor
+// parameter when given, and by page number otherwise.
+func (h *Handler) ListDoctors(c *gin.Context) {
+	page, limit := pageParams(c)
+	filter := repository.DoctorFilter{Specialization: c.Query("specialization")}
+	ctx := c.Request.Context()
 
-	// Get total count for pagination
-	countQuery := "SELECT COUNT(*) FROM doctors d JOIN users u ON d.user_id = u.id"
-	if specialization != "" {
-		countQuery += " WHERE d.specialization = $1"
+	from, err := cursorParam(c, func(cur *cursor) bool { return cur.Doctor != nil })
+	if err != nil {
+		respondError(c, err)
+		return
 	}
-	
-	var total int
-	if specialization != "" {
-		err := database.DB.QueryRow(countQuery, specialization).Scan(&total)
+
+	var pagination PaginationInfo
+	var doctors []models.Doctor
+	if from != nil {
+		if from.Prev {
+			filter.Before = from.Doctor
+		} else {
+			filter.After = from.Doctor
+		}
+		filter.Limit = limit + 1
+
+		doctors, err = h.Doctors.List(ctx, filter)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, MobileResponse{
-				Success: false,
-				Error:   "Failed to count doctors",
-			})
+			respondError(c, apierror.Internal("Failed to fetch doctors", err))
 			return
 		}
+
+		var more bool
+		doctors, more = trimPage(doctors, limit, from.Prev)
+		pagination = cursorPagination(from, limit, more)
 	} else {
-		err := database.DB.QueryRow(countQuery).Scan(&total)
+		// Get total count for pagination
+		total, err := h.Doctors.Count(ctx, filter)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, MobileResponse{
-				Success: false,
-				Error:   "Failed to count doctors",
-			})
+			respondError(c, apierror.Internal("Failed to count doctors", err))
 			return
 		}
-	}
 
-	rows, err := database.DB.Query(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, MobileResponse{
-			Success: false,
-			Error:   "Failed to fetch doctors",
-		})
-		return
-	}
-	defer rows.Close()
-
-	var doctors []models.Doctor
-	for rows.Next() {
-		var doctor models.Doctor
-		var user models.User
-		var slotsJSON string
-
-		err := rows.Scan(
-			&doctor.ID, &doctor.UserID, &doctor.Specialization, &doctor.Experience,
-			&doctor.Phone, &slotsJSON, &doctor.CreatedAt, &doctor.UpdatedAt,
-			&user.Name, &user.Email,
-		)
+		filter.Limit = limit
+		filter.Offset = (page - 1) * limit
+		doctors, err = h.Doctors.List(ctx, filter)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, MobileResponse{
-				Success: false,
-				Error:   "Failed to scan doctor data",
-			})
+			respondError(c, apierror.Internal("Failed to fetch doctors", err))
 			return
 		}
-
-		// Parse available slots JSON
-		if err := json.Unmarshal([]byte(slotsJSON), &doctor.AvailableSlots); err != nil {
-			doctor.AvailableSlots = make(map[string][]string)
-		}
-
-		user.ID = doctor.UserID
-		doctor.User = &user
-		doctors = append(doctors, doctor)
+		pagination = newPagination(page, limit, total)
 	}
 
-	// Calculate pagination info
-	totalPages := (total + limit - 1) / limit
+	if len(doctors) > 0 {
+		pagination.setCursors(doctorCursor(doctors[0]), doctorCursor(doctors[len(doctors)-1]))
+	} else if from != nil {
+		pagination.setCursors(*from, *from)
+	}
 
-	c.JSON(http.StatusOK, MobileResponse{
-		Success: true,
-		Message: "Doctors fetched successfully",
-		Data: map[string]interface{}{
-			"doctors": doctors,
-			"pagination": PaginationInfo{
-				Page:       page,
-				Limit:      limit,
-				Total:      total,
-				TotalPages: totalPages,
-				HasNext:    page < totalPages,
-				HasPrev:    page > 1,
-			},
-		},
+	respond(c, http.StatusOK, "Doctors fetched successfully", gin.H{
+		"doctors":    doctors,
+		"pagination": pagination,
 	})
 }
 
-// Enhanced appointment booking with better validation
-func CreateAppointmentMobile(c *gin.Context) {
-	var req models.CreateAppointmentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, MobileResponse{
-			Success: false,
-			Error:   "Invalid request data: " + err.Error(),
-		})
-		return
-	}
-
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, MobileResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+// CreateAppointmentMobile is CreateAppointment answering with the
+// appointment's local date and slot, as /api/mobile/appointments always has
+func (h *Handler) CreateAppointmentMobile(c *gin.Context) {
+	appointment, req, ok := h.createAppointment(c)
+	if !ok {
 		return
 	}
 
-	// Parse appointment date
-	appointmentDate, err := time.Parse("2006-01-02T15:04:05Z", req.AppointmentDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, MobileResponse{
-			Success: false,
-			Error:   "Invalid date format. Use ISO 8601 format (YYYY-MM-DDTHH:MM:SSZ)",
-		})
-		return
-	}
-
-	// Check if doctor exists
-	var doctorExists bool
-	err = database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM doctors WHERE id = $1)", req.DoctorID).Scan(&doctorExists)
-	if err != nil || !doctorExists {
-		c.JSON(http.StatusBadRequest, MobileResponse{
-			Success: false,
-			Error:   "Doctor not found",
-		})
-		return
-	}
-
-	// Check if appointment slot is available
-	var existingAppointment string
-	err = database.DB.QueryRow(`
-		SELECT id FROM appointments 
-		WHERE doctor_id = $1 AND appointment_date = $2 AND slot = $3 AND status != 'cancelled'
-	`, req.DoctorID, appointmentDate, req.Slot).Scan(&existingAppointment)
-
-	if err != sql.ErrNoRows {
-		c.JSON(http.StatusConflict, MobileResponse{
-			Success: false,
-			Error:   "Appointment slot is already booked",
-		})
-		return
-	}
-
-	// Create appointment
-	appointmentID := uuid.New()
-	query := `
-		INSERT INTO appointments (id, doctor_id, patient_id, appointment_date, slot, status, notes)
-		VALUES ($1, $2, $3, $4, $5, 'scheduled', $6)
-	`
-
-	_, err = database.DB.Exec(query, appointmentID, req.DoctorID, userID, appointmentDate, req.Slot, req.Notes)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, MobileResponse{
-			Success: false,
-			Error:   "Failed to create appointment",
-		})
-		return
-	}
-
-	c.JSON(http.StatusCreated, MobileResponse{
-		Success: true,
-		Message: "Appointment created successfully",
-		Data: map[string]interface{}{
-			"appointment_id": appointmentID,
-			"appointment_date": appointmentDate.Format("2006-01-02"),
-			"appointment_time": appointmentDate.Format("15:04"),
-			"slot": req.Slot,
-		},
+	// Date and time are reported in the doctor's timezone
+	local := appointment.AppointmentDate.In(h.location(appointment.Doctor))
+	respond(c, http.StatusCreated, "Appointment created successfully", gin.H{
+		"appointment_id":   appointment.ID,
+		"appointment_date": local.Format(dateLayout),
+		"appointment_time": local.Format(slotLayout),
+		"slot":             req.Slot,
+		"appointment_type": appointment.Type,
+		"duration_minutes": appointment.DurationMinutes,
+		"start_time_utc":   appointment.AppointmentDate,
+		"end_time_utc":     appointment.EndsAt,
+		"start_time_local": appointment.LocalTime,
+		"timezone":         appointment.Timezone,
 	})
 }
 
-// Get user appointments with pagination
-func GetUserAppointmentsMobile(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
+// ListAppointments returns one page of the authenticated user's
+// appointments, newest first. The page is read from the cursor parameter
+// when given, and by page number otherwise.
+func (h *Handler) ListAppointments(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, MobileResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
 		return
 	}
 
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 20
-	}
-	
-	offset := (page - 1) * limit
-
-	// Get total count
-	var total int
-	err := database.DB.QueryRow(`
-		SELECT COUNT(*) FROM appointments 
-		WHERE patient_id = $1
-	`, userID).Scan(&total)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, MobileResponse{
-			Success: false,
-			Error:   "Failed to count appointments",
-		})
-		return
-	}
+	page, limit := pageParams(c)
+	filter := repository.AppointmentFilter{PatientID: userID}
+	ctx := c.Request.Context()
 
-	query := `
-		SELECT a.id, a.doctor_id, a.patient_id, a.appointment_date, a.slot, 
-		       a.status, a.notes, a.created_at, a.updated_at,
-		       d.specialization, u.name as doctor_name, u.email as doctor_email
-		FROM appointments a
-		JOIN doctors d ON a.doctor_id = d.id
-		JOIN users u ON d.user_id = u.id
-		WHERE a.patient_id = $1
-		ORDER BY a.appointment_date DESC
-		LIMIT $2 OFFSET $3
-	`
-
-	rows, err := database.DB.Query(query, userID, limit, offset)
+	from, err := cursorParam(c, func(cur *cursor) bool { return cur.Appointment != nil })
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, MobileResponse{
-			Success: false,
-			Error:   "Failed to fetch appointments",
-		})
+		respondError(c, err)
 		return
 	}
-	defer rows.Close()
 
+	var pagination PaginationInfo
 	var appointments []models.Appointment
-	for rows.Next() {
-		var appointment models.Appointment
-		var doctor models.Doctor
-		var user models.User
-
-		err := rows.Scan(
-			&appointment.ID, &appointment.DoctorID, &appointment.PatientID,
-			&appointment.AppointmentDate, &appointment.Slot, &appointment.Status,
-			&appointment.Notes, &appointment.CreatedAt, &appointment.UpdatedAt,
-			&doctor.Specialization, &user.Name, &user.Email,
-		)
+	if from != nil {
+		if from.Prev {
+			filter.Before = from.Appointment
+		} else {
+			filter.After = from.Appointment
+		}
+		filter.Limit = limit + 1
+
+		appointments, err = h.Appointments.List(ctx, filter)
+		if err != nil {
+			respondError(c, apierror.Internal("Failed to fetch appointments", err))
+			return
+		}
+
+		var more bool
+		appointments, more = trimPage(appointments, limit, from.Prev)
+		pagination = cursorPagination(from, limit, more)
+	} else {
+		// Get total count
+		total, err := h.Appointments.Count(ctx, filter)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, MobileResponse{
-				Success: false,
-				Error:   "Failed to scan appointment data",
-			})
+			respondError(c, apierror.Internal("Failed to count appointments", err))
 			return
 		}
 
-		doctor.ID = appointment.DoctorID
-		doctor.User = &user
-		appointment.Doctor = &doctor
-		appointments = append(appointments, appointment)
+		filter.Limit = limit
+		filter.Offset = (page - 1) * limit
+		appointments, err = h.Appointments.List(ctx, filter)
+		if err != nil {
+			respondError(c, apierror.Internal("Failed to fetch appointments", err))
+			return
+		}
+		pagination = newPagination(page, limit, total)
 	}
 
-	// Calculate pagination info
-	totalPages := (total + limit - 1) / limit
+	if len(appointments) > 0 {
+		pagination.setCursors(appointmentCursor(appointments[0]), appointmentCursor(appointments[len(appointments)-1]))
+	} else if from != nil {
+		pagination.setCursors(*from, *from)
+	}
+	h.localizeAppointments(appointments)
 
-	c.JSON(http.StatusOK, MobileResponse{
-		Success: true,
-		Message: "Appointments fetched successfully",
-		Data: map[string]interface{}{
-			"appointments": appointments,
-			"pagination": PaginationInfo{
-				Page:       page,
-				Limit:      limit,
-				Total:      total,
-				TotalPages: totalPages,
-				HasNext:    page < totalPages,
-				HasPrev:    page > 1,
-			},
-		},
+	respond(c, http.StatusOK, "Appointments fetched successfully", gin.H{
+		"appointments": appointments,
+		"pagination":   pagination,
 	})
 }
 
-// Search doctors by specialization
-func SearchDoctorsMobile(c *gin.Context) {
-	query := c.Query("q")
+// SearchDoctors returns one page of the active doctors matching q by
+// name, specialization, language or clinic, tolerating typos, best matches
+// first. Each match carries its score and its matching words highlighted.
+func (h *Handler) SearchDoctors(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
 	specialization := c.Query("specialization")
-	
+
 	if query == "" && specialization == "" {
-		c.JSON(http.StatusBadRequest, MobileResponse{
-			Success: false,
-			Error:   "Search query or specialization is required",
-		})
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Search query or specialization is required"))
 		return
 	}
 
-	searchQuery := `
-		SELECT d.id, d.user_id, d.specialization, d.experience, d.phone, 
-		       d.available_slots, d.created_at, d.updated_at,
-		       u.name, u.email
-		FROM doctors d
-		JOIN users u ON d.user_id = u.id
-		WHERE 1=1
-	`
-	
-	args := []interface{}{}
-	argIndex := 1
-	
-	if query != "" {
-		searchQuery += " AND (u.name ILIKE $" + strconv.Itoa(argIndex) + " OR d.specialization ILIKE $" + strconv.Itoa(argIndex) + ")"
-		args = append(args, "%"+query+"%")
-		argIndex++
-	}
-	
-	if specialization != "" {
-		searchQuery += " AND d.specialization = $" + strconv.Itoa(argIndex)
-		args = append(args, specialization)
-		argIndex++
+	page, limit := pageParams(c)
+	search := repository.DoctorSearch{Query: query, Specialization: specialization}
+	ctx := c.Request.Context()
+
+	total, err := h.Doctors.CountSearch(ctx, search)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to search doctors", err))
+		return
 	}
-	
-	searchQuery += " ORDER BY u.name LIMIT 20"
 
-	rows, err := database.DB.Query(searchQuery, args...)
+	search.Limit = limit
+	search.Offset = (page - 1) * limit
+	doctors, err := h.Doctors.Search(ctx, search)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, MobileResponse{
-			Success: false,
-			Error:   "Failed to search doctors",
-		})
+		respondError(c, apierror.Internal("Failed to search doctors", err))
 		return
 	}
-	defer rows.Close()
+	for i := range doctors {
+		doctors[i].Highlights = searchHighlights(query, doctors[i].Doctor)
+	}
 
-	var doctors []models.Doctor
-	for rows.Next() {
-		var doctor models.Doctor
-		var user models.User
-		var slotsJSON string
-
-		err := rows.Scan(
-			&doctor.ID, &doctor.UserID, &doctor.Specialization, &doctor.Experience,
-			&doctor.Phone, &slotsJSON, &doctor.CreatedAt, &doctor.UpdatedAt,
-			&user.Name, &user.Email,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, MobileResponse{
-				Success: false,
-				Error:   "Failed to scan doctor data",
-			})
-			return
-		}
+	respond(c, http.StatusOK, "Search completed successfully", gin.H{
+		"doctors":        doctors,
+		"query":          query,
+		"specialization": specialization,
+		"pagination":     newPagination(page, limit, total),
+	})
+}
 
-		// Parse available slots JSON
-		if err := json.Unmarshal([]byte(slotsJSON), &doctor.AvailableSlots); err != nil {
-			doctor.AvailableSlots = make(map[string][]string)
-		}
+// searchHighlights returns the searched fields of doctor that match query,
+// with the matching words marked; nil when none match
+func searchHighlights(query string, doctor models.Doctor) map[string]string {
+	fields := map[string]string{
+		"specialization": doctor.Specialization,
+		"languages":      strings.Join(doctor.Languages, ", "),
+		"clinic":         doctor.Clinic,
+	}
+	if doctor.User != nil {
+		fields["name"] = doctor.User.Name
+	}
 
-		user.ID = doctor.UserID
-		doctor.User = &user
-		doctors = append(doctors, doctor)
+	var highlights map[string]string
+	for field, text := range fields {
+		marked, ok := textsearch.Highlight(query, text)
+		if !ok {
+			continue
+		}
+		if highlights == nil {
+			highlights = map[string]string{}
+		}
+		highlights[field] = marked
 	}
+	return highlights
+}
 
-	c.JSON(http.StatusOK, MobileResponse{
-		Success: true,
-		Message: "Search completed successfully",
-		Data: map[string]interface{}{
-			"doctors": doctors,
-			"query": query,
-			"specialization": specialization,
-		},
-	})
+// pageParams reads the page (default 1) and limit (default 20, at most
+// 50) query parameters
+func pageParams(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+	return page, limit
 }
 
+// newPagination describes page of a listing of total items
+func newPagination(page, limit, total int) PaginationInfo {
+	totalPages := (total + limit - 1) / limit
+	return PaginationInfo{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"hospital-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respond answers the request with message and data in the envelope of
+// the route: as a MobileResponse on /api/v1 and /api/mobile routes, and on
+// legacy routes as data's fields beside "message"
+func respond(c *gin.Context, status int, message string, data gin.H) {
+	if middleware.Enveloped(c) {
+		response := MobileResponse{Success: true, Message: message}
+		if len(data) > 0 {
+			response.Data = data
+		}
+		c.JSON(status, response)
+		return
+	}
+
+	body := make(gin.H, len(data)+1)
+	for key, value := range data {
+		body[key] = value
+	}
+	if message != "" {
+		body["message"] = message
+	}
+	c.JSON(status, body)
+}
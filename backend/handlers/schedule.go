@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"hospital-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// slotUnavailableError rejects a booking for a slot the doctor does not
+// work on that date, or that has already started
+type slotUnavailableError struct {
+	Slot       string
+	Date       string
+	Weekday    string
+	ValidSlots []string
+	// Reason explains why the doctor does not work the date at all, such
+	// as a holiday or leave
+	Reason string
+	// Started is set when the slot is worked but already began
+	Started bool
+}
+
+func (e *slotUnavailableError) Error() string {
+	if e.Started {
+		return fmt.Sprintf("Slot %s on %s has already started", e.Slot, e.Date)
+	}
+	if len(e.ValidSlots) == 0 {
+		if e.Reason != "" {
+			return fmt.Sprintf("Doctor is not available on %s: %s", e.Date, e.Reason)
+		}
+		return fmt.Sprintf("Doctor is not available on %s", e.Weekday)
+	}
+	return fmt.Sprintf("Slot %s is not available on %s. Valid slots: %s",
+		e.Slot, e.Weekday, strings.Join(e.ValidSlots, ", "))
+}
+
+// slotsForDate returns the slots published for the weekday of date. Weekday
+// keys are matched case-insensitively ("Monday", "monday").
+func slotsForDate(availableSlots map[string][]string, date time.Time) []string {
+	weekday := date.Weekday().String()
+	for day, slots := range availableSlots {
+		if strings.EqualFold(day, weekday) {
+			return slots
+		}
+	}
+	return nil
+}
+
+// schedule resolves the slots a doctor works on each date of a range from
+// the weekly template, the doctor's schedule exceptions and the hospital
+// holidays
+type schedule struct {
+	weekly       map[string][]string
+	slotDuration time.Duration
+	buffer       time.Duration
+	exceptions   map[string][]models.ScheduleException
+	holidays     map[string]models.Holiday
+}
+
+// slotSettings returns the doctor's slot duration and buffer, defaulting
+// unset values
+func slotSettings(doctor *models.Doctor) (time.Duration, time.Duration) {
+	duration, buffer := doctor.SlotDuration, doctor.BufferMinutes
+	if duration <= 0 {
+		duration = models.DefaultSlotDuration
+	}
+	if buffer < 0 {
+		buffer = models.DefaultBufferMinutes
+	}
+	return time.Duration(duration) * time.Minute, time.Duration(buffer) * time.Minute
+}
+
+// loadSchedule reads what is needed to resolve the doctor's slots between
+// from and to (inclusive)
+func (h *Handler) loadSchedule(ctx context.Context, doctor *models.Doctor, from, to time.Time) (*schedule, error) {
+	exceptions, err := h.Schedules.ListExceptions(ctx, doctor.ID, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := h.Schedules.ListHolidays(ctx, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schedule{
+		weekly:     doctor.AvailableSlots,
+		exceptions: make(map[string][]models.ScheduleException, len(exceptions)),
+		holidays:   make(map[string]models.Holiday, len(holidays)),
+	}
+	s.slotDuration, s.buffer = slotSettings(doctor)
+	for _, exception := range exceptions {
+		s.exceptions[exception.Date] = append(s.exceptions[exception.Date], exception)
+	}
+	for _, holiday := range holidays {
+		s.holidays[holiday.Date] = holiday
+	}
+	return s, nil
+}
+
+// slots returns the sorted slots worked on date. Holidays drop the weekly
+// slots, extras add slots (even on holidays) and blocks remove slots last,
+// so leave always wins.
+func (s *schedule) slots(date time.Time) []string {
+	key := date.Format(dateLayout)
+
+	worked := map[string]bool{}
+	if _, holiday := s.holidays[key]; !holiday {
+		for _, slot := range slotsForDate(s.weekly, date) {
+			worked[slot] = true
+		}
+	}
+	for _, exception := range s.exceptions[key] {
+		if exception.Kind == models.ExceptionExtra {
+			for _, slot := range exception.Slots {
+				worked[slot] = true
+			}
+		}
+	}
+	for _, exception := range s.exceptions[key] {
+		if exception.Kind != models.ExceptionBlock {
+			continue
+		}
+		for slot := range worked {
+			if exception.StartTime == "" || slot >= exception.StartTime && slot < exception.EndTime {
+				delete(worked, slot)
+			}
+		}
+	}
+
+	slots := make([]string, 0, len(worked))
+	for slot := range worked {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	return slots
+}
+
+// starts returns the sorted slots worked on date at which an appointment
+// lasting duration can start. Each worked slot covers slotDuration, and
+// slots no further apart than the buffer form one stretch of working time;
+// the appointment must end within the stretch it starts in, so a long
+// appointment type spans several consecutive slots.
+func (s *schedule) starts(date time.Time, duration time.Duration) []string {
+	worked := s.slots(date)
+
+	// Stretches of working time as [start, end) minutes of the day
+	type stretch struct{ start, end int }
+	slotMinutes, bufferMinutes := int(s.slotDuration/time.Minute), int(s.buffer/time.Minute)
+	stretches := []stretch{}
+	for _, slot := range worked {
+		start := clockMinutes(slot)
+		if n := len(stretches); n > 0 && start <= stretches[n-1].end+bufferMinutes {
+			if end := start + slotMinutes; end > stretches[n-1].end {
+				stretches[n-1].end = end
+			}
+			continue
+		}
+		stretches = append(stretches, stretch{start, start + slotMinutes})
+	}
+
+	starts := []string{}
+	needed := int(duration / time.Minute)
+	for _, slot := range worked {
+		start := clockMinutes(slot)
+		for _, span := range stretches {
+			if start >= span.start && start < span.end {
+				if start+needed <= span.end {
+					starts = append(starts, slot)
+				}
+				break
+			}
+		}
+	}
+	return starts
+}
+
+// clockMinutes returns the minutes since midnight of a slot ("15:04"), or
+// -1 if it is not a valid time
+func clockMinutes(slot string) int {
+	clock, err := time.Parse(slotLayout, slot)
+	if err != nil {
+		return -1
+	}
+	return clock.Hour()*60 + clock.Minute()
+}
+
+// closure explains why the doctor does not work on date at all, or returns
+// "" when nothing closes the date
+func (s *schedule) closure(date time.Time) string {
+	key := date.Format(dateLayout)
+	for _, exception := range s.exceptions[key] {
+		if exception.Kind == models.ExceptionBlock && exception.StartTime == "" {
+			if exception.Reason != "" {
+				return "on leave (" + exception.Reason + ")"
+			}
+			return "on leave"
+		}
+	}
+	if holiday, ok := s.holidays[key]; ok {
+		return "hospital holiday (" + holiday.Name + ")"
+	}
+	return ""
+}
+
+// validate checks that an appointment lasting duration can start at slot
+// on date
+func (s *schedule) validate(date time.Time, slot string, duration time.Duration) error {
+	validSlots := s.starts(date, duration)
+	for _, valid := range validSlots {
+		if valid == slot {
+			return nil
+		}
+	}
+
+	unavailable := &slotUnavailableError{
+		Slot:       slot,
+		Date:       date.Format(dateLayout),
+		Weekday:    date.Weekday().String(),
+		ValidSlots: validSlots,
+	}
+	switch {
+	case len(s.slots(date)) == 0:
+		unavailable.Reason = s.closure(date)
+	case len(validSlots) == 0:
+		unavailable.Reason = fmt.Sprintf("no working time long enough for a %d-minute appointment", int(duration/time.Minute))
+	}
+	return unavailable
+}
+
+// validateSlot checks that an appointment lasting duration can start at
+// slot on date, and that slot has not started yet
+func (h *Handler) validateSlot(ctx context.Context, doctor *models.Doctor, date time.Time, slot string, duration time.Duration) error {
+	s, err := h.loadSchedule(ctx, doctor, date, date)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(date, slot, duration); err != nil {
+		return err
+	}
+
+	if start, err := slotStart(date, slot, h.location(doctor)); err == nil && !start.After(time.Now()) {
+		return &slotUnavailableError{
+			Slot:       slot,
+			Date:       date.Format(dateLayout),
+			Weekday:    date.Weekday().String(),
+			ValidSlots: []string{},
+			Started:    true,
+		}
+	}
+	return nil
+}
+
+// flagForReschedule sets reschedule_required on the active appointments
+// among appointments whose slot is no longer worked, for example after
+// leave or a holiday was added. It returns the IDs of the flagged
+// appointments.
+func (h *Handler) flagForReschedule(ctx context.Context, changedBy uuid.UUID, appointments []models.Appointment) ([]uuid.UUID, error) {
+	byDoctor := map[uuid.UUID][]models.Appointment{}
+	for _, appointment := range appointments {
+		if !models.IsFinalStatus(appointment.Status) && !appointment.RescheduleRequired {
+			byDoctor[appointment.DoctorID] = append(byDoctor[appointment.DoctorID], appointment)
+		}
+	}
+
+	flagged := []uuid.UUID{}
+	for doctorID, doctorAppointments := range byDoctor {
+		doctor, err := h.Doctors.Get(ctx, doctorID)
+		if err != nil {
+			return nil, err
+		}
+
+		loc := h.location(doctor)
+		from := civilDate(doctorAppointments[0].AppointmentDate, loc)
+		to := from
+		for _, appointment := range doctorAppointments {
+			date := civilDate(appointment.AppointmentDate, loc)
+			if date.Before(from) {
+				from = date
+			}
+			if date.After(to) {
+				to = date
+			}
+		}
+
+		s, err := h.loadSchedule(ctx, doctor, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, appointment := range doctorAppointments {
+			duration := time.Duration(appointment.DurationMinutes) * time.Minute
+			if s.validate(civilDate(appointment.AppointmentDate, loc), appointment.Slot, duration) == nil {
+				continue
+			}
+
+			_, err := h.Appointments.Update(ctx, appointment.ID, changedBy, func(current *models.Appointment) error {
+				if current.Slot == appointment.Slot && current.AppointmentDate.Equal(appointment.AppointmentDate) &&
+					!models.IsFinalStatus(current.Status) {
+					current.RescheduleRequired = true
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			flagged = append(flagged, appointment.ID)
+		}
+	}
+
+	return flagged, nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+func TestScheduleSlots(t *testing.T) {
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	wednesday := monday.AddDate(0, 0, 2)
+	thursday := monday.AddDate(0, 0, 3)
+
+	s := &schedule{
+		slotDuration: 15 * time.Minute,
+		weekly: map[string][]string{
+			"Monday":    {"09:00", "10:00", "11:00"},
+			"tuesday":   {"14:00"},
+			"Wednesday": {"09:00"},
+		},
+		exceptions: map[string][]models.ScheduleException{
+			// A partial block and an extra slot on the same day
+			"2030-01-07": {
+				{Kind: models.ExceptionBlock, StartTime: "10:00", EndTime: "11:00"},
+				{Kind: models.ExceptionExtra, Slots: []string{"16:00"}},
+			},
+			// Leave wins over extra slots
+			"2030-01-09": {
+				{Kind: models.ExceptionExtra, Slots: []string{"12:00"}},
+				{Kind: models.ExceptionBlock, Reason: "conference"},
+			},
+			// Extra slots are worked even on holidays
+			"2030-01-10": {
+				{Kind: models.ExceptionExtra, Slots: []string{"08:00"}},
+			},
+		},
+		holidays: map[string]models.Holiday{
+			"2030-01-08": {Date: "2030-01-08", Name: "Founders' Day"},
+			"2030-01-10": {Date: "2030-01-10", Name: "Open Day"},
+		},
+	}
+
+	tests := []struct {
+		date    time.Time
+		slots   []string
+		closure string
+	}{
+		{monday, []string{"09:00", "11:00", "16:00"}, ""},
+		{tuesday, []string{}, "hospital holiday (Founders' Day)"},
+		{wednesday, []string{}, "on leave (conference)"},
+		{thursday, []string{"08:00"}, "hospital holiday (Open Day)"},
+	}
+	for _, tt := range tests {
+		if got := s.slots(tt.date); !reflect.DeepEqual(got, tt.slots) {
+			t.Errorf("%s: slots = %v, want %v", tt.date.Weekday(), got, tt.slots)
+		}
+		if got := s.closure(tt.date); got != tt.closure {
+			t.Errorf("%s: closure = %q, want %q", tt.date.Weekday(), got, tt.closure)
+		}
+	}
+
+	if err := s.validate(monday, "16:00", 15*time.Minute); err != nil {
+		t.Errorf("extra slot: %v", err)
+	}
+	if err := s.validate(thursday, "08:00", 15*time.Minute); err != nil {
+		t.Errorf("extra slot on a holiday: %v", err)
+	}
+
+	unavailable, ok := s.validate(monday, "10:00", 15*time.Minute).(*slotUnavailableError)
+	if !ok || !reflect.DeepEqual(unavailable.ValidSlots, []string{"09:00", "11:00", "16:00"}) {
+		t.Errorf("blocked slot: error = %v, want the remaining slots as valid", unavailable)
+	}
+	unavailable, ok = s.validate(wednesday, "09:00", 15*time.Minute).(*slotUnavailableError)
+	if !ok || unavailable.Error() != "Doctor is not available on 2030-01-09: on leave (conference)" {
+		t.Errorf("slot on leave: error = %v", unavailable)
+	}
+}
+
+func TestScheduleStarts(t *testing.T) {
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	weekly := map[string][]string{"Monday": {"09:00", "09:15", "09:30", "10:00"}}
+
+	tests := []struct {
+		name     string
+		buffer   time.Duration
+		duration time.Duration
+		want     []string
+	}{
+		{"consultation", 0, 15 * time.Minute, []string{"09:00", "09:15", "09:30", "10:00"}},
+		{"procedure across consecutive slots", 0, 45 * time.Minute, []string{"09:00"}},
+		{"procedure bridging a gap within the buffer", 15 * time.Minute, 45 * time.Minute, []string{"09:00", "09:15", "09:30"}},
+		{"longer than any stretch", 0, 2 * time.Hour, []string{}},
+	}
+	for _, tt := range tests {
+		s := &schedule{weekly: weekly, slotDuration: 15 * time.Minute, buffer: tt.buffer}
+		if got := s.starts(monday, tt.duration); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: starts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	s := &schedule{weekly: weekly, slotDuration: 15 * time.Minute}
+	unavailable, ok := s.validate(monday, "09:00", 2*time.Hour).(*slotUnavailableError)
+	if !ok || unavailable.Reason != "no working time long enough for a 120-minute appointment" {
+		t.Errorf("validate = %v, want no working time long enough", unavailable)
+	}
+}
+
+func TestValidateSlot(t *testing.T) {
+	ctx := context.Background()
+	h := New(memory.NewStore(), Config{})
+	doctor := &models.Doctor{
+		ID: uuid.New(),
+		AvailableSlots: map[string][]string{
+			"Monday":  {"09:00", "10:00"},
+			"tuesday": {"14:00"},
+		},
+	}
+	now := time.Now().UTC()
+	nextMonday := now.AddDate(0, 0, 1)
+	for nextMonday.Weekday() != time.Monday {
+		nextMonday = nextMonday.AddDate(0, 0, 1)
+	}
+	lastMonday := nextMonday.AddDate(0, 0, -7)
+	nextTuesday := nextMonday.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name       string
+		date       time.Time
+		slot       string
+		wantErr    bool
+		started    bool
+		validSlots int
+	}{
+		{name: "published slot", date: nextMonday, slot: "09:00"},
+		{name: "lower-case weekday key", date: nextTuesday, slot: "14:00"},
+		{name: "unpublished slot", date: nextMonday, slot: "11:00", wantErr: true, validSlots: 2},
+		{name: "day off", date: nextMonday.AddDate(0, 0, 2), slot: "09:00", wantErr: true},
+		{name: "past slot", date: lastMonday, slot: "09:00", wantErr: true, started: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := h.validateSlot(ctx, doctor, tt.date, tt.slot, 15*time.Minute)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("validateSlot: %v", err)
+				}
+				return
+			}
+
+			unavailable, ok := err.(*slotUnavailableError)
+			if !ok {
+				t.Fatalf("validateSlot error = %v, want a *slotUnavailableError", err)
+			}
+			if unavailable.Started != tt.started {
+				t.Errorf("Started = %v, want %v", unavailable.Started, tt.started)
+			}
+			if len(unavailable.ValidSlots) != tt.validSlots {
+				t.Errorf("ValidSlots = %v, want %d slots", unavailable.ValidSlots, tt.validSlots)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"hospital-backend/models"
+)
+
+// locations caches loaded IANA zones by name
+var locations sync.Map
+
+// invalidDateError rejects an appointment_date that cannot be read or
+// does not match the slot
+type invalidDateError struct {
+	message string
+}
+
+func (e *invalidDateError) Error() string {
+	return e.message
+}
+
+// LoadLocation returns the IANA zone named name, caching it for later
+// calls
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// location returns the zone a doctor's slots are read in: the doctor's own
+// timezone when set, otherwise the hospital timezone
+func (h *Handler) location(doctor *models.Doctor) *time.Location {
+	if doctor != nil && doctor.Timezone != "" {
+		if loc, err := LoadLocation(doctor.Timezone); err == nil {
+			return loc
+		}
+	}
+	return h.Location
+}
+
+// today returns the current date in loc
+func today(loc *time.Location) time.Time {
+	return civilDate(time.Now(), loc)
+}
+
+// civilDate returns the calendar date of t in loc. Calendar dates are
+// carried as UTC midnight, so Format(dateLayout) and Weekday read them
+// directly.
+func civilDate(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// slotStart returns the instant slot ("15:04") begins on date in loc
+func slotStart(date time.Time, slot string, loc *time.Location) (time.Time, error) {
+	clock, err := time.Parse(slotLayout, slot)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid slot %q", slot)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), nil
+}
+
+// dayBounds returns the instants [start, end) covering the dates from to
+// to (inclusive) in loc
+func dayBounds(from, to time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	return start, end
+}
+
+// appointmentDay returns the date an appointment_date value selects for a
+// booking of slot in loc. The value is a date ("2026-10-19") or an RFC 3339
+// timestamp. Midnight UTC written with Z ("2026-10-19T00:00:00Z"), which
+// older clients send for a bare date, selects the date as written. Any
+// other timestamp is the start of the appointment: it is converted to loc
+// and must fall on slot.
+func appointmentDay(value, slot string, loc *time.Location) (time.Time, error) {
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		return date, nil
+	}
+
+	instant, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &invalidDateError{
+			"Invalid date format. Use YYYY-MM-DD or RFC 3339 (e.g. 2026-10-19T09:00:00+05:30)",
+		}
+	}
+
+	if strings.HasSuffix(value, "Z") && instant.Hour() == 0 && instant.Minute() == 0 && instant.Second() == 0 {
+		year, month, day := instant.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	local := instant.In(loc)
+	if local.Format(slotLayout) != slot || local.Second() != 0 {
+		return time.Time{}, &invalidDateError{fmt.Sprintf(
+			"appointment_date %s is %s in %s, which does not match slot %s",
+			value, local.Format("2006-01-02 15:04"), loc, slot)}
+	}
+	return civilDate(instant, loc), nil
+}
+
+// localizeAppointment reports the appointment's start and end in UTC and
+// its start in the doctor's timezone
+func (h *Handler) localizeAppointment(appointment *models.Appointment) {
+	loc := h.location(appointment.Doctor)
+	appointment.AppointmentDate = appointment.AppointmentDate.UTC()
+	appointment.EndsAt = appointment.AppointmentDate.Add(time.Duration(appointment.DurationMinutes) * time.Minute)
+	appointment.Timezone = loc.String()
+	appointment.LocalTime = appointment.AppointmentDate.In(loc).Format(time.RFC3339)
+}
+
+// localizeAppointments applies localizeAppointment to each appointment
+func (h *Handler) localizeAppointments(appointments []models.Appointment) {
+	for i := range appointments {
+		h.localizeAppointment(&appointments[i])
+	}
+}
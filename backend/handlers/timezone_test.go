@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAppointmentDay(t *testing.T) {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+
+	tests := []struct {
+		name  string
+		value string
+		slot  string
+		loc   *time.Location
+		want  string
+	}{
+		{"date only", "2026-10-19", "09:00", ist, "2026-10-19"},
+		{"legacy Z midnight", "2026-10-19T00:00:00Z", "09:00", ist, "2026-10-19"},
+		{"start in the doctor's offset", "2026-10-19T09:00:00+05:30", "09:00", ist, "2026-10-19"},
+		{"start in UTC", "2026-10-19T03:30:00Z", "09:00", ist, "2026-10-19"},
+		{"start on the previous UTC day", "2026-10-18T21:30:00Z", "03:00", ist, "2026-10-19"},
+		{"local midnight matching the slot", "2026-10-19T00:00:00+05:30", "00:00", ist, "2026-10-19"},
+		{"UTC midnight with a numeric offset", "2026-10-19T00:00:00+00:00", "00:00", time.UTC, "2026-10-19"},
+		{"offset midnight is not a bare date", "2026-10-19T00:00:00+05:30", "09:00", ist, ""},
+		{"offset midnight across the IST day boundary", "2026-10-19T00:00:00+05:30", "18:30", time.UTC, "2026-10-18"},
+		{"numeric UTC midnight is not a bare date", "2026-10-19T00:00:00+00:00", "09:00", time.UTC, ""},
+		{"start on another slot", "2026-10-19T10:00:00+05:30", "09:00", ist, ""},
+		{"start with seconds", "2026-10-19T09:00:30+05:30", "09:00", ist, ""},
+		{"unreadable date", "19-10-2026", "09:00", ist, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := appointmentDay(tt.value, tt.slot, tt.loc)
+			if tt.want == "" {
+				var invalid *invalidDateError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("appointmentDay(%q, %q) = %v, %v, want an *invalidDateError", tt.value, tt.slot, date, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("appointmentDay(%q, %q): %v", tt.value, tt.slot, err)
+			}
+			if got := date.Format(dateLayout); got != tt.want {
+				t.Errorf("appointmentDay(%q, %q) = %s, want %s", tt.value, tt.slot, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"hospital-backend/apierror"
+	"hospital-backend/middleware"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurrentUser returns the provisioned profile of the authenticated user
+func (h *Handler) GetCurrentUser(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	user, err := h.Users.Get(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch user", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "User fetched successfully", gin.H{
+		"user": user,
+	})
+}
+
+// UpdateCurrentUser changes the authenticated user's phone number, which
+// receives SMS reminders
+func (h *Handler) UpdateCurrentUser(c *gin.Context) {
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	phone := strings.TrimSpace(req.Phone)
+	if phone != "" && !phonePattern.MatchString(phone) {
+		respondInvalidFields(c, map[string]string{"phone": "must be a phone number such as +1234567890"})
+		return
+	}
+
+	user, err := h.Users.UpdatePhone(c.Request.Context(), userID, phone)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to update user", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Profile updated successfully", gin.H{
+		"user": user,
+	})
+}
+
+// ListUsers returns all users, optionally filtered by role (admin only)
+func (h *Handler) ListUsers(c *gin.Context) {
+	role := c.Query("role")
+	if role != "" && !middleware.IsValidRole(role) {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid role"))
+		return
+	}
+
+	limit, offset := parseLimitOffset(c)
+	users, err := h.Users.List(c.Request.Context(), role, limit, offset)
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch users", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Users fetched successfully", gin.H{
+		"users": users,
+	})
+}
+
+// GetDoctorProfile returns the doctor profile of the authenticated doctor
+func (h *Handler) GetDoctorProfile(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	doctor, err := h.Doctors.GetByUserID(c.Request.Context(), userID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeDoctorNotFound, "Doctor profile not found"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch doctor profile", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Doctor profile fetched successfully", gin.H{
+		"doctor": doctor,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// phonePattern accepts international numbers with an optional leading +
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+// workingHoursRule rejects a request setting both available_slots and the
+// working_hours that generate them
+const workingHoursRule = "must be omitted when working_hours is set"
+
+// timezoneRule describes the values validTimezone accepts
+const timezoneRule = "must be an IANA timezone such as Asia/Kolkata"
+
+// validTimezone reports whether name is an IANA timezone. "Local" is
+// rejected because it depends on the server.
+func validTimezone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+	_, err := LoadLocation(name)
+	return err == nil
+}
+
+func init() {
+	// Report binding failures with JSON field names instead of Go ones
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// fieldErrors describes the binding rules that err reports as failed, keyed
+// by JSON field name. It returns nil for errors that are not validation
+// errors, such as malformed JSON.
+func fieldErrors(err error) map[string]string {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	fields := make(map[string]string, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		switch fieldErr.Tag() {
+		case "required":
+			fields[fieldErr.Field()] = "is required"
+		case "email":
+			fields[fieldErr.Field()] = "must be a valid email address"
+		case "oneof":
+			fields[fieldErr.Field()] = "must be one of: " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+		case "min", "max":
+			bound := "at least"
+			if fieldErr.Tag() == "max" {
+				bound = "at most"
+			}
+			if fieldErr.Kind() == reflect.String {
+				fields[fieldErr.Field()] = fmt.Sprintf("must be %s %s characters", bound, fieldErr.Param())
+			} else {
+				fields[fieldErr.Field()] = fmt.Sprintf("must be %s %s", bound, fieldErr.Param())
+			}
+		default:
+			fields[fieldErr.Field()] = "is invalid"
+		}
+	}
+	return fields
+}
@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxWaitlistDays caps the date range of a waitlist entry
+const maxWaitlistDays = 90
+
+// JoinWaitlist registers the patient for a slot of a fully booked doctor
+// between from_date and to_date, optionally only at preferred_slots. When
+// a matching slot is freed the patient gets an offer to accept before it
+// expires.
+func (h *Handler) JoinWaitlist(c *gin.Context) {
+	var req models.CreateWaitlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, err)
+		return
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	doctor, err := h.Doctors.Get(ctx, req.DoctorID)
+	if err == repository.ErrNotFound || err == nil && !doctor.Active {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeDoctorNotFound, "Doctor not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to join waitlist", err))
+		return
+	}
+
+	entry, fields := validateWaitlistEntry(req, today(h.location(doctor)))
+	if fields != nil {
+		respondInvalidFields(c, fields)
+		return
+	}
+
+	entry.PatientID = userID
+	err = h.Waitlist.CreateEntry(ctx, entry)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeDoctorNotFound, "Doctor not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to join waitlist", err))
+		return
+	}
+
+	respond(c, http.StatusCreated, "Joined waitlist successfully", gin.H{
+		"entry": entry,
+	})
+}
+
+// GetWaitlist returns the patient's waitlist entries and their pending
+// offers
+func (h *Handler) GetWaitlist(c *gin.Context) {
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	entries, err := h.Waitlist.ListEntries(ctx, repository.WaitlistFilter{PatientID: userID})
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch waitlist", err))
+		return
+	}
+	offers, err := h.Waitlist.ListOffers(ctx, repository.OfferFilter{
+		PatientID: userID,
+		Status:    models.OfferPending,
+	})
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch waitlist", err))
+		return
+	}
+
+	respond(c, http.StatusOK, "Waitlist fetched successfully", gin.H{
+		"entries": entries,
+		"offers":  offers,
+	})
+}
+
+// LeaveWaitlist cancels one of the patient's waitlist entries. A pending
+// offer of the entry is declined and passed to the next patient.
+func (h *Handler) LeaveWaitlist(c *gin.Context) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid waitlist entry ID"))
+		return
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	offer, err := h.Waitlist.CancelEntry(ctx, entryID, userID)
+	if err == repository.ErrNotFound {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Waitlist entry not found"))
+		return
+	}
+	if err == repository.ErrConflict {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "Waitlist entry is already booked or cancelled"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to leave waitlist", err))
+		return
+	}
+
+	if offer != nil {
+		h.passOffer(ctx, offer)
+	}
+
+	respond(c, http.StatusOK, "Left waitlist successfully", nil)
+}
+
+// AcceptWaitlistOffer books the slot of one of the patient's pending
+// offers
+func (h *Handler) AcceptWaitlistOffer(c *gin.Context) {
+	userID, offer, ok := h.pendingOffer(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	doctor, err := h.Doctors.Get(ctx, offer.DoctorID)
+	if err != nil && err != repository.ErrNotFound {
+		respondError(c, apierror.Internal("Failed to accept offer", err))
+		return
+	}
+
+	// The local start selects the date and the slot in the doctor's timezone
+	appointment, err := h.bookAppointment(ctx, userID, models.CreateAppointmentRequest{
+		DoctorID:        offer.DoctorID,
+		AppointmentDate: offer.StartsAt.In(h.location(doctor)).Format(time.RFC3339),
+		Slot:            offer.Slot,
+		Type:            offer.Type,
+		HoldToken:       offer.HoldID.String(),
+	})
+	if err == errDoctorNotFound {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "Doctor is no longer available"))
+		return
+	}
+
+	if unavailable, ok := err.(*slotUnavailableError); ok {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeSlotUnavailable, unavailable.Error()))
+		return
+	}
+
+	if err == repository.ErrHoldExpired {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeHoldExpired, "Offer has expired"))
+		return
+	}
+
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to accept offer", err))
+		return
+	}
+
+	// The booking consumed the hold, so the slot is the patient's even if
+	// the offer expired meanwhile
+	if _, err := h.Waitlist.ResolveOffer(ctx, offer.ID, models.OfferAccepted); err != nil {
+		log.Printf("Failed to mark waitlist offer %s accepted: %v", offer.ID, err)
+	}
+
+	respond(c, http.StatusCreated, "Offer accepted and appointment created successfully", gin.H{
+		"appointment_id":   appointment.ID,
+		"appointment_date": appointment.AppointmentDate,
+		"ends_at":          appointment.EndsAt,
+		"appointment_type": appointment.Type,
+		"local_time":       appointment.LocalTime,
+		"timezone":         appointment.Timezone,
+	})
+}
+
+// DeclineWaitlistOffer turns down one of the patient's pending offers,
+// which passes to the next patient. The entry stays on the waitlist.
+func (h *Handler) DeclineWaitlistOffer(c *gin.Context) {
+	_, offer, ok := h.pendingOffer(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	offer, err := h.Waitlist.ResolveOffer(ctx, offer.ID, models.OfferDeclined)
+	if err == repository.ErrConflict {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "Offer is no longer pending"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to decline offer", err))
+		return
+	}
+
+	h.passOffer(ctx, offer)
+
+	respond(c, http.StatusOK, "Offer declined", nil)
+}
+
+// pendingOffer loads the offer named by the "id" path parameter and checks
+// that it is a pending offer of the authenticated patient, responding with
+// an error and returning false otherwise
+func (h *Handler) pendingOffer(c *gin.Context) (uuid.UUID, *models.WaitlistOffer, bool) {
+	offerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid offer ID"))
+		return uuid.Nil, nil, false
+	}
+
+	userID, _, exists := authenticatedUser(c)
+	if !exists {
+		respondError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+		return uuid.Nil, nil, false
+	}
+
+	offer, err := h.Waitlist.GetOffer(c.Request.Context(), offerID)
+	if err == repository.ErrNotFound || err == nil && offer.PatientID != userID {
+		respondError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Offer not found"))
+		return uuid.Nil, nil, false
+	}
+	if err != nil {
+		respondError(c, apierror.Internal("Failed to fetch offer", err))
+		return uuid.Nil, nil, false
+	}
+
+	if offer.Status != models.OfferPending || !offer.ExpiresAt.After(time.Now()) {
+		respondError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "Offer is no longer pending"))
+		return uuid.Nil, nil, false
+	}
+
+	return userID, offer, true
+}
+
+// FreedSlots delivers the appointments whose time was freed by a
+// cancellation or a reschedule, for the waitlist worker
+func (h *Handler) FreedSlots() <-chan models.Appointment {
+	return h.freed
+}
+
+// slotFreed queues the time of appointment for the waitlist worker without
+// blocking the request. When the queue is full the slot is not offered.
+func (h *Handler) slotFreed(appointment models.Appointment) {
+	select {
+	case h.freed <- appointment:
+	default:
+		log.Printf("Waitlist queue is full; the slot of appointment %s is not offered", appointment.ID)
+	}
+}
+
+// passOffer releases the slot of an offer that was declined or expired and
+// queues it for the next patient on the waitlist
+func (h *Handler) passOffer(ctx context.Context, offer *models.WaitlistOffer) {
+	err := h.Holds.Release(ctx, offer.HoldID, offer.PatientID)
+	if err != nil && err != repository.ErrNotFound {
+		log.Printf("Failed to release the hold of waitlist offer %s: %v", offer.ID, err)
+	}
+	h.slotFreed(models.Appointment{
+		DoctorID:        offer.DoctorID,
+		AppointmentDate: offer.StartsAt,
+		Slot:            offer.Slot,
+	})
+}
+
+// OfferFreedSlot offers the time freed by appointment to the waitlist
+// entry that has waited longest among those covering its date and slot,
+// reserving the slot for the patient with a hold until the offer expires.
+// Entries already offered this slot, patients with a pending offer for the
+// doctor, and appointment types that do not fit the doctor's schedule are
+// passed over.
+func (h *Handler) OfferFreedSlot(ctx context.Context, appointment models.Appointment) error {
+	if !appointment.AppointmentDate.After(time.Now()) {
+		return nil
+	}
+
+	doctor, err := h.Doctors.Get(ctx, appointment.DoctorID)
+	if err == repository.ErrNotFound || err == nil && !doctor.Active {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	loc := h.location(doctor)
+	date := civilDate(appointment.AppointmentDate, loc).Format(dateLayout)
+	entries, err := h.Waitlist.ListEntries(ctx, repository.WaitlistFilter{
+		DoctorID: doctor.ID,
+		Date:     date,
+		Status:   models.WaitlistWaiting,
+	})
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	// Entries that already had this slot, and patients already holding an
+	// offer of the doctor, are passed over
+	offers, err := h.Waitlist.ListOffers(ctx, repository.OfferFilter{DoctorID: doctor.ID})
+	if err != nil {
+		return err
+	}
+	passed := map[uuid.UUID]bool{}
+	busy := map[uuid.UUID]bool{}
+	for _, offer := range offers {
+		if offer.StartsAt.Equal(appointment.AppointmentDate) {
+			passed[offer.EntryID] = true
+		}
+		if offer.Status == models.OfferPending {
+			busy[offer.PatientID] = true
+		}
+	}
+
+	for _, entry := range entries {
+		if passed[entry.ID] || busy[entry.PatientID] || !prefersSlot(entry, appointment.Slot) {
+			continue
+		}
+
+		planned, err := h.plannedAppointment(ctx, doctor.ID, date, appointment.Slot, entry.Type)
+		if _, ok := err.(*slotUnavailableError); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		hold := &models.SlotHold{
+			DoctorID:      doctor.ID,
+			PatientID:     entry.PatientID,
+			StartsAt:      planned.AppointmentDate,
+			OccupiedUntil: planned.OccupiedUntil,
+			Slot:          planned.Slot,
+			Type:          planned.Type,
+			Kind:          models.HoldOffer,
+			ExpiresAt:     time.Now().Add(h.OfferTTL),
+		}
+		err = h.Holds.Create(ctx, hold)
+		if err == repository.ErrSlotTaken || err == repository.ErrSlotHeld {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		offer := &models.WaitlistOffer{
+			EntryID:   entry.ID,
+			DoctorID:  doctor.ID,
+			PatientID: entry.PatientID,
+			HoldID:    hold.ID,
+			StartsAt:  hold.StartsAt,
+			Slot:      hold.Slot,
+			Type:      hold.Type,
+			ExpiresAt: hold.ExpiresAt,
+		}
+		if err := h.Waitlist.CreateOffer(ctx, offer); err != nil {
+			h.Holds.Release(ctx, hold.ID, entry.PatientID)
+			if err == repository.ErrConflict {
+				continue
+			}
+			return err
+		}
+
+		log.Printf("Offered %s %s with doctor %s to waitlist entry %s until %s",
+			date, offer.Slot, doctor.ID, entry.ID, offer.ExpiresAt.Format(time.RFC3339))
+		return nil
+	}
+	return nil
+}
+
+// ExpireWaitlistOffers expires the offers that were not answered in time
+// and passes their slots to the next patients
+func (h *Handler) ExpireWaitlistOffers(ctx context.Context, now time.Time) error {
+	offers, err := h.Waitlist.ExpireOffers(ctx, now)
+	if err != nil {
+		return err
+	}
+	for i := range offers {
+		h.passOffer(ctx, &offers[i])
+	}
+	return nil
+}
+
+// prefersSlot reports whether entry accepts slot
+func prefersSlot(entry models.WaitlistEntry, slot string) bool {
+	if len(entry.PreferredSlots) == 0 {
+		return true
+	}
+	for _, preferred := range entry.PreferredSlots {
+		if preferred == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWaitlistEntry checks a request to join a waitlist and returns the
+// entry it describes, or the problems keyed by JSON field name. The range
+// must start no earlier than today and span at most maxWaitlistDays.
+func validateWaitlistEntry(req models.CreateWaitlistEntryRequest, today time.Time) (*models.WaitlistEntry, map[string]string) {
+	fields := map[string]string{}
+
+	from, err := time.Parse(dateLayout, req.FromDate)
+	if err != nil {
+		fields["from_date"] = "must be a date in YYYY-MM-DD format"
+	} else if from.Before(today) {
+		fields["from_date"] = "must not be in the past"
+	}
+
+	to, err := time.Parse(dateLayout, req.ToDate)
+	if err != nil {
+		fields["to_date"] = "must be a date in YYYY-MM-DD format"
+	} else if _, ok := fields["from_date"]; !ok {
+		if to.Before(from) {
+			fields["to_date"] = "must not be before from_date"
+		} else if to.Sub(from) >= maxWaitlistDays*24*time.Hour {
+			fields["to_date"] = fmt.Sprintf("must be less than %d days after from_date", maxWaitlistDays)
+		}
+	}
+
+	seen := make(map[string]bool, len(req.PreferredSlots))
+	for _, slot := range req.PreferredSlots {
+		parsed, err := time.Parse(slotLayout, strings.TrimSpace(slot))
+		if err != nil {
+			fields["preferred_slots"] = "must be times in HH:MM format"
+			break
+		}
+		seen[parsed.Format(slotLayout)] = true
+	}
+	slots := make([]string, 0, len(seen))
+	for slot := range seen {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	kind := req.Type
+	if kind == "" {
+		kind = models.TypeConsultation
+	}
+	return &models.WaitlistEntry{
+		DoctorID:       req.DoctorID,
+		FromDate:       req.FromDate,
+		ToDate:         req.ToDate,
+		PreferredSlots: slots,
+		Type:           kind,
+	}, nil
+}
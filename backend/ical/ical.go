@@ -0,0 +1,167 @@
+// Package ical writes RFC 5545 iCalendar documents. It covers the subset
+// needed to publish appointments: one VCALENDAR of VEVENTs with UTC times.
+package ical
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of iCalendar documents
+const ContentType = "text/calendar; charset=utf-8"
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// timestampLayout is the UTC DATE-TIME form
+const timestampLayout = "20060102T150405Z"
+
+// maxLineOctets is the longest content line before folding
+const maxLineOctets = 75
+
+// Calendar is a VCALENDAR document
+type Calendar struct {
+	// ProdID identifies the product that created the document
+	ProdID string
+	// Name is shown by clients that support X-WR-CALNAME
+	Name string
+	// RefreshInterval suggests how often subscribers poll the feed
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is a VEVENT
+type Event struct {
+	// UID is globally unique and stays the same across updates
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Write encodes cal to w. Times are written in UTC, so no VTIMEZONE
+// components are needed. DTSTAMP is set to now.
+func (cal *Calendar) Write(w io.Writer, now time.Time) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN", "VCALENDAR")
+	lw.line("VERSION", "2.0")
+	lw.line("PRODID", cal.ProdID)
+	lw.line("CALSCALE", "GREGORIAN")
+	lw.line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		lw.line("X-WR-CALNAME", escape(cal.Name))
+	}
+	if cal.RefreshInterval > 0 {
+		interval := duration(cal.RefreshInterval)
+		lw.line("REFRESH-INTERVAL;VALUE=DURATION", interval)
+		lw.line("X-PUBLISHED-TTL", interval)
+	}
+
+	stamp := timestamp(now)
+	for _, event := range cal.Events {
+		lw.line("BEGIN", "VEVENT")
+		lw.line("UID", escape(event.UID))
+		lw.line("DTSTAMP", stamp)
+		lw.line("DTSTART", timestamp(event.Start))
+		lw.line("DTEND", timestamp(event.End))
+		lw.line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			lw.line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			lw.line("LOCATION", escape(event.Location))
+		}
+		if event.Status != "" {
+			lw.line("STATUS", event.Status)
+		}
+		if !event.Created.IsZero() {
+			lw.line("CREATED", timestamp(event.Created))
+		}
+		if !event.LastModified.IsZero() {
+			lw.line("LAST-MODIFIED", timestamp(event.LastModified))
+		}
+		lw.line("END", "VEVENT")
+	}
+
+	lw.line("END", "VCALENDAR")
+	return lw.err
+}
+
+// lineWriter writes folded content lines, keeping the first error
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+// line writes "name:value" folded at 75 octets without splitting UTF-8
+// sequences, ending each physical line with CRLF
+func (lw *lineWriter) line(name, value string) {
+	if lw.err != nil {
+		return
+	}
+
+	content := name + ":" + value
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !startsRune(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
+
+// startsRune reports whether b can begin a UTF-8 sequence
+func startsRune(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// escape escapes a TEXT value
+var escape = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+).Replace
+
+// timestamp formats t as a UTC DATE-TIME
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// duration formats d as a DURATION in whole minutes, such as PT1H30M
+func duration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	value := "PT"
+	if hours := minutes / 60; hours > 0 {
+		value += strconv.Itoa(hours) + "H"
+	}
+	if minutes%60 > 0 {
+		value += strconv.Itoa(minutes%60) + "M"
+	}
+	return value
+}
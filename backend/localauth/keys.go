@@ -0,0 +1,174 @@
+package localauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// keyBits is the size of generated RSA signing keys
+const keyBits = 2048
+
+// JWK is a single RSA public key in JSON Web Key form
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set as served or stored on disk
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// GenerateKey creates a new RSA signing key
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, keyBits)
+}
+
+// KeyID returns the RFC 7638 thumbprint of a public key, used as "kid"
+func KeyID(pub *rsa.PublicKey) string {
+	// Members must be in lexicographic order with no whitespace
+	thumbprint := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, encodeExponent(pub.E), encodeModulus(pub.N))
+	sum := sha256.Sum256([]byte(thumbprint))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewJWK converts a public key to its JSON Web Key form
+func NewJWK(pub *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: KeyID(pub),
+		N:   encodeModulus(pub.N),
+		E:   encodeExponent(pub.E),
+	}
+}
+
+// PublicKey decodes the RSA public key held by the JWK
+func (k JWK) PublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent too large")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+// LoadPrivateKey reads a PEM-encoded PKCS#1 or PKCS#8 RSA private key
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an RSA key", path)
+	}
+	return key, nil
+}
+
+// WritePrivateKey stores key as a PEM-encoded PKCS#1 file readable only by
+// the owner
+func WritePrivateKey(path string, key *rsa.PrivateKey) error {
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	return os.WriteFile(path, data, 0600)
+}
+
+// LoadJWKS reads a JSON Web Key Set and returns its RSA keys by key ID
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+		kid := jwk.Kid
+		if kid == "" {
+			kid = KeyID(pub)
+		}
+		keys[kid] = pub
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", path)
+	}
+	return keys, nil
+}
+
+// WriteJWKS stores the public halves of keys as a JSON Web Key Set
+func WriteJWKS(path string, keys ...*rsa.PublicKey) error {
+	set := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, NewJWK(key))
+	}
+
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func encodeModulus(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func encodeExponent(e int) string {
+	return base64.RawURLEncoding.EncodeToString(big.NewInt(int64(e)).Bytes())
+}
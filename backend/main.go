@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone database for hosts without one
 
 	"hospital-backend/database"
 	"hospital-backend/handlers"
 	"hospital-backend/middleware"
+	"hospital-backend/notify"
+	"hospital-backend/push"
+	"hospital-backend/repository"
+	"hospital-backend/repository/memory"
+	"hospital-backend/repository/postgres"
+	"hospital-backend/workers"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -19,14 +29,95 @@ func main() {
 		log.Println("No .env file found")
 	}
 
-	// Initialize database
-	if err := database.InitDB(); err != nil {
+	// Schema migrations: main migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// OpenAPI document: main openapi
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		runOpenAPI()
+		return
+	}
+
+	// Hospital timezone, in which doctors' slots are read
+	location, err := hospitalLocation()
+	if err != nil {
+		log.Fatal("Invalid HOSPITAL_TIMEZONE:", err)
+	}
+
+	// Slot holds: how long they last and how often expired ones are swept
+	holdTTL, err := durationEnv("SLOT_HOLD_TTL", handlers.DefaultHoldTTL)
+	if err != nil {
+		log.Fatal("Invalid SLOT_HOLD_TTL:", err)
+	}
+	sweepInterval, err := durationEnv("SLOT_HOLD_SWEEP_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal("Invalid SLOT_HOLD_SWEEP_INTERVAL:", err)
+	}
+
+	// Waitlist offers: how long patients have to accept and how often
+	// unanswered ones are passed on
+	offerTTL, err := durationEnv("WAITLIST_OFFER_TTL", handlers.DefaultOfferTTL)
+	if err != nil {
+		log.Fatal("Invalid WAITLIST_OFFER_TTL:", err)
+	}
+	offerInterval, err := durationEnv("WAITLIST_EXPIRY_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal("Invalid WAITLIST_EXPIRY_INTERVAL:", err)
+	}
+
+	// Idempotency keys: how long responses are replayed and how often
+	// expired ones are swept
+	idempotencyTTL, err := durationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	if err != nil {
+		log.Fatal("Invalid IDEMPOTENCY_KEY_TTL:", err)
+	}
+	idempotencyInterval, err := durationEnv("IDEMPOTENCY_SWEEP_INTERVAL", time.Hour)
+	if err != nil {
+		log.Fatal("Invalid IDEMPOTENCY_SWEEP_INTERVAL:", err)
+	}
+
+	// Retirement of the legacy /api and /api/mobile routes
+	deprecatedSince, err := dateEnv("LEGACY_API_DEPRECATED", "2026-11-01")
+	if err != nil {
+		log.Fatal("Invalid LEGACY_API_DEPRECATED:", err)
+	}
+	sunset, err := dateEnv("LEGACY_API_SUNSET", "2027-05-01")
+	if err != nil {
+		log.Fatal("Invalid LEGACY_API_SUNSET:", err)
+	}
+
+	// Appointment reminders
+	notifier, err := newNotifier()
+	if err != nil {
+		log.Fatal("Failed to configure notifications:", err)
+	}
+	reminderOffsets, err := reminderOffsets()
+	if err != nil {
+		log.Fatal("Invalid REMINDER_OFFSETS:", err)
+	}
+	reminderInterval, err := durationEnv("REMINDER_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal("Invalid REMINDER_INTERVAL:", err)
+	}
+
+	// Initialize storage
+	store, err := newStore()
+	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	// Initialize Firebase Admin SDK
-	if err := middleware.InitFirebase(); err != nil {
-		log.Fatal("Failed to initialize Firebase:", err)
+	// Initialize token verification
+	if err := middleware.InitAuth(); err != nil {
+		log.Fatal("Failed to initialize authentication:", err)
+	}
+
+	// Push notifications, which may share the Firebase app with auth
+	messenger, err := newMessenger()
+	if err != nil {
+		log.Fatal("Failed to configure push notifications:", err)
 	}
 
 	// Set Gin mode
@@ -34,50 +125,43 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create Gin router
-	r := gin.Default()
-
-	// CORS configuration
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // In production, specify your frontend URL
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
-
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Hospital API is running",
-		})
+	h := handlers.New(store, handlers.Config{
+		Location: location,
+		HoldTTL:  holdTTL,
+		OfferTTL: offerTTL,
+		Push:     messenger != nil,
 	})
 
-	// API routes
-	api := r.Group("/api")
-	{
-		// Public routes (no auth required)
-		api.GET("/doctors", handlers.GetDoctors)
-		api.GET("/doctors/:id", handlers.GetDoctorByID)
-		
-		// Mobile-optimized public routes
-		api.GET("/mobile/doctors", handlers.GetDoctorsMobile)
-		api.GET("/mobile/search/doctors", handlers.SearchDoctorsMobile)
-
-		// Protected routes (require Firebase auth)
-		protected := api.Group("/")
-		protected.Use(middleware.ValidateFirebaseToken())
-		{
-			protected.POST("/appointments", handlers.CreateAppointment)
-			protected.GET("/appointments", handlers.GetUserAppointments)
-			protected.PUT("/appointments/:id", handlers.UpdateAppointment)
-			protected.DELETE("/appointments/:id", handlers.CancelAppointment)
-			
-			// Mobile-optimized protected routes
-			protected.POST("/mobile/appointments", handlers.CreateAppointmentMobile)
-			protected.GET("/mobile/appointments", handlers.GetUserAppointmentsMobile)
+	// Background workers
+	go workers.SweepHolds(context.Background(), store.Holds, sweepInterval)
+	go workers.PromoteWaitlist(context.Background(), h, offerInterval)
+	go workers.SweepIdempotencyKeys(context.Background(), store.Idempotency, idempotencyInterval)
+	if len(reminderOffsets) > 0 {
+		reminders := &workers.Reminders{
+			Appointments: store.Appointments,
+			Reminders:    store.Reminders,
+			Notifier:     notifier,
+			Offsets:      reminderOffsets,
+			MaxAttempts:  3,
+			Location:     location,
 		}
+		go reminders.Run(context.Background(), reminderInterval)
+	}
+	if messenger != nil {
+		pushes := &workers.PushNotifications{
+			Devices:   store.Devices,
+			Messenger: messenger,
+			Location:  location,
+		}
+		go pushes.Run(context.Background(), h.AppointmentEvents())
+	}
+
+	r, err := newRouter(store, h, idempotencyTTL, middleware.Deprecation{
+		Since:  deprecatedSince,
+		Sunset: sunset,
+	})
+	if err != nil {
+		log.Fatal("Failed to set up routes:", err)
 	}
 
 	// Start server
@@ -92,3 +176,161 @@ func main() {
 	}
 }
 
+// newStore returns the repositories selected by STORAGE_BACKEND: "postgres"
+// (the default) or "memory", which keeps sample data in process memory and
+// needs no database
+func newStore() (repository.Store, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "postgres":
+		if err := database.InitDB(); err != nil {
+			return repository.Store{}, err
+		}
+		return postgres.NewStore(database.DB), nil
+
+	case "memory":
+		log.Println("Using in-memory storage; data is lost on restart")
+		return memory.NewSampleStore(), nil
+
+	default:
+		return repository.Store{}, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// newNotifier returns the notifier selected by NOTIFIER: "log" (the
+// default) writes messages to the server log, "file" appends them to
+// NOTIFY_FILE, "smtp" emails them through SMTP_ADDR and "sms" sends them
+// through the HTTP gateway at SMS_GATEWAY_URL
+func newNotifier() (notify.Notifier, error) {
+	switch kind := os.Getenv("NOTIFIER"); kind {
+	case "", "log":
+		return notify.NewLogNotifier(log.Writer()), nil
+
+	case "file":
+		path := os.Getenv("NOTIFY_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("NOTIFY_FILE is required for NOTIFIER=file")
+		}
+		return notify.NewFileNotifier(path)
+
+	case "smtp":
+		notifier := &notify.SMTPNotifier{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+		if notifier.Addr == "" || notifier.From == "" {
+			return nil, fmt.Errorf("SMTP_ADDR and SMTP_FROM are required for NOTIFIER=smtp")
+		}
+		return notifier, nil
+
+	case "sms":
+		notifier := &notify.SMSNotifier{
+			URL:    os.Getenv("SMS_GATEWAY_URL"),
+			APIKey: os.Getenv("SMS_API_KEY"),
+			From:   os.Getenv("SMS_FROM"),
+		}
+		if notifier.URL == "" {
+			return nil, fmt.Errorf("SMS_GATEWAY_URL is required for NOTIFIER=sms")
+		}
+		return notifier, nil
+
+	default:
+		return nil, fmt.Errorf("unknown NOTIFIER %q", kind)
+	}
+}
+
+// newMessenger returns the push messenger selected by PUSH_PROVIDER: "fcm"
+// sends through Firebase Cloud Messaging with the FIREBASE_PROJECT_ID app,
+// "http" posts to PUSH_HTTP_URL (such as cmd/pushsink), and "none" (the
+// default) turns push notifications off and returns nil
+func newMessenger() (push.Messenger, error) {
+	switch provider := os.Getenv("PUSH_PROVIDER"); provider {
+	case "", "none":
+		return nil, nil
+
+	case "fcm":
+		if middleware.FirebaseApp == nil {
+			if err := middleware.InitFirebase(); err != nil {
+				return nil, err
+			}
+		}
+		client, err := middleware.FirebaseApp.Messaging(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return &push.FCMMessenger{Client: client}, nil
+
+	case "http":
+		url := os.Getenv("PUSH_HTTP_URL")
+		if url == "" {
+			return nil, fmt.Errorf("PUSH_HTTP_URL is required for PUSH_PROVIDER=http")
+		}
+		return &push.HTTPMessenger{URL: url}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown PUSH_PROVIDER %q", provider)
+	}
+}
+
+// reminderOffsets returns the comma-separated durations in REMINDER_OFFSETS
+// (default "24h,1h"). "none" turns reminders off.
+func reminderOffsets() ([]time.Duration, error) {
+	value := os.Getenv("REMINDER_OFFSETS")
+	if value == "" {
+		value = "24h,1h"
+	}
+	if value == "none" {
+		return nil, nil
+	}
+
+	offsets := []time.Duration{}
+	for _, part := range strings.Split(value, ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if offset < time.Minute {
+			return nil, fmt.Errorf("offset %s is shorter than a minute", offset)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, nil
+}
+
+// hospitalLocation returns the timezone named by HOSPITAL_TIMEZONE (default
+// UTC)
+func hospitalLocation() (*time.Location, error) {
+	name := os.Getenv("HOSPITAL_TIMEZONE")
+	if name == "" {
+		name = "UTC"
+	}
+	return handlers.LoadLocation(name)
+}
+
+// durationEnv returns the positive duration ("90s", "5m") in the
+// environment variable name, or fallback when it is unset
+func durationEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return duration, nil
+}
+
+// dateEnv returns the date (YYYY-MM-DD, midnight UTC) in the environment
+// variable name, or fallback when it is unset
+func dateEnv(name, fallback string) (time.Time, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		value = fallback
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hospital-backend/handlers"
+	"hospital-backend/localauth"
+	"hospital-backend/middleware"
+	"hospital-backend/models"
+	"hospital-backend/openapi"
+	"hospital-backend/repository"
+	"hospital-backend/repository/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testAPI serves newRouter for tests, authenticating the tokens it mints
+type testAPI struct {
+	t      *testing.T
+	store  repository.Store
+	h      *handlers.Handler
+	router *gin.Engine
+	issuer *localauth.Issuer
+}
+
+// testResponse is a decoded /api/v1 response
+type testResponse struct {
+	Status  int
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+	Code    string          `json:"code"`
+}
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+	testKeyErr  error
+)
+
+// newTestAPI routes requests to a handler on store configured by config,
+// with local auth trusting a key generated for the tests
+func newTestAPI(t *testing.T, store repository.Store, config handlers.Config) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	testKeyOnce.Do(func() {
+		testKey, testKeyErr = localauth.GenerateKey()
+	})
+	if testKeyErr != nil {
+		t.Fatalf("generating signing key: %v", testKeyErr)
+	}
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := localauth.WriteJWKS(jwksPath, &testKey.PublicKey); err != nil {
+		t.Fatalf("writing JWKS: %v", err)
+	}
+	verifier, err := localauth.NewVerifier(jwksPath, "")
+	if err != nil {
+		t.Fatalf("loading JWKS: %v", err)
+	}
+	previous := middleware.Verifier
+	middleware.Verifier = &middleware.LocalVerifier{Verifier: verifier}
+	t.Cleanup(func() { middleware.Verifier = previous })
+
+	h := handlers.New(store, config)
+	r, err := newRouter(store, h, time.Hour, middleware.Deprecation{})
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	return &testAPI{t: t, store: store, h: h, router: r, issuer: localauth.NewIssuer(testKey, "")}
+}
+
+// token mints a bearer token for the user with uid, provisioned with role
+// on its first request
+func (api *testAPI) token(uid, role string) string {
+	api.t.Helper()
+	token, err := api.issuer.Mint(uid, uid+"@example.com", "", role, time.Hour)
+	if err != nil {
+		api.t.Fatalf("minting token: %v", err)
+	}
+	return token
+}
+
+// do sends a request with body encoded as JSON, authenticated by token
+// unless it is empty, and decodes the response
+func (api *testAPI) do(method, path, token string, body interface{}) testResponse {
+	api.t.Helper()
+	response, err := api.send(method, path, token, body)
+	if err != nil {
+		api.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return response
+}
+
+// send is do for goroutines other than the test's own, returning errors
+// rather than failing the test
+func (api *testAPI) send(method, path, token string, body interface{}) (testResponse, error) {
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return testResponse{}, fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, req)
+
+	response := testResponse{Status: w.Code}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		return response, fmt.Errorf("decoding response %q: %w", w.Body.String(), err)
+	}
+	return response, nil
+}
+
+// decode decodes the data of response into v
+func (response testResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		t.Fatalf("decoding response data %s: %v", response.Data, err)
+	}
+}
+
+// sampleDoctor returns the doctor profile of the sample user with
+// firebaseUID
+func sampleDoctor(t *testing.T, store repository.Store, firebaseUID string) *models.Doctor {
+	t.Helper()
+	ctx := context.Background()
+	user, err := store.Users.GetByFirebaseUID(ctx, firebaseUID)
+	if err != nil {
+		t.Fatalf("looking up sample user %s: %v", firebaseUID, err)
+	}
+	doctor, err := store.Doctors.GetByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("looking up sample doctor %s: %v", firebaseUID, err)
+	}
+	return doctor
+}
+
+// nextWeekday returns the date, as YYYY-MM-DD, of the first weekday at
+// least two days from now in UTC, so its slots are all in the future
+func nextWeekday(weekday time.Weekday) string {
+	day := time.Now().UTC().AddDate(0, 0, 2)
+	for day.Weekday() != weekday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.Format("2006-01-02")
+}
+
+// bookingRequest is the body booking slot on date with doctor
+func bookingRequest(doctor *models.Doctor, date, slot string) models.CreateAppointmentRequest {
+	return models.CreateAppointmentRequest{DoctorID: doctor.ID, AppointmentDate: date, Slot: slot}
+}
+
+// TestOpenAPICoversRoutes checks that the document served at /openapi.json
+// has an operation for every route the router serves
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	r, err := newRouter(store, handlers.New(store, handlers.Config{}), time.Hour, middleware.Deprecation{})
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding OpenAPI document: %v", err)
+	}
+
+	documented := 0
+	for _, route := range r.Routes() {
+		name := openapi.HandlerName(route.Handler)
+		if routeDocs[name].Hidden {
+			continue
+		}
+		documented++
+
+		path := ginPathTemplate(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("%s %s: path %s missing from the document", route.Method, route.Path, path)
+			continue
+		}
+		if item[strings.ToLower(route.Method)] == nil {
+			t.Errorf("%s %s: no %s operation on %s", route.Method, route.Path, route.Method, path)
+		}
+	}
+
+	operations := 0
+	for _, item := range doc.Paths {
+		operations += len(item)
+	}
+	if operations != documented {
+		t.Errorf("document has %d operations, want one per each of the %d served routes", operations, documented)
+	}
+}
+
+// ginPathTemplate turns a gin path into its OpenAPI path template:
+// /doctors/:id becomes /doctors/{id}
+func ginPathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && (segment[0] == ':' || segment[0] == '*') {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
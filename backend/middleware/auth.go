@@ -2,10 +2,16 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strings"
 
+	"hospital-backend/apierror"
+	"hospital-backend/localauth"
+	"hospital-backend/repository"
+
 	"firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
 	"github.com/gin-gonic/gin"
@@ -14,15 +20,53 @@ import (
 var (
 	FirebaseApp *firebase.App
 	AuthClient  *auth.Client
+
+	// Verifier checks bearer tokens on protected routes. It is nil when no
+	// auth provider is configured, in which case every request is rejected.
+	Verifier TokenVerifier
 )
 
-// InitFirebase initializes Firebase Admin SDK
-func InitFirebase() error {
-	// Check if Firebase is enabled via environment variable
-	if os.Getenv("FIREBASE_ENABLED") != "true" {
-		return nil // Firebase is disabled, skip initialization
+// InitAuth configures the token verifier selected by AUTH_PROVIDER:
+// "firebase" verifies Firebase ID tokens, "local" verifies tokens signed by
+// a key in the LOCAL_JWKS_FILE key set. FIREBASE_ENABLED=true is kept as a
+// shorthand for AUTH_PROVIDER=firebase.
+func InitAuth() error {
+	provider := os.Getenv("AUTH_PROVIDER")
+	if provider == "" && os.Getenv("FIREBASE_ENABLED") == "true" {
+		provider = "firebase"
+	}
+
+	switch provider {
+	case "firebase":
+		if err := InitFirebase(); err != nil {
+			return err
+		}
+		Verifier = &FirebaseVerifier{Client: AuthClient}
+
+	case "local":
+		jwksPath := os.Getenv("LOCAL_JWKS_FILE")
+		if jwksPath == "" {
+			jwksPath = "dev-keys/jwks.json"
+		}
+
+		verifier, err := localauth.NewVerifier(jwksPath, os.Getenv("LOCAL_JWT_ISSUER"))
+		if err != nil {
+			return fmt.Errorf("failed to load local JWKS: %w", err)
+		}
+		Verifier = &LocalVerifier{Verifier: verifier}
+
+	case "":
+		log.Println("No auth provider configured; protected routes will reject all requests")
+
+	default:
+		return fmt.Errorf("unknown AUTH_PROVIDER %q", provider)
 	}
 
+	return nil
+}
+
+// InitFirebase initializes Firebase Admin SDK
+func InitFirebase() error {
 	// In production, use service account key file
 	// For now, we'll use the Firebase project ID
 	projectID := os.Getenv("FIREBASE_PROJECT_ID")
@@ -31,7 +75,7 @@ func InitFirebase() error {
 	}
 
 	ctx := context.Background()
-	
+
 	// Initialize Firebase app
 	app, err := firebase.NewApp(ctx, &firebase.Config{
 		ProjectID: projectID,
@@ -52,63 +96,58 @@ func InitFirebase() error {
 	return nil
 }
 
-// ValidateFirebaseToken middleware validates Firebase JWT tokens
-func ValidateFirebaseToken() gin.HandlerFunc {
+// ValidateToken middleware verifies the bearer token with the configured
+// TokenVerifier and provisions the matching users row
+func ValidateToken(users repository.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// If Firebase is not initialized, skip authentication for testing
-		if AuthClient == nil {
-			// For testing purposes, set dummy user data
-			c.Set("user_id", "test-user-123")
-			c.Set("user_email", "test@example.com")
-			c.Set("user_name", "Test User")
-			c.Next()
+		if Verifier == nil {
+			AbortWithError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Authentication is not configured"))
 			return
 		}
 
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header required",
-			})
-			c.Abort()
+			AbortWithError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Authorization header required"))
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization header format",
-			})
-			c.Abort()
+			AbortWithError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Invalid authorization header format"))
 			return
 		}
 
 		token := tokenParts[1]
 
-		// Verify token with Firebase
-		ctx := context.Background()
-		tokenResult, err := AuthClient.VerifyIDToken(ctx, token)
+		// Verify token
+		identity, err := Verifier.VerifyToken(c.Request.Context(), token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid token",
-			})
-			c.Abort()
+			AbortWithError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Invalid token"))
 			return
 		}
 
-		// Extract user information
-		userID := tokenResult.UID
-		email := tokenResult.Claims["email"].(string)
-		name := tokenResult.Claims["name"].(string)
-
-		// Store user info in context
-		c.Set("user_id", userID)
-		c.Set("user_email", email)
-		c.Set("user_name", name)
-
-		c.Next()
+		setAuthenticatedUser(c, users, *identity)
 	}
 }
 
+// setAuthenticatedUser provisions the users row for a verified identity and
+// stores it in the context. "user_id" holds the internal users.id UUID.
+func setAuthenticatedUser(c *gin.Context, users repository.UserRepository, identity Identity) {
+	user, err := ProvisionUser(c.Request.Context(), users, identity)
+	if err != nil {
+		log.Printf("Failed to provision user %s: %v", identity.FirebaseUID, err)
+		AbortWithError(c, apierror.Internal("Failed to load user profile", err))
+		return
+	}
+
+	// Store user info in context
+	c.Set("user_id", user.ID)
+	c.Set("firebase_uid", user.FirebaseUID)
+	c.Set("user_email", user.Email)
+	c.Set("user_name", user.Name)
+	c.Set("user_role", user.Role)
+
+	c.Next()
+}
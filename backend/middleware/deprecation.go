@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation schedules the retirement of the legacy /api and /api/mobile
+// routes in favour of /api/v1
+type Deprecation struct {
+	// Since is when the routes were deprecated
+	Since time.Time
+	// Sunset is when they stop being served; zero when not yet decided
+	Sunset time.Time
+}
+
+// renamedRoutes maps legacy paths, with /api and /mobile trimmed, whose
+// /api/v1 successor lives elsewhere
+var renamedRoutes = map[string]string{
+	"/search/doctors": "/doctors/search",
+}
+
+// Deprecated marks responses of legacy routes with the Deprecation
+// (RFC 9745) and Sunset (RFC 8594) headers and a Link to their /api/v1
+// successor, and logs every use with the route, user and client so the
+// routes can be retired once their traffic stops
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		c.Header("Link", "<"+successorPath(c.Request.URL.Path)+`>; rel="successor-version"`)
+
+		c.Next()
+
+		user := "anonymous"
+		if userID, exists := c.Get("user_id"); exists {
+			user = fmt.Sprintf("%s %v", c.GetString("user_role"), userID)
+		}
+		log.Printf("[%s] deprecated route %s %s used by %s (client %q, status %d)",
+			RequestID(c), c.Request.Method, c.FullPath(), user, c.Request.UserAgent(), c.Writer.Status())
+	}
+}
+
+// successorPath returns the /api/v1 path serving the legacy path
+func successorPath(path string) string {
+	rest := strings.TrimPrefix(path, "/api")
+	rest = strings.TrimPrefix(rest, "/mobile")
+	if renamed, ok := renamedRoutes[rest]; ok {
+		rest = renamed
+	}
+	return "/api/v1" + rest
+}
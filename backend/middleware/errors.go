@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"hospital-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of a request in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern accepts client-supplied request IDs that are safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// envelopedPrefixes start the paths of routes answering in the
+// {"success", "message", "data"} envelope
+var envelopedPrefixes = []string{"/api/v1/", "/api/mobile/"}
+
+// Errors assigns every request an ID, taken from a well-formed
+// X-Request-ID header or generated, and returns it in X-Request-ID. Errors
+// given to AbortWithError, or added with c.Error and left unanswered by the
+// handler, are rendered with their code and the request ID: as
+// {"error", "code", "request_id", "fields"} on legacy routes, and in the
+// {"success", "message", "data"} envelope on /api/v1 and /api/mobile
+// routes. It must run before the
+// other middleware so their errors are rendered too.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			renderError(c, apierror.From(c.Errors.Last().Err))
+		}
+	}
+}
+
+// RequestID returns the ID Errors assigned to the request
+func RequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// Enveloped reports whether the request's route answers in the
+// {"success", "message", "data"} envelope rather than with bare fields
+func Enveloped(c *gin.Context) bool {
+	return EnvelopedPath(c.Request.URL.Path)
+}
+
+// EnvelopedPath reports whether the route at path answers in the
+// {"success", "message", "data"} envelope
+func EnvelopedPath(path string) bool {
+	for _, prefix := range envelopedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AbortWithError answers the request with err and stops the handler
+// chain. Errors other than *apierror.Error are reported as internal errors.
+func AbortWithError(c *gin.Context, err error) {
+	c.Abort()
+	renderError(c, apierror.From(err))
+}
+
+// renderError writes err in the envelope of the route, logging the cause
+// of server errors
+func renderError(c *gin.Context, err *apierror.Error) {
+	requestID := RequestID(c)
+	if err.Status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s failed: %v", requestID, c.Request.Method, c.Request.URL.Path, err)
+	}
+
+	if Enveloped(c) {
+		body := gin.H{
+			"success":    false,
+			"message":    "",
+			"error":      err.Message,
+			"code":       err.Code,
+			"request_id": requestID,
+		}
+		if len(err.Fields) > 0 {
+			body["fields"] = err.Fields
+		}
+		if len(err.Details) > 0 {
+			body["data"] = err.Details
+		}
+		c.JSON(err.Status, body)
+		return
+	}
+
+	// Details sit beside "error", where legacy clients read valid_slots
+	body := gin.H{}
+	for key, value := range err.Details {
+		body[key] = value
+	}
+	body["error"] = err.Message
+	body["code"] = err.Code
+	body["request_id"] = requestID
+	if len(err.Fields) > 0 {
+		body["fields"] = err.Fields
+	}
+	c.JSON(err.Status, body)
+}
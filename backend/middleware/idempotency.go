@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// IdempotencyKeyHeader names the request header carrying the key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength matches idempotency_keys.idempotency_key
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody bounds the request bodies read for hashing
+	maxIdempotentBody = 1 << 20
+	// idempotencyWait is how long a retry waits for the first request
+	// with its key to finish before giving up with 409
+	idempotencyWait = 10 * time.Second
+	// idempotencyPoll is how often a waiting retry checks the first request
+	idempotencyPoll = 100 * time.Millisecond
+)
+
+// Idempotency makes the route it guards safe to retry. The first request a
+// user sends with an Idempotency-Key is handled normally and its response
+// stored for ttl; retries with the same key and payload get that response
+// again, marked with an Idempotent-Replayed header, without reaching the
+// handler. A retry arriving while the first request is still handled waits
+// for it. Reusing a key with a different method, path or body returns 422.
+// Server errors are not stored, so the key can be retried. Requests without
+// the header are not affected. The route must be authenticated.
+func Idempotency(records repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			AbortWithError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		value, _ := c.Get("user_id")
+		userID, ok := value.(uuid.UUID)
+		if !ok {
+			AbortWithError(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User not authenticated"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody+1))
+		if err != nil || len(body) > maxIdempotentBody {
+			AbortWithError(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		record := &models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash(c.Request.Method, c.Request.URL.Path, body),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		existing, err := records.Start(ctx, record)
+		if err != nil {
+			log.Printf("Failed to record idempotency key for user %s: %v", userID, err)
+			AbortWithError(c, apierror.Internal("Failed to process request", err))
+			return
+		}
+
+		if existing != nil {
+			replay(c, records, existing, record.RequestHash)
+			c.Abort()
+			return
+		}
+
+		// Handle the first request, keeping a copy of the response. A
+		// panicking handler leaves no response, so the key is freed.
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			if !completed {
+				abandon(records, userID, key)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			abandon(records, userID, key)
+			completed = true
+			return
+		}
+
+		err = records.Complete(context.WithoutCancel(ctx), userID, key, status,
+			writer.Header().Get("Content-Type"), writer.body.Bytes())
+		if err != nil {
+			log.Printf("Failed to store the response for idempotency key of user %s: %v", userID, err)
+		}
+		completed = true
+	}
+}
+
+// replay answers a retry with the stored response of existing, waiting for
+// the first request to finish if needed
+func replay(c *gin.Context, records repository.IdempotencyRepository, existing *models.IdempotencyRecord, hash string) {
+	if existing.RequestHash != hash {
+		AbortWithError(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request"))
+		return
+	}
+
+	deadline := time.Now().Add(idempotencyWait)
+	for existing.StatusCode == 0 {
+		if time.Now().After(deadline) {
+			AbortWithError(c, apierror.New(http.StatusConflict, apierror.CodeRequestInProgress, "A request with this Idempotency-Key is still being processed"))
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(idempotencyPoll):
+		}
+
+		var err error
+		existing, err = records.Get(c.Request.Context(), existing.UserID, existing.Key)
+		if err == repository.ErrNotFound {
+			// The first request failed and freed the key
+			AbortWithError(c, apierror.New(http.StatusConflict, apierror.CodeConflict, "The original request with this Idempotency-Key failed; retry it"))
+			return
+		}
+		if err != nil {
+			AbortWithError(c, apierror.Internal("Failed to process request", err))
+			return
+		}
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+}
+
+// abandon frees the key of a request that produced no storable response
+func abandon(records repository.IdempotencyRepository, userID uuid.UUID, key string) {
+	err := records.Abandon(context.Background(), userID, key)
+	if err != nil && err != repository.ErrNotFound {
+		log.Printf("Failed to release idempotency key of user %s: %v", userID, err)
+	}
+}
+
+// requestHash fingerprints a request so a key reused for a different one
+// can be detected
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, method+" "+path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingWriter copies the response body as it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
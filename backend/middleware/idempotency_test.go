@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// idempotentRoute serves POST /appointments through Idempotency for one
+// authenticated user, answering with handle
+func idempotentRoute(handle gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+
+	r := gin.New()
+	r.POST("/appointments",
+		func(c *gin.Context) { c.Set("user_id", userID) },
+		Idempotency(memory.NewStore().Idempotency, time.Hour),
+		handle,
+	)
+	return r
+}
+
+// post sends body to r with the Idempotency-Key key
+func post(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/appointments", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(IdempotencyKeyHeader, key)
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// countingHandler books a numbered appointment on each call
+type countingHandler struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (h *countingHandler) handle(c *gin.Context) {
+	h.mu.Lock()
+	h.calls++
+	calls := h.calls
+	h.mu.Unlock()
+	c.JSON(http.StatusCreated, gin.H{"appointment": calls})
+}
+
+func (h *countingHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	handler := &countingHandler{}
+	r := idempotentRoute(handler.handle)
+
+	first := post(r, "key-1", `{"slot": "09:00"}`)
+	retry := post(r, "key-1", `{"slot": "09:00"}`)
+	if handler.count() != 1 {
+		t.Fatalf("handler called %d times, want 1", handler.count())
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want the first response %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked Idempotent-Replayed")
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("first response is marked Idempotent-Replayed")
+	}
+
+	// Another key is another request
+	post(r, "key-2", `{"slot": "09:00"}`)
+	if handler.count() != 2 {
+		t.Errorf("handler called %d times after a new key, want 2", handler.count())
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	handler := &countingHandler{}
+	r := idempotentRoute(handler.handle)
+
+	post(r, "key-1", `{"slot": "09:00"}`)
+	reused := post(r, "key-1", `{"slot": "10:00"}`)
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", reused.Code, http.StatusUnprocessableEntity)
+	}
+	var body struct {
+		Code apierror.Code `json:"code"`
+	}
+	if err := json.Unmarshal(reused.Body.Bytes(), &body); err != nil || body.Code != apierror.CodeIdempotencyKeyReused {
+		t.Errorf("code = %q (%v), want %s", body.Code, err, apierror.CodeIdempotencyKeyReused)
+	}
+	if handler.count() != 1 {
+		t.Errorf("handler called %d times, want 1", handler.count())
+	}
+}
+
+// TestIdempotencyRetryInFlight checks that a retry arriving while the first
+// request is handled waits for its response
+func TestIdempotencyRetryInFlight(t *testing.T) {
+	handler := &countingHandler{}
+	started, release := make(chan struct{}), make(chan struct{})
+	r := idempotentRoute(func(c *gin.Context) {
+		close(started)
+		<-release
+		handler.handle(c)
+	})
+
+	responses := make([]*httptest.ResponseRecorder, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		responses[0] = post(r, "key-1", `{"slot": "09:00"}`)
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		responses[1] = post(r, "key-1", `{"slot": "09:00"}`)
+	}()
+
+	// Let the retry find the request in flight before it completes
+	time.Sleep(2 * idempotencyPoll)
+	close(release)
+	wg.Wait()
+
+	if handler.count() != 1 {
+		t.Fatalf("handler called %d times, want 1", handler.count())
+	}
+	first, retry := responses[0], responses[1]
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want the first response %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked Idempotent-Replayed")
+	}
+}
+
+// TestIdempotencyServerErrorNotStored checks that a key whose request
+// failed with a server error can be retried
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	handler := &countingHandler{}
+	failing := true
+	r := idempotentRoute(func(c *gin.Context) {
+		if failing {
+			failing = false
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+			return
+		}
+		handler.handle(c)
+	})
+
+	if failed := post(r, "key-1", `{"slot": "09:00"}`); failed.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d, want %d", failed.Code, http.StatusInternalServerError)
+	}
+	retry := post(r, "key-1", `{"slot": "09:00"}`)
+	if retry.Code != http.StatusCreated {
+		t.Errorf("retry status = %d, want %d", retry.Code, http.StatusCreated)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("retry of a failed request is marked Idempotent-Replayed")
+	}
+	if handler.count() != 1 {
+		t.Errorf("handler booked %d times, want 1", handler.count())
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+)
+
+// Identity holds the claims of a verified token. Name, Email and Role are
+// empty when the token does not carry them.
+type Identity struct {
+	FirebaseUID string
+	Name        string
+	Email       string
+	Role        string
+}
+
+// ProvisionUser returns the users row for a verified identity, creating it
+// on first sight and keeping name and email in sync with the token claims.
+// The role claim only sets the role of users created here: once a user
+// exists, the stored role is authoritative and changes only through the
+// audited admin API.
+func ProvisionUser(ctx context.Context, users repository.UserRepository, identity Identity) (*models.User, error) {
+	user, err := users.GetByFirebaseUID(ctx, identity.FirebaseUID)
+	if err != nil && err != repository.ErrNotFound {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	name, email, role := identity.Name, identity.Email, identity.Role
+	if !IsValidRole(role) {
+		role = ""
+	}
+
+	// Claims are optional, so only overwrite what the token actually carries
+	if user != nil {
+		if name == "" {
+			name = user.Name
+		}
+		if email == "" {
+			email = user.Email
+		}
+		role = user.Role
+		if name == user.Name && email == user.Email && role == user.Role {
+			return user, nil
+		}
+	}
+
+	if name == "" {
+		name = defaultDisplayName(identity.FirebaseUID, email)
+	}
+	if role == "" {
+		role = models.RolePatient
+	}
+
+	provisioned := &models.User{
+		FirebaseUID: identity.FirebaseUID,
+		Name:        name,
+		Email:       email,
+		Role:        role,
+	}
+	if err := users.Upsert(ctx, provisioned); err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	return provisioned, nil
+}
+
+// defaultDisplayName picks a name for users whose token has no name claim,
+// since users.name is NOT NULL
+func defaultDisplayName(firebaseUID, email string) string {
+	if local, _, found := strings.Cut(email, "@"); found && local != "" {
+		return local
+	}
+	return firebaseUID
+}
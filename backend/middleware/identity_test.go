@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"hospital-backend/models"
+	"hospital-backend/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+func TestProvisionUserKeepsStoredRole(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewStore().Users
+
+	identity := Identity{FirebaseUID: "dr-bob", Email: "bob@hospital.com", Role: models.RoleDoctor}
+	user, err := ProvisionUser(ctx, users, identity)
+	if err != nil {
+		t.Fatalf("provisioning new user: %v", err)
+	}
+	if user.Role != models.RoleDoctor {
+		t.Fatalf("new user role = %q, want the claimed %q", user.Role, models.RoleDoctor)
+	}
+
+	if _, err := users.UpdateRole(ctx, user.ID, models.RolePatient, uuid.New()); err != nil {
+		t.Fatalf("updating role: %v", err)
+	}
+
+	identity.Name = "Bob"
+	user, err = ProvisionUser(ctx, users, identity)
+	if err != nil {
+		t.Fatalf("provisioning existing user: %v", err)
+	}
+	if user.Role != models.RolePatient {
+		t.Errorf("role after a later claim = %q, want the stored %q", user.Role, models.RolePatient)
+	}
+	if user.Name != "Bob" {
+		t.Errorf("name = %q, want it synced to %q", user.Name, "Bob")
+	}
+
+	stored, err := users.GetByFirebaseUID(ctx, "dr-bob")
+	if err != nil {
+		t.Fatalf("reading user: %v", err)
+	}
+	if stored.Role != models.RolePatient {
+		t.Errorf("stored role = %q, want %q", stored.Role, models.RolePatient)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"hospital-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoutePolicy names the roles allowed to use a group of routes
+type RoutePolicy struct {
+	Name  string
+	Roles []string
+}
+
+// Route policies used when declaring route groups in the router
+var (
+	AdminOnly = RoutePolicy{
+		Name:  "admin-only",
+		Roles: []string{models.RoleAdmin},
+	}
+	DoctorOnly = RoutePolicy{
+		Name:  "doctor-only",
+		Roles: []string{models.RoleDoctor},
+	}
+	PatientOnly = RoutePolicy{
+		Name:  "patient-only",
+		Roles: []string{models.RolePatient},
+	}
+	AnyRole = RoutePolicy{
+		Name:  "any-role",
+		Roles: []string{models.RolePatient, models.RoleDoctor, models.RoleAdmin},
+	}
+)
+
+// Allows reports whether the policy admits the given role
+func (p RoutePolicy) Allows(role string) bool {
+	for _, allowed := range p.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforce returns middleware that rejects users whose role the policy does
+// not admit
+func (p RoutePolicy) Enforce() gin.HandlerFunc {
+	return RequireRole(p.Roles...)
+}
+
+// IsValidRole reports whether role is one of the roles accepted by the
+// users.role CHECK constraint
+func IsValidRole(role string) bool {
+	return AnyRole.Allows(role)
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"hospital-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole middleware only lets through users whose role is one of
+// roles. It must run after ValidateToken, which sets "user_role".
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("user_role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		forbidden(c)
+	}
+}
+
+// forbidden writes the 403 response shared by every access denial
+func forbidden(c *gin.Context) {
+	AbortWithError(c, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "Insufficient permissions"))
+}
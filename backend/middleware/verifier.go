@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+
+	"hospital-backend/localauth"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// TokenVerifier verifies a bearer token and returns the identity it carries
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*Identity, error)
+}
+
+// FirebaseVerifier verifies Firebase ID tokens with the Admin SDK
+type FirebaseVerifier struct {
+	Client *auth.Client
+}
+
+// VerifyToken implements TokenVerifier
+func (v *FirebaseVerifier) VerifyToken(ctx context.Context, token string) (*Identity, error) {
+	tokenResult, err := v.Client.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Email, name and the role custom claim are optional
+	email, _ := tokenResult.Claims["email"].(string)
+	name, _ := tokenResult.Claims["name"].(string)
+	role, _ := tokenResult.Claims["role"].(string)
+
+	return &Identity{
+		FirebaseUID: tokenResult.UID,
+		Name:        name,
+		Email:       email,
+		Role:        role,
+	}, nil
+}
+
+// LocalVerifier verifies tokens minted by localauth.Issuer, e.g. with the
+// devtoken command
+type LocalVerifier struct {
+	Verifier *localauth.Verifier
+}
+
+// VerifyToken implements TokenVerifier
+func (v *LocalVerifier) VerifyToken(ctx context.Context, token string) (*Identity, error) {
+	claims, err := v.Verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		FirebaseUID: claims.Subject,
+		Name:        claims.Name,
+		Email:       claims.Email,
+		Role:        claims.Role,
+	}, nil
+}
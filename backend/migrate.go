@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"hospital-backend/database"
+)
+
+const migrateUsage = `Usage: main migrate <command>
+
+Commands:
+  up         apply all pending migrations
+  down [n]   revert the last n applied migrations (default 1)
+  status     list migrations and when they were applied`
+
+// runMigrate implements the "migrate" subcommand
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	if err := database.Connect(); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer database.DB.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, database.DB)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatalf("Invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+
+		reverted, err := database.MigrateDown(ctx, database.DB, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+
+	case "status":
+		states, err := database.MigrationStatus(ctx, database.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, applied)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Appointment types, matching the CHECK constraint on
+// appointments.appointment_type
+const (
+	TypeConsultation = "consultation"
+	TypeFollowUp     = "follow_up"
+	TypeProcedure    = "procedure"
+)
+
+// appointmentDurations is the length of each appointment type in minutes.
+// Types longer than the doctor's slot duration span several slots.
+var appointmentDurations = map[string]int{
+	TypeConsultation: 15,
+	TypeFollowUp:     10,
+	TypeProcedure:    45,
+}
+
+// Default slot settings of doctors that have not configured their own
+const (
+	DefaultSlotDuration  = 15
+	DefaultBufferMinutes = 0
+)
+
+// AppointmentDuration returns the length of appointment type kind, or false
+// when kind is not a known type. An empty kind is a consultation.
+func AppointmentDuration(kind string) (time.Duration, bool) {
+	if kind == "" {
+		kind = TypeConsultation
+	}
+	minutes, ok := appointmentDurations[kind]
+	return time.Duration(minutes) * time.Minute, ok
+}
@@ -6,45 +6,100 @@ import (
 	"github.com/google/uuid"
 )
 
+// User roles, matching the CHECK constraint on users.role
+const (
+	RolePatient = "patient"
+	RoleDoctor  = "doctor"
+	RoleAdmin   = "admin"
+)
+
 // User represents a user in the system
 type User struct {
 	ID          uuid.UUID `json:"id" db:"id"`
 	FirebaseUID string    `json:"firebase_uid" db:"firebase_uid"`
 	Name        string    `json:"name" db:"name"`
 	Email       string    `json:"email" db:"email"`
-	Role        string    `json:"role" db:"role"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// Phone receives SMS notifications; users set it themselves
+	Phone     string    `json:"phone,omitempty" db:"phone"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // Doctor represents a doctor profile
 type Doctor struct {
-	ID              uuid.UUID       `json:"id" db:"id"`
-	UserID          uuid.UUID       `json:"user_id" db:"user_id"`
-	Specialization  string          `json:"specialization" db:"specialization"`
-	Experience      int             `json:"experience" db:"experience"`
-	Phone           string          `json:"phone" db:"phone"`
-	AvailableSlots  map[string][]string `json:"available_slots" db:"available_slots"`
-	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID           `json:"id" db:"id"`
+	UserID         uuid.UUID           `json:"user_id" db:"user_id"`
+	Specialization string              `json:"specialization" db:"specialization"`
+	Experience     int                 `json:"experience" db:"experience"`
+	Phone          string              `json:"phone" db:"phone"`
+	AvailableSlots map[string][]string `json:"available_slots" db:"available_slots"`
+	Active         bool                `json:"active" db:"active"`
+	// Timezone is the IANA zone the slots are read in; empty means the
+	// hospital timezone
+	Timezone string `json:"timezone,omitempty" db:"timezone"`
+	// WorkingHours, when set, generates AvailableSlots: a slot starts every
+	// SlotDuration plus BufferMinutes within each period
+	WorkingHours map[string][]WorkingPeriod `json:"working_hours,omitempty" db:"working_hours"`
+	// SlotDuration is the length of a slot in minutes
+	SlotDuration int `json:"slot_duration" db:"slot_duration"`
+	// BufferMinutes is the gap kept free after each appointment
+	BufferMinutes int `json:"buffer_minutes" db:"buffer_minutes"`
+	// Languages the doctor consults in
+	Languages []string `json:"languages,omitempty" db:"languages"`
+	// Clinic is the clinic or department the doctor works at
+	Clinic    string    `json:"clinic,omitempty" db:"clinic"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	// Joined fields
-	User            *User           `json:"user,omitempty"`
+	User *User `json:"user,omitempty"`
+}
+
+// DoctorMatch is a doctor found by a search
+type DoctorMatch struct {
+	Doctor
+	// Score is how similar the query is to the doctor's name,
+	// specialization, languages and clinic, from 0 to 1
+	Score float64 `json:"score"`
+	// Highlights holds the searched fields that matched, as HTML with the
+	// matching words in <mark> tags
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// WorkingPeriod is a span of a working day, from Start up to End ("15:04")
+type WorkingPeriod struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
 }
 
 // Appointment represents an appointment
 type Appointment struct {
-	ID              uuid.UUID `json:"id" db:"id"`
-	DoctorID        uuid.UUID `json:"doctor_id" db:"doctor_id"`
-	PatientID       uuid.UUID `json:"patient_id" db:"patient_id"`
+	ID        uuid.UUID `json:"id" db:"id"`
+	DoctorID  uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	PatientID uuid.UUID `json:"patient_id" db:"patient_id"`
+	// AppointmentDate is the instant the slot starts
 	AppointmentDate time.Time `json:"appointment_date" db:"appointment_date"`
 	Slot            string    `json:"slot" db:"slot"`
-	Status          string    `json:"status" db:"status"`
-	Notes           string    `json:"notes" db:"notes"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	Type            string    `json:"appointment_type" db:"appointment_type"`
+	DurationMinutes int       `json:"duration_minutes" db:"duration_minutes"`
+	// OccupiedUntil is the end of the appointment plus the doctor's buffer;
+	// no other appointment of the doctor may start before it
+	OccupiedUntil time.Time `json:"-" db:"occupied_until"`
+	Status        string    `json:"status" db:"status"`
+	Notes         string    `json:"notes" db:"notes"`
+	ClinicalNotes string    `json:"clinical_notes,omitempty" db:"clinical_notes"`
+	// RescheduleRequired is set when leave or a holiday removed the slot
+	RescheduleRequired bool `json:"reschedule_required" db:"reschedule_required"`
+	// EndsAt is the instant the appointment ends
+	EndsAt time.Time `json:"ends_at" db:"-"`
+	// Timezone and LocalTime give the start in the doctor's timezone
+	Timezone  string    `json:"timezone,omitempty" db:"-"`
+	LocalTime string    `json:"local_time,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	// Joined fields
-	Doctor          *Doctor   `json:"doctor,omitempty"`
-	Patient         *User     `json:"patient,omitempty"`
+	Doctor  *Doctor `json:"doctor,omitempty"`
+	Patient *User   `json:"patient,omitempty"`
 }
 
 // CreateAppointmentRequest represents the request to create an appointment
@@ -52,7 +107,43 @@ type CreateAppointmentRequest struct {
 	DoctorID        uuid.UUID `json:"doctor_id" binding:"required"`
 	AppointmentDate string    `json:"appointment_date" binding:"required"`
 	Slot            string    `json:"slot" binding:"required"`
+	Type            string    `json:"appointment_type" binding:"omitempty,oneof=consultation follow_up procedure"`
 	Notes           string    `json:"notes"`
+	// HoldToken books the slot reserved by one of the patient's holds
+	HoldToken string `json:"hold_token"`
+}
+
+// SlotHold reserves a doctor's time for one patient until ExpiresAt, while
+// they complete a booking. Its ID is the hold token.
+type SlotHold struct {
+	ID        uuid.UUID `json:"hold_token" db:"id"`
+	DoctorID  uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	PatientID uuid.UUID `json:"patient_id" db:"patient_id"`
+	// StartsAt is the instant the held slot starts
+	StartsAt      time.Time `json:"starts_at" db:"starts_at"`
+	OccupiedUntil time.Time `json:"-" db:"occupied_until"`
+	Slot          string    `json:"slot" db:"slot"`
+	Type          string    `json:"appointment_type" db:"appointment_type"`
+	// Kind is HoldCheckout or HoldOffer; an empty kind is stored as
+	// HoldCheckout
+	Kind      string    `json:"kind" db:"kind"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Slot hold kinds. Checkout holds are taken by patients while they book;
+// offer holds reserve the slot of a waitlist offer.
+const (
+	HoldCheckout = "checkout"
+	HoldOffer    = "offer"
+)
+
+// HoldSlotRequest represents a patient holding a slot while they book it
+type HoldSlotRequest struct {
+	DoctorID        uuid.UUID `json:"doctor_id" binding:"required"`
+	AppointmentDate string    `json:"appointment_date" binding:"required"`
+	Slot            string    `json:"slot" binding:"required"`
+	Type            string    `json:"appointment_type" binding:"omitempty,oneof=consultation follow_up procedure"`
 }
 
 // UpdateAppointmentRequest represents the request to update an appointment
@@ -63,4 +154,290 @@ type UpdateAppointmentRequest struct {
 	Notes           string `json:"notes"`
 }
 
+// DayAvailability lists the free slots of a doctor on one date
+type DayAvailability struct {
+	Date    string   `json:"date"`
+	Weekday string   `json:"weekday"`
+	Slots   []string `json:"slots"`
+}
+
+// UpdateAppointmentStatusRequest represents a doctor marking a visit
+// completed or no-show
+type UpdateAppointmentStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// ClinicalNotesRequest represents the clinical notes a doctor adds to a visit
+type ClinicalNotesRequest struct {
+	ClinicalNotes string `json:"clinical_notes" binding:"required"`
+}
+
+// UpdateAvailableSlotsRequest replaces a doctor's weekly slot template
+type UpdateAvailableSlotsRequest struct {
+	AvailableSlots map[string][]string `json:"available_slots" binding:"required"`
+}
+
+// UpdateWorkingHoursRequest replaces a doctor's working hours and slot
+// settings
+type UpdateWorkingHoursRequest struct {
+	WorkingHours  map[string][]WorkingPeriod `json:"working_hours" binding:"required"`
+	SlotDuration  int                        `json:"slot_duration" binding:"required,min=5,max=240"`
+	BufferMinutes int                        `json:"buffer_minutes" binding:"min=0,max=120"`
+}
+
+// DayAgenda lists a doctor's appointments on one date
+type DayAgenda struct {
+	Date         string        `json:"date"`
+	Weekday      string        `json:"weekday"`
+	Appointments []Appointment `json:"appointments"`
+}
+
+// AuditEntry records one administrative change. Changes maps each changed
+// field to its old and new value.
+type AuditEntry struct {
+	ID         uuid.UUID              `json:"id" db:"id"`
+	ActorID    uuid.UUID              `json:"actor_id" db:"actor_id"`
+	Action     string                 `json:"action" db:"action"`
+	EntityType string                 `json:"entity_type" db:"entity_type"`
+	EntityID   uuid.UUID              `json:"entity_id" db:"entity_id"`
+	Changes    map[string]FieldChange `json:"changes" db:"changes"`
+	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
+}
+
+// FieldChange is the old and new value of a field in an AuditEntry
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// CreateUserRequest represents an admin creating a user
+type CreateUserRequest struct {
+	FirebaseUID string `json:"firebase_uid" binding:"required,max=255"`
+	Name        string `json:"name" binding:"required,max=255"`
+	Email       string `json:"email" binding:"required,email,max=255"`
+	Role        string `json:"role" binding:"required,oneof=patient doctor admin"`
+}
+
+// UpdateProfileRequest represents a user changing their own contact
+// details. An empty phone removes the number.
+type UpdateProfileRequest struct {
+	Phone string `json:"phone"`
+}
+
+// UpdateUserRoleRequest represents an admin changing a user's role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=patient doctor admin"`
+}
+
+// CreateDoctorRequest represents an admin creating the doctor profile of a
+// user with the doctor role
+type CreateDoctorRequest struct {
+	UserID         uuid.UUID                  `json:"user_id" binding:"required"`
+	Specialization string                     `json:"specialization" binding:"required,max=255"`
+	Experience     int                        `json:"experience" binding:"min=0,max=80"`
+	Phone          string                     `json:"phone" binding:"max=20"`
+	AvailableSlots map[string][]string        `json:"available_slots"`
+	Timezone       string                     `json:"timezone" binding:"max=64"`
+	WorkingHours   map[string][]WorkingPeriod `json:"working_hours"`
+	SlotDuration   int                        `json:"slot_duration" binding:"omitempty,min=5,max=240"`
+	BufferMinutes  int                        `json:"buffer_minutes" binding:"min=0,max=120"`
+	Languages      []string                   `json:"languages" binding:"max=10,dive,min=1,max=50"`
+	Clinic         string                     `json:"clinic" binding:"max=255"`
+}
 
+// UpdateDoctorRequest represents an admin updating a doctor profile. Only
+// the fields present in the request are changed.
+type UpdateDoctorRequest struct {
+	Specialization *string                    `json:"specialization" binding:"omitempty,min=1,max=255"`
+	Experience     *int                       `json:"experience" binding:"omitempty,min=0,max=80"`
+	Phone          *string                    `json:"phone" binding:"omitempty,max=20"`
+	AvailableSlots map[string][]string        `json:"available_slots"`
+	Active         *bool                      `json:"active"`
+	Timezone       *string                    `json:"timezone" binding:"omitempty,max=64"`
+	WorkingHours   map[string][]WorkingPeriod `json:"working_hours"`
+	SlotDuration   *int                       `json:"slot_duration" binding:"omitempty,min=5,max=240"`
+	BufferMinutes  *int                       `json:"buffer_minutes" binding:"omitempty,min=0,max=120"`
+	Languages      []string                   `json:"languages" binding:"omitempty,max=10,dive,min=1,max=50"`
+	Clinic         *string                    `json:"clinic" binding:"omitempty,max=255"`
+}
+
+// Schedule exception kinds
+const (
+	ExceptionBlock = "block"
+	ExceptionExtra = "extra"
+)
+
+// ScheduleException changes a doctor's weekly slots on one date. A block
+// removes the slots from StartTime up to EndTime, or the whole day when
+// both are empty; an extra adds Slots.
+type ScheduleException struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	DoctorID  uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	Date      string    `json:"date" db:"exception_date"`
+	Kind      string    `json:"kind" db:"kind"`
+	StartTime string    `json:"start_time,omitempty" db:"start_time"`
+	EndTime   string    `json:"end_time,omitempty" db:"end_time"`
+	Slots     []string  `json:"slots,omitempty" db:"slots"`
+	Reason    string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Holiday is a hospital-wide day off
+type Holiday struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Date      string    `json:"date" db:"holiday_date"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateScheduleExceptionRequest represents a doctor blocking time or
+// adding extra slots on one date
+type CreateScheduleExceptionRequest struct {
+	Date      string   `json:"date" binding:"required"`
+	Kind      string   `json:"kind" binding:"required,oneof=block extra"`
+	StartTime string   `json:"start_time"`
+	EndTime   string   `json:"end_time"`
+	Slots     []string `json:"slots"`
+	Reason    string   `json:"reason" binding:"max=255"`
+}
+
+// CreateHolidayRequest represents an admin adding a hospital holiday
+type CreateHolidayRequest struct {
+	Date string `json:"date" binding:"required"`
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// Waitlist entry statuses
+const (
+	WaitlistWaiting   = "waiting"
+	WaitlistOffered   = "offered"
+	WaitlistBooked    = "booked"
+	WaitlistCancelled = "cancelled"
+)
+
+// Waitlist offer statuses
+const (
+	OfferPending  = "pending"
+	OfferAccepted = "accepted"
+	OfferDeclined = "declined"
+	OfferExpired  = "expired"
+)
+
+// WaitlistEntry registers a patient for a slot of a doctor between FromDate
+// and ToDate (inclusive, in the doctor's timezone). An empty
+// PreferredSlots accepts any slot.
+type WaitlistEntry struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	PatientID      uuid.UUID `json:"patient_id" db:"patient_id"`
+	DoctorID       uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	FromDate       string    `json:"from_date" db:"from_date"`
+	ToDate         string    `json:"to_date" db:"to_date"`
+	PreferredSlots []string  `json:"preferred_slots" db:"preferred_slots"`
+	Type           string    `json:"appointment_type" db:"appointment_type"`
+	Status         string    `json:"status" db:"status"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WaitlistOffer gives the patient of a waitlist entry a freed slot until
+// ExpiresAt. The slot is reserved for them by the slot hold HoldID.
+type WaitlistOffer struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	EntryID   uuid.UUID `json:"entry_id" db:"entry_id"`
+	DoctorID  uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	PatientID uuid.UUID `json:"patient_id" db:"patient_id"`
+	HoldID    uuid.UUID `json:"-" db:"hold_id"`
+	// StartsAt is the instant the offered slot starts
+	StartsAt    time.Time  `json:"starts_at" db:"starts_at"`
+	Slot        string     `json:"slot" db:"slot"`
+	Type        string     `json:"appointment_type" db:"appointment_type"`
+	Status      string     `json:"status" db:"status"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+}
+
+// CreateWaitlistEntryRequest represents a patient joining a doctor's
+// waitlist
+type CreateWaitlistEntryRequest struct {
+	DoctorID       uuid.UUID `json:"doctor_id" binding:"required"`
+	FromDate       string    `json:"from_date" binding:"required"`
+	ToDate         string    `json:"to_date" binding:"required"`
+	PreferredSlots []string  `json:"preferred_slots"`
+	Type           string    `json:"appointment_type" binding:"omitempty,oneof=consultation follow_up procedure"`
+}
+
+// Reminder delivery states, matching the CHECK constraint on
+// appointment_reminders.status
+const (
+	ReminderSending = "sending"
+	ReminderSent    = "sent"
+	ReminderFailed  = "failed"
+	ReminderSkipped = "skipped"
+)
+
+// Reminder is the delivery state of the reminder sent OffsetMinutes before
+// an appointment starts. A reschedule moves StartsAt and so starts a new
+// set of reminders.
+type Reminder struct {
+	AppointmentID uuid.UUID  `json:"appointment_id" db:"appointment_id"`
+	StartsAt      time.Time  `json:"starts_at" db:"starts_at"`
+	OffsetMinutes int        `json:"offset_minutes" db:"offset_minutes"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Device platforms accepted when registering a push token
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWeb     = "web"
+)
+
+// DeviceToken is a push notification token registered by one of a user's
+// devices
+type DeviceToken struct {
+	Token     string    `json:"token" db:"token"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Platform  string    `json:"platform" db:"platform"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RegisterDeviceRequest represents a device registering its push token
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required,max=4096"`
+	Platform string `json:"platform" binding:"required,oneof=android ios web"`
+}
+
+// Appointment events announced to the patient by push notification
+const (
+	EventBooked      = "appointment_booked"
+	EventRescheduled = "appointment_rescheduled"
+	EventCancelled   = "appointment_cancelled"
+	EventNotesAdded  = "clinical_notes_added"
+)
+
+// AppointmentEvent is a change to an appointment the patient is told about
+type AppointmentEvent struct {
+	Kind        string
+	Appointment Appointment
+}
+
+// IdempotencyRecord is the response to the first request a user sent with
+// an Idempotency-Key, replayed to retries with the same key. StatusCode is
+// zero while the first request is still being handled.
+type IdempotencyRecord struct {
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Key         string    `json:"key" db:"idempotency_key"`
+	RequestHash string    `json:"request_hash" db:"request_hash"`
+	StatusCode  int       `json:"status_code" db:"status_code"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Body        []byte    `json:"-" db:"response_body"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
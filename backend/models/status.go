@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Appointment statuses, matching the CHECK constraint on appointments.status
+const (
+	StatusScheduled   = "scheduled"
+	StatusRescheduled = "rescheduled"
+	StatusCancelled   = "cancelled"
+	StatusCompleted   = "completed"
+	StatusNoShow      = "no_show"
+)
+
+// AppointmentStatuses lists every valid appointment status
+var AppointmentStatuses = []string{
+	StatusScheduled, StatusRescheduled, StatusCancelled, StatusCompleted, StatusNoShow,
+}
+
+// transitions maps each status to the statuses it may move to and the
+// actors allowed to trigger that move. Actors are the appointment's own
+// patient (RolePatient), its own doctor (RoleDoctor) or any admin
+// (RoleAdmin). Statuses missing from the map are final.
+var transitions = map[string]map[string][]string{
+	StatusScheduled: {
+		StatusRescheduled: {RolePatient, RoleDoctor, RoleAdmin},
+		StatusCancelled:   {RolePatient, RoleDoctor, RoleAdmin},
+		StatusCompleted:   {RoleDoctor, RoleAdmin},
+		StatusNoShow:      {RoleDoctor, RoleAdmin},
+	},
+	StatusRescheduled: {
+		StatusRescheduled: {RolePatient, RoleDoctor, RoleAdmin},
+		StatusCancelled:   {RolePatient, RoleDoctor, RoleAdmin},
+		StatusCompleted:   {RoleDoctor, RoleAdmin},
+		StatusNoShow:      {RoleDoctor, RoleAdmin},
+	},
+}
+
+// TransitionError explains why a status change was rejected
+type TransitionError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	return e.Reason
+}
+
+// IsValidStatus reports whether status is a known appointment status
+func IsValidStatus(status string) bool {
+	for _, valid := range AppointmentStatuses {
+		if status == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinalStatus reports whether no further transitions leave status
+func IsFinalStatus(status string) bool {
+	_, ok := transitions[status]
+	return !ok
+}
+
+// CheckTransition returns a *TransitionError unless actor may move an
+// appointment from status from to status to
+func CheckTransition(from, to, actor string) error {
+	if !IsValidStatus(to) {
+		return &TransitionError{From: from, To: to, Reason: fmt.Sprintf(
+			"Unknown status %q. Valid statuses: %s", to, strings.Join(AppointmentStatuses, ", "))}
+	}
+
+	targets, ok := transitions[from]
+	if !ok {
+		return &TransitionError{From: from, To: to, Reason: fmt.Sprintf(
+			"Appointment is %s and can no longer change status", from)}
+	}
+
+	actors, ok := targets[to]
+	if !ok {
+		return &TransitionError{From: from, To: to, Reason: fmt.Sprintf(
+			"Cannot change status from %s to %s", from, to)}
+	}
+
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+
+	return &TransitionError{From: from, To: to, Reason: fmt.Sprintf(
+		"Only %s can change status from %s to %s", describeActors(actors), from, to)}
+}
+
+func describeActors(actors []string) string {
+	names := make([]string, 0, len(actors))
+	for _, actor := range actors {
+		switch actor {
+		case RolePatient:
+			names = append(names, "the patient")
+		case RoleDoctor:
+			names = append(names, "the doctor")
+		case RoleAdmin:
+			names = append(names, "an admin")
+		}
+	}
+
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
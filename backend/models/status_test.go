@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	staff := []string{RoleDoctor, RoleAdmin}
+	everyone := []string{RolePatient, RoleDoctor, RoleAdmin}
+	fromActive := map[string][]string{
+		StatusRescheduled: everyone,
+		StatusCancelled:   everyone,
+		StatusCompleted:   staff,
+		StatusNoShow:      staff,
+	}
+	// allowed lists who may move an appointment from each status to each
+	// other; final statuses allow nothing
+	allowed := map[string]map[string][]string{
+		StatusScheduled:   fromActive,
+		StatusRescheduled: fromActive,
+	}
+
+	for _, from := range AppointmentStatuses {
+		for _, to := range AppointmentStatuses {
+			for _, actor := range everyone {
+				want := false
+				for _, permitted := range allowed[from][to] {
+					want = want || permitted == actor
+				}
+
+				err := CheckTransition(from, to, actor)
+				if want && err != nil {
+					t.Errorf("%s: %s -> %s: %v, want allowed", actor, from, to, err)
+				}
+				if !want {
+					var transitionErr *TransitionError
+					if !errors.As(err, &transitionErr) {
+						t.Errorf("%s: %s -> %s: error = %v, want a *TransitionError", actor, from, to, err)
+						continue
+					}
+					if transitionErr.From != from || transitionErr.To != to {
+						t.Errorf("%s: %s -> %s: error names %s -> %s", actor, from, to, transitionErr.From, transitionErr.To)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCheckTransitionReasons(t *testing.T) {
+	tests := []struct {
+		from, to, actor string
+		reason          string
+	}{
+		{StatusScheduled, "archived", RoleAdmin,
+			`Unknown status "archived". Valid statuses: scheduled, rescheduled, cancelled, completed, no_show`},
+		{StatusCompleted, StatusCancelled, RoleAdmin, "Appointment is completed and can no longer change status"},
+		{StatusCancelled, StatusRescheduled, RolePatient, "Appointment is cancelled and can no longer change status"},
+		{StatusNoShow, StatusScheduled, RoleDoctor, "Appointment is no_show and can no longer change status"},
+		{StatusScheduled, StatusScheduled, RoleAdmin, "Cannot change status from scheduled to scheduled"},
+		{StatusRescheduled, StatusScheduled, RoleDoctor, "Cannot change status from rescheduled to scheduled"},
+		{StatusScheduled, StatusCompleted, RolePatient, "Only the doctor or an admin can change status from scheduled to completed"},
+		{StatusRescheduled, StatusNoShow, RolePatient, "Only the doctor or an admin can change status from rescheduled to no_show"},
+	}
+	for _, tt := range tests {
+		err := CheckTransition(tt.from, tt.to, tt.actor)
+		if err == nil || err.Error() != tt.reason {
+			t.Errorf("%s: %s -> %s: error = %v, want %q", tt.actor, tt.from, tt.to, err, tt.reason)
+		}
+	}
+
+	// Rescheduling again is a transition of its own
+	if err := CheckTransition(StatusRescheduled, StatusRescheduled, RolePatient); err != nil {
+		t.Errorf("rescheduled -> rescheduled: %v", err)
+	}
+	if !IsFinalStatus(StatusCompleted) || !IsFinalStatus(StatusCancelled) || !IsFinalStatus(StatusNoShow) ||
+		IsFinalStatus(StatusScheduled) || IsFinalStatus(StatusRescheduled) {
+		t.Error("IsFinalStatus disagrees with the transition table")
+	}
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier writes each message as a JSON line instead of delivering it.
+// It is meant for development and for checking what would be sent.
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogNotifier returns a LogNotifier writing to w
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+// NewFileNotifier returns a LogNotifier appending to the file at path,
+// creating it if needed
+func NewFileNotifier(path string) (*LogNotifier, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogNotifier(file), nil
+}
+
+// Notify implements Notifier
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"time":    time.Now().UTC(),
+		"name":    msg.To.Name,
+		"email":   msg.To.Email,
+		"phone":   msg.To.Phone,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err = n.w.Write(append(line, '\n'))
+	return err
+}
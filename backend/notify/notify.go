@@ -0,0 +1,34 @@
+// Package notify delivers messages to patients. Each Notifier uses one
+// channel: email over SMTP, SMS through an HTTP gateway, or a log for
+// development.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoAddress is returned when the recipient has no address on the
+// notifier's channel, such as an SMS to a user without a phone number
+var ErrNoAddress = errors.New("recipient has no address for this channel")
+
+// Recipient is the person a message is for
+type Recipient struct {
+	Name  string
+	Email string
+	Phone string
+}
+
+// Message is a notification to one recipient. SMS gateways send only Body.
+type Message struct {
+	To      Recipient
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages
+type Notifier interface {
+	// Notify delivers msg, returning ErrNoAddress if the recipient cannot
+	// be reached on this channel
+	Notify(ctx context.Context, msg Message) error
+}
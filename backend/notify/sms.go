@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SMSNotifier sends messages as text messages through an HTTP gateway. It
+// POSTs {"to", "from", "message"} as JSON to URL with APIKey as a bearer
+// token, and treats any 2xx response as delivered.
+type SMSNotifier struct {
+	URL    string
+	APIKey string
+	// From is the sender ID or number, if the gateway needs one
+	From string
+	// Client defaults to a client with a 10 second timeout
+	Client *http.Client
+}
+
+// smsClient is used when SMSNotifier.Client is nil
+var smsClient = &http.Client{Timeout: 10 * time.Second}
+
+// Notify implements Notifier
+func (n *SMSNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.To.Phone == "" {
+		return ErrNoAddress
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"to":      msg.To.Phone,
+		"from":    n.From,
+		"message": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+n.APIKey)
+	}
+
+	client := n.Client
+	if client == nil {
+		client = smsClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
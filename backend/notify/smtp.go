@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends messages as plain-text email through an SMTP server
+type SMTPNotifier struct {
+	// Addr is the server's host:port
+	Addr string
+	// From is the sender address
+	From string
+	// Username and Password enable PLAIN authentication when Username is
+	// set. net/smtp only sends them over TLS or to localhost.
+	Username string
+	Password string
+}
+
+// Notify implements Notifier
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.To.Email == "" {
+		return ErrNoAddress
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, err := net.SplitHostPort(n.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	// smtp.SendMail takes no context, so cancellation only applies before
+	// sending starts
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(n.Addr, auth, n.From, []string{msg.To.Email}, n.compose(msg))
+}
+
+// compose formats msg as an RFC 5322 message
+func (n *SMTPNotifier) compose(msg Message) []byte {
+	to := msg.To.Email
+	if msg.To.Name != "" {
+		to = fmt.Sprintf("%s <%s>", mime.QEncoding.Encode("utf-8", msg.To.Name), msg.To.Email)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
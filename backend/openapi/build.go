@@ -0,0 +1,324 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access is who may call a route
+type Access int
+
+const (
+	// Public routes need no authentication
+	Public Access = iota
+	// Authenticated routes need a verified bearer token
+	Authenticated
+	// Admin routes need the admin role
+	Admin
+	// Doctor routes need the doctor role
+	Doctor
+)
+
+// Route documents the handler serving one or more gin routes
+type Route struct {
+	Summary     string
+	Description string
+	Tag         string
+	Access      Access
+	Query       []Param
+	// Body is a value of the JSON request body type
+	Body interface{}
+	// Status answers success, http.StatusOK when zero
+	Status int
+	// Data is a value of the type of the response data, usually Fields;
+	// nil for responses with a message only
+	Data interface{}
+	// ContentType replaces the JSON response, as text/calendar does
+	ContentType string
+	// Idempotent routes replay responses to retries with the same
+	// Idempotency-Key
+	Idempotent bool
+	// Hidden routes serve no API, such as the assets of the docs UI
+	Hidden bool
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Description string
+	// Type is the JSON type of the parameter, "string" when empty
+	Type   string
+	Format string
+	Enum   []string
+	// Default is the value used when the parameter is omitted
+	Default interface{}
+}
+
+// Options describe what Build documents beyond the routes
+type Options struct {
+	Info Info
+	Tags []Tag
+	// Envelope is a value of the type wrapping the data of enveloped
+	// responses
+	Envelope interface{}
+	// Enveloped reports whether the route at path answers in Envelope
+	// rather than with the data's fields beside "message"
+	Enveloped func(path string) bool
+	// Legacy reports whether the route at path is deprecated
+	Legacy func(path string) bool
+	// LegacyTag groups the deprecated routes
+	LegacyTag string
+	// ErrorCodes are the values of the "code" of error responses
+	ErrorCodes []string
+}
+
+// Build documents each served route with the Route of its handler in
+// routes, keyed by handler name (the method or function name, such as
+// "ListDoctors"). It returns the document and the served routes whose
+// handler has no Route, which are left out of it.
+func Build(served gin.RoutesInfo, routes map[string]Route, opts Options) (*Document, []string) {
+	s := &schemas{components: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    opts.Info,
+		Tags:    opts.Tags,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Firebase ID token, or a local token from cmd/devtoken",
+				},
+			},
+		},
+	}
+	if opts.LegacyTag != "" {
+		doc.Tags = append(doc.Tags, Tag{Name: opts.LegacyTag, Description: "Deprecated routes, served until their sunset"})
+	}
+
+	envelope := s.of(opts.Envelope)
+	s.components["Error"] = errorSchema(opts.ErrorCodes)
+
+	var missing []string
+	operationIDs := map[string]int{}
+	for _, info := range served {
+		name := HandlerName(info.Handler)
+		route, ok := routes[name]
+		if !ok {
+			missing = append(missing, fmt.Sprintf("%s %s (%s)", info.Method, info.Path, name))
+			continue
+		}
+		if route.Hidden {
+			continue
+		}
+
+		path, params := pathTemplate(info.Path)
+		enveloped := opts.Enveloped != nil && opts.Enveloped(info.Path)
+		legacy := opts.Legacy != nil && opts.Legacy(info.Path)
+
+		op := &Operation{
+			Tags:        []string{route.Tag},
+			Summary:     route.Summary,
+			Description: route.Description,
+			OperationID: operationID(name, info.Path, legacy, operationIDs),
+			Parameters:  params,
+			Responses:   map[string]Response{},
+			Deprecated:  legacy,
+		}
+		if legacy && opts.LegacyTag != "" {
+			op.Tags = []string{opts.LegacyTag}
+		}
+		if note := accessNote(route.Access); note != "" {
+			op.Description = strings.TrimSpace(op.Description + "\n\n" + note)
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		for _, param := range route.Query {
+			op.Parameters = append(op.Parameters, param.parameter())
+		}
+		if route.Idempotent {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        "Idempotency-Key",
+				In:          "header",
+				Description: "Unique key of this attempt; retries with the same key get the first response",
+				Schema:      &Schema{Type: "string", MaxLength: intPtr(255)},
+			})
+		}
+
+		if route.Body != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: s.of(route.Body)}},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		switch {
+		case route.ContentType != "":
+			success.Content = map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string"}}}
+		case enveloped:
+			success.Content = jsonContent(s.enveloped(envelope, route.Data))
+		default:
+			success.Content = jsonContent(s.flat(route.Data))
+		}
+		if legacy {
+			success.Headers = deprecationHeaders()
+		}
+		op.Responses[strconv.Itoa(status)] = success
+
+		errorBody := &Schema{Ref: "#/components/schemas/Error"}
+		if enveloped {
+			errorBody = &Schema{AllOf: []*Schema{envelope, errorBody}}
+		}
+		op.Responses["default"] = Response{Description: "Error", Content: jsonContent(errorBody)}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(info.Method)] = op
+	}
+
+	sort.Strings(missing)
+	return doc, missing
+}
+
+// HandlerName returns the method or function name of a handler from its
+// full name in gin.RouteInfo, such as "ListDoctors" for
+// "hospital-backend/handlers.(*Handler).ListDoctors-fm"
+func HandlerName(handler string) string {
+	handler = strings.TrimSuffix(handler, "-fm")
+	return handler[strings.LastIndex(handler, ".")+1:]
+}
+
+// pathTemplate turns a gin path into an OpenAPI path template and its path
+// parameters: /doctors/:id becomes /doctors/{id}
+func pathTemplate(path string) (string, []Parameter) {
+	segments := strings.Split(path, "/")
+	var params []Parameter
+	for i, segment := range segments {
+		if segment == "" || segment[0] != ':' && segment[0] != '*' {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID returns a unique operation ID for the handler name served at
+// path. Legacy routes are prefixed with their family.
+func operationID(name, path string, legacy bool, seen map[string]int) string {
+	id := name
+	if legacy {
+		family := "legacy"
+		if strings.HasPrefix(path, "/api/mobile/") {
+			family = "mobile"
+		}
+		id = family + strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	seen[id]++
+	if seen[id] > 1 {
+		id += strconv.Itoa(seen[id])
+	}
+	return id
+}
+
+// accessNote describes who may call a route; "" for public routes
+func accessNote(access Access) string {
+	switch access {
+	case Authenticated:
+		return "Requires a bearer token."
+	case Admin:
+		return "Requires a bearer token of an admin."
+	case Doctor:
+		return "Requires a bearer token of a doctor."
+	}
+	return ""
+}
+
+// enveloped returns the schema of envelope carrying data
+func (s *schemas) enveloped(envelope *Schema, data interface{}) *Schema {
+	if data == nil {
+		return envelope
+	}
+	return &Schema{AllOf: []*Schema{envelope, {
+		Type:       "object",
+		Properties: map[string]*Schema{"data": s.of(data)},
+	}}}
+}
+
+// flat returns the schema of a legacy response: the fields of data beside
+// "message"
+func (s *schemas) flat(data interface{}) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if fields, ok := data.(Fields); ok {
+		schema = s.object(fields)
+	} else if data != nil {
+		schema.AllOf = []*Schema{s.of(data)}
+	}
+	schema.Properties["message"] = &Schema{Type: "string"}
+	return schema
+}
+
+// errorSchema returns the schema of the fields describing an error
+func errorSchema(codes []string) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error":      {Type: "string", Description: "Human-readable description"},
+			"code":       {Type: "string", Enum: codes, Description: "Stable error code"},
+			"request_id": {Type: "string", Description: "ID of the request, as in the X-Request-ID header"},
+			"fields": {
+				Type:                 "object",
+				Description:          "The rule each invalid field broke",
+				AdditionalProperties: &Schema{Type: "string"},
+			},
+		},
+		Required: []string{"code", "error", "request_id"},
+	}
+}
+
+// deprecationHeaders describes the headers announcing a legacy route's
+// retirement
+func deprecationHeaders() map[string]Header {
+	return map[string]Header{
+		"Deprecation": {Description: "When the route was deprecated (RFC 9745)", Schema: &Schema{Type: "string"}},
+		"Sunset":      {Description: "When the route stops being served (RFC 8594)", Schema: &Schema{Type: "string"}},
+		"Link":        {Description: "The successor-version route under /api/v1", Schema: &Schema{Type: "string"}},
+	}
+}
+
+// parameter returns the query parameter p describes
+func (p Param) parameter() Parameter {
+	schema := &Schema{Type: p.Type, Format: p.Format, Enum: p.Enum, Default: p.Default}
+	if schema.Type == "" {
+		schema.Type = "string"
+	}
+	return Parameter{Name: p.Name, In: "query", Description: p.Description, Schema: schema}
+}
+
+// jsonContent returns a JSON body of schema
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// intPtr returns a pointer to n
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fields describes a JSON object by example: each key is a property and
+// each value a value of its type, such as models.Doctor{} or "". Handlers
+// answer with gin.H objects described this way.
+type Fields map[string]interface{}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	uuidType   = reflect.TypeOf(uuid.UUID{})
+	fieldsType = reflect.TypeOf(Fields{})
+)
+
+// schemas builds schemas from Go types, collecting named structs as
+// components
+type schemas struct {
+	components map[string]*Schema
+}
+
+// of returns the schema of values like value
+func (s *schemas) of(value interface{}) *Schema {
+	if fields, ok := value.(Fields); ok {
+		return s.object(fields)
+	}
+	return s.typeSchema(reflect.TypeOf(value))
+}
+
+// object returns the schema of an object with fields
+func (s *schemas) object(fields Fields) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for name, value := range fields {
+		schema.Properties[name] = s.of(value)
+	}
+	return schema
+}
+
+// typeSchema returns the schema of values of t as encoding/json writes
+// them. Named structs are referenced from the components.
+func (s *schemas) typeSchema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case fieldsType:
+		return &Schema{Type: "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := s.typeSchema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			s.components[t.Name()] = &Schema{}
+			*s.components[t.Name()] = *s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	// Interfaces and anything else hold any JSON value
+	return &Schema{}
+}
+
+// structSchema returns the object schema of the exported, JSON-encoded
+// fields of t. Binding rules become required properties, enums and limits.
+func (s *schemas) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// Untagged embedded structs contribute their fields, as
+		// encoding/json writes them
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := s.structSchema(field.Type)
+			for property, embeddedSchema := range embedded.Properties {
+				schema.Properties[property] = embeddedSchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.typeSchema(field.Type)
+		if applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// applyBinding adds the validator rules in binding to schema and reports
+// whether they make the property required
+func applyBinding(schema *Schema, binding string) bool {
+	if binding == "" {
+		return false
+	}
+
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		// Rules after dive apply to the elements
+		if name == "dive" {
+			break
+		}
+		switch name {
+		case "required":
+			required = true
+		case "oneof":
+			schema.Enum = strings.Fields(arg)
+		case "email":
+			schema.Format = "email"
+		case "min", "max":
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				continue
+			}
+			setLimit(schema, name, limit)
+		}
+	}
+	return required
+}
+
+// setLimit applies a min or max validator rule: a bound on numbers, on
+// the length of strings and on the items of arrays
+func setLimit(schema *Schema, rule string, limit int) {
+	switch schema.Type {
+	case "string":
+		if rule == "min" {
+			schema.MinLength = &limit
+		} else {
+			schema.MaxLength = &limit
+		}
+		return
+	case "array":
+		if rule == "min" {
+			schema.MinItems = &limit
+		} else {
+			schema.MaxItems = &limit
+		}
+		return
+	}
+
+	bound := float64(limit)
+	if rule == "min" {
+		schema.Minimum = &bound
+	} else {
+		schema.Maximum = &bound
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"hospital-backend/handlers"
+	"hospital-backend/models"
+	"hospital-backend/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+// listedPage is one page of a paged listing: the IDs of its items and its
+// pagination
+type listedPage struct {
+	ids        []uuid.UUID
+	pagination handlers.PaginationInfo
+}
+
+// listPage fetches path with query, reading the items listed under key
+func (api *testAPI) listPage(token, path, key string, query url.Values) listedPage {
+	api.t.Helper()
+	response := api.do(http.MethodGet, path+"?"+query.Encode(), token, nil)
+	if response.Status != http.StatusOK {
+		api.t.Fatalf("GET %s?%s: status = %d (%s)", path, query.Encode(), response.Status, response.Code)
+	}
+
+	var data map[string]json.RawMessage
+	response.decode(api.t, &data)
+	var items []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(data[key], &items); err != nil {
+		api.t.Fatalf("decoding %s: %v", key, err)
+	}
+	var page listedPage
+	if err := json.Unmarshal(data["pagination"], &page.pagination); err != nil {
+		api.t.Fatalf("decoding pagination: %v", err)
+	}
+	for _, item := range items {
+		page.ids = append(page.ids, item.ID)
+	}
+	return page
+}
+
+// expectCursorWalk walks the listing at path page by page with next_cursor
+// to its end and back with prev_cursor to its start, and fails the test
+// unless both walks list exactly the items of the unpaged listing, in
+// order, with has_next and has_prev set on every page that has a
+// neighbour in that direction
+func (api *testAPI) expectCursorWalk(token, path, key string, limit int) {
+	api.t.Helper()
+	t := api.t
+	want := api.listPage(token, path, key, url.Values{"limit": {"50"}}).ids
+	if len(want) <= 2*limit {
+		t.Fatalf("%s lists %d items, too few for a walk of pages of %d", path, len(want), limit)
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range want {
+		if seen[id] {
+			t.Fatalf("%s lists %s twice", path, id)
+		}
+		seen[id] = true
+	}
+	pageQuery := func(cursor string) url.Values {
+		query := url.Values{"limit": {fmt.Sprint(limit)}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		return query
+	}
+
+	page := api.listPage(token, path, key, pageQuery(""))
+	if page.pagination.HasPrev || page.pagination.PrevCursor != "" {
+		t.Errorf("%s first page has a previous page", path)
+	}
+	forward := page.ids
+	for page.pagination.HasNext {
+		page = api.listPage(token, path, key, pageQuery(page.pagination.NextCursor))
+		if len(page.ids) == 0 || len(page.ids) > limit {
+			t.Fatalf("%s page after %d items has %d items, want 1 to %d", path, len(forward), len(page.ids), limit)
+		}
+		if !page.pagination.HasPrev || page.pagination.PrevCursor == "" {
+			t.Errorf("%s page after %d items has no previous page", path, len(forward))
+		}
+		forward = append(forward, page.ids...)
+		if len(forward) > len(want) {
+			t.Fatalf("%s forward walk listed %d items, more than the %d there are", path, len(forward), len(want))
+		}
+	}
+	if !slices.Equal(forward, want) {
+		t.Errorf("%s forward walk = %v, want %v", path, forward, want)
+	}
+
+	backward := page.ids
+	for page.pagination.HasPrev {
+		page = api.listPage(token, path, key, pageQuery(page.pagination.PrevCursor))
+		if len(page.ids) == 0 || len(page.ids) > limit {
+			t.Fatalf("%s page before %d items has %d items, want 1 to %d", path, len(backward), len(page.ids), limit)
+		}
+		if !page.pagination.HasNext || page.pagination.NextCursor == "" {
+			t.Errorf("%s page before %d items has no next page", path, len(backward))
+		}
+		backward = append(slices.Clone(page.ids), backward...)
+		if len(backward) > len(want) {
+			t.Fatalf("%s backward walk listed %d items, more than the %d there are", path, len(backward), len(want))
+		}
+	}
+	if !slices.Equal(backward, want) {
+		t.Errorf("%s backward walk = %v, want %v", path, backward, want)
+	}
+}
+
+func TestDoctorCursorWalk(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+
+	// Doctors sharing a name are ordered by ID
+	for i := 0; i < 5; i++ {
+		user := &models.User{
+			FirebaseUID: fmt.Sprintf("dr-lee-%d", i),
+			Name:        "Dr. Alex Lee",
+			Email:       fmt.Sprintf("lee%d@hospital.com", i),
+			Role:        models.RoleDoctor,
+		}
+		if err := store.Users.Create(ctx, user, uuid.Nil); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		doctor := &models.Doctor{UserID: user.ID, Specialization: "Neurology"}
+		if err := store.Doctors.Create(ctx, doctor, uuid.Nil); err != nil {
+			t.Fatalf("creating doctor: %v", err)
+		}
+	}
+
+	api.expectCursorWalk("", "/api/v1/doctors", "doctors", 2)
+}
+
+func TestAppointmentCursorWalk(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	cardiologist := sampleDoctor(t, store, sampleCardiologist)
+	dermatologist := sampleDoctor(t, store, sampleDermatologist)
+	alice := api.token("alice", models.RolePatient)
+
+	// Appointments with both doctors at once are ordered by ID
+	monday := nextWeekday(time.Monday)
+	for _, date := range []string{monday, mustAddDays(t, monday, 7)} {
+		for _, slot := range []string{"10:00", "11:00"} {
+			api.mustBook(alice, cardiologist, date, slot)
+			api.mustBook(alice, dermatologist, date, slot)
+		}
+	}
+	api.mustBook(alice, cardiologist, monday, "09:00")
+
+	api.expectCursorWalk(alice, "/api/v1/appointments", "appointments", 3)
+}
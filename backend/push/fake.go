@@ -0,0 +1,89 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Delivery is a message recorded by a Fake
+type Delivery struct {
+	Tokens []string `json:"tokens"`
+	Message
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Fake records the messages sent through it instead of delivering them.
+// Tokens in Stale are reported as no longer registered. A Fake is also an
+// http.Handler speaking the HTTPMessenger protocol, so a server can be
+// pointed at one running in another process.
+type Fake struct {
+	mu         sync.Mutex
+	deliveries []Delivery
+	// Stale holds the tokens reported as no longer registered
+	Stale map[string]bool
+}
+
+// Send implements Messenger
+func (f *Fake) Send(ctx context.Context, tokens []string, msg Message) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deliveries = append(f.deliveries, Delivery{
+		Tokens:  append([]string{}, tokens...),
+		Message: msg,
+		SentAt:  time.Now(),
+	})
+
+	stale := []string{}
+	for _, token := range tokens {
+		if f.Stale[token] {
+			stale = append(stale, token)
+		}
+	}
+	return stale, nil
+}
+
+// Deliveries returns the messages sent so far, oldest first
+func (f *Fake) Deliveries() []Delivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Delivery{}, f.deliveries...)
+}
+
+// Reset forgets the recorded messages
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries = nil
+}
+
+// ServeHTTP records a POSTed HTTPMessenger request, lists the recorded
+// deliveries on GET and forgets them on DELETE
+func (f *Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid request"}`, http.StatusBadRequest)
+			return
+		}
+		stale, _ := f.Send(r.Context(), req.Tokens, req.Message)
+		json.NewEncoder(w).Encode(Response{Stale: stale})
+
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"deliveries": f.Deliveries()})
+
+	case http.MethodDelete:
+		f.Reset()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,42 @@
+package push
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// fcmBatchSize is the most tokens FCM accepts in one multicast
+const fcmBatchSize = 500
+
+// FCMMessenger sends push notifications through Firebase Cloud Messaging
+type FCMMessenger struct {
+	Client *messaging.Client
+}
+
+// Send implements Messenger. Tokens FCM reports as unregistered or
+// malformed are returned as stale; other per-token failures are dropped.
+func (m *FCMMessenger) Send(ctx context.Context, tokens []string, msg Message) ([]string, error) {
+	stale := []string{}
+	for start := 0; start < len(tokens); start += fcmBatchSize {
+		batch := tokens[start:min(start+fcmBatchSize, len(tokens))]
+		resp, err := m.Client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+			Tokens: batch,
+			Data:   msg.Data,
+			Notification: &messaging.Notification{
+				Title: msg.Title,
+				Body:  msg.Body,
+			},
+		})
+		if err != nil {
+			return stale, err
+		}
+
+		for i, result := range resp.Responses {
+			if !result.Success && (messaging.IsUnregistered(result.Error) || messaging.IsInvalidArgument(result.Error)) {
+				stale = append(stale, batch[i])
+			}
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,72 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPMessenger sends push notifications to an HTTP endpoint, such as a
+// push gateway or the local stand-in in cmd/pushsink. It POSTs
+// {"tokens", "title", "body", "data"} as JSON to URL and reads the stale
+// tokens from an optional {"stale": [...]} response.
+type HTTPMessenger struct {
+	URL string
+	// Client defaults to a client with a 10 second timeout
+	Client *http.Client
+}
+
+// httpClient is used when HTTPMessenger.Client is nil
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Request is the body HTTPMessenger sends
+type Request struct {
+	Tokens []string `json:"tokens"`
+	Message
+}
+
+// Response is the body HTTPMessenger accepts in reply
+type Response struct {
+	Stale []string `json:"stale"`
+}
+
+// Send implements Messenger
+func (m *HTTPMessenger) Send(ctx context.Context, tokens []string, msg Message) ([]string, error) {
+	payload, err := json.Marshal(Request{Tokens: tokens, Message: msg})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := m.Client
+	if client == nil {
+		client = httpClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("push endpoint returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var result Response
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("invalid push endpoint response: %w", err)
+		}
+	}
+	return result.Stale, nil
+}
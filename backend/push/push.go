@@ -0,0 +1,23 @@
+// Package push sends push notifications to the devices users register.
+// Messengers deliver through Firebase Cloud Messaging, through an HTTP
+// endpoint such as the local stand-in in cmd/pushsink, or into a Fake that
+// records them.
+package push
+
+import "context"
+
+// Message is a push notification. Data is delivered to the app alongside
+// the visible title and body.
+type Message struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// Messenger delivers push notifications to device tokens
+type Messenger interface {
+	// Send delivers msg to each of tokens. It returns the tokens the
+	// service reports as no longer registered, which should be forgotten,
+	// and an error only if the message could not be sent at all.
+	Send(ctx context.Context, tokens []string, msg Message) (stale []string, err error)
+}
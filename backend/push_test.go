@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"hospital-backend/handlers"
+	"hospital-backend/models"
+	"hospital-backend/push"
+	"hospital-backend/repository/memory"
+	"hospital-backend/workers"
+)
+
+// withPush runs the push worker of api on a Fake until the test ends
+func (api *testAPI) withPush() *push.Fake {
+	messenger := &push.Fake{}
+	pushes := &workers.PushNotifications{
+		Devices:   api.store.Devices,
+		Messenger: messenger,
+		Location:  time.UTC,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	api.t.Cleanup(cancel)
+	go pushes.Run(ctx, api.h.AppointmentEvents())
+	return messenger
+}
+
+// registerDevice registers device token for the user of bearer
+func (api *testAPI) registerDevice(bearer, token string) {
+	api.t.Helper()
+	response := api.do(http.MethodPost, "/api/v1/devices", bearer, models.RegisterDeviceRequest{Token: token, Platform: "android"})
+	if response.Status != http.StatusCreated {
+		api.t.Fatalf("registering device %s: status = %d (%s)", token, response.Status, response.Code)
+	}
+}
+
+// awaitDelivery waits for the next delivery of messenger after the first
+// seen ones, failing the test if none is sent within a few seconds
+func awaitDelivery(t *testing.T, messenger *push.Fake, seen int) push.Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries := messenger.Deliveries(); len(deliveries) > seen {
+			return deliveries[seen]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no push notification after the first %d", seen)
+	return push.Delivery{}
+}
+
+// expectDelivery fails the test unless delivery is the event kind for
+// appointment, starting at start, with title and body, sent to tokens
+func expectDelivery(t *testing.T, delivery push.Delivery, kind string, appointment models.Appointment, start time.Time, title, body string, tokens ...string) {
+	t.Helper()
+	got := slices.Sorted(slices.Values(delivery.Tokens))
+	if want := slices.Sorted(slices.Values(tokens)); !slices.Equal(got, want) {
+		t.Errorf("%s sent to %v, want %v", kind, got, want)
+	}
+	if delivery.Title != title || delivery.Body != body {
+		t.Errorf("%s message = %q: %q, want %q: %q", kind, delivery.Title, delivery.Body, title, body)
+	}
+	data := map[string]string{
+		"event":          kind,
+		"appointment_id": appointment.ID.String(),
+		"starts_at":      start.Format(time.RFC3339),
+	}
+	for key, value := range data {
+		if delivery.Data[key] != value {
+			t.Errorf("%s data %s = %q, want %q", kind, key, delivery.Data[key], value)
+		}
+	}
+}
+
+func TestAppointmentPushNotifications(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{Location: time.UTC, Push: true})
+	messenger := api.withPush()
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+
+	api.registerDevice(alice, "alice-phone")
+	api.registerDevice(alice, "alice-tablet")
+	api.registerDevice(bob, "bob-phone")
+
+	monday := nextWeekday(time.Monday)
+	start, err := time.Parse("2006-01-02 15:04", monday+" 09:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := func(start time.Time) string { return start.Format("Mon, 2 Jan at 15:04") }
+
+	appointment := api.mustBook(alice, doctor, monday, "09:00")
+	expectDelivery(t, awaitDelivery(t, messenger, 0), models.EventBooked, appointment, start,
+		"Appointment confirmed", "Your appointment with Dr. John Smith is booked for "+when(start)+".",
+		"alice-phone", "alice-tablet")
+
+	path := "/api/v1/appointments/" + appointment.ID.String()
+	response := api.do(http.MethodPut, path, alice, models.UpdateAppointmentRequest{Slot: "10:00"})
+	if response.Status != http.StatusOK {
+		t.Fatalf("rescheduling: status = %d (%s)", response.Status, response.Code)
+	}
+	moved := start.Add(time.Hour)
+	expectDelivery(t, awaitDelivery(t, messenger, 1), models.EventRescheduled, appointment, moved,
+		"Appointment rescheduled", "Your appointment with Dr. John Smith is now on "+when(moved)+".",
+		"alice-phone", "alice-tablet")
+
+	response = api.do(http.MethodDelete, path, alice, nil)
+	if response.Status != http.StatusOK {
+		t.Fatalf("cancelling: status = %d (%s)", response.Status, response.Code)
+	}
+	expectDelivery(t, awaitDelivery(t, messenger, 2), models.EventCancelled, appointment, moved,
+		"Appointment cancelled", "Your appointment with Dr. John Smith on "+when(moved)+" has been cancelled.",
+		"alice-phone", "alice-tablet")
+
+	// Updates that neither move nor cancel the appointment send nothing,
+	// so the next message is bob's own booking, to bob's device only
+	other := api.mustBook(bob, doctor, monday, "11:00")
+	response = api.do(http.MethodPut, "/api/v1/appointments/"+other.ID.String(), bob, models.UpdateAppointmentRequest{Notes: "Bring reports"})
+	if response.Status != http.StatusOK {
+		t.Fatalf("updating notes: status = %d (%s)", response.Status, response.Code)
+	}
+	bobStart := start.Add(2 * time.Hour)
+	expectDelivery(t, awaitDelivery(t, messenger, 3), models.EventBooked, other, bobStart,
+		"Appointment confirmed", "Your appointment with Dr. John Smith is booked for "+when(bobStart)+".",
+		"bob-phone")
+	time.Sleep(50 * time.Millisecond)
+	if n := len(messenger.Deliveries()); n != 4 {
+		t.Errorf("sent %d push notifications, want 4", n)
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"reflect"
+
+	"hospital-backend/models"
+)
+
+// Audited entity types
+const (
+	EntityUser   = "user"
+	EntityDoctor = "doctor"
+)
+
+// Audit actions
+const (
+	ActionUserCreate       = "user.create"
+	ActionUserRoleChange   = "user.role_change"
+	ActionDoctorCreate     = "doctor.create"
+	ActionDoctorUpdate     = "doctor.update"
+	ActionDoctorDeactivate = "doctor.deactivate"
+	ActionDoctorActivate   = "doctor.activate"
+)
+
+// DoctorChanges returns the audited fields that differ between before and
+// after, and the action that describes the change
+func DoctorChanges(before, after *models.Doctor) (string, map[string]models.FieldChange) {
+	changes := map[string]models.FieldChange{}
+	add := func(field string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			changes[field] = models.FieldChange{From: from, To: to}
+		}
+	}
+
+	add("specialization", before.Specialization, after.Specialization)
+	add("experience", before.Experience, after.Experience)
+	add("phone", before.Phone, after.Phone)
+	add("available_slots", before.AvailableSlots, after.AvailableSlots)
+	add("active", before.Active, after.Active)
+	add("timezone", before.Timezone, after.Timezone)
+	add("working_hours", before.WorkingHours, after.WorkingHours)
+	add("slot_duration", before.SlotDuration, after.SlotDuration)
+	add("buffer_minutes", before.BufferMinutes, after.BufferMinutes)
+	add("languages", before.Languages, after.Languages)
+	add("clinic", before.Clinic, after.Clinic)
+
+	action := ActionDoctorUpdate
+	if before.Active != after.Active {
+		action = ActionDoctorActivate
+		if !after.Active {
+			action = ActionDoctorDeactivate
+		}
+	}
+	return action, changes
+}
+
+// DoctorFields returns the audited fields of a new doctor profile
+func DoctorFields(doctor *models.Doctor) map[string]models.FieldChange {
+	return map[string]models.FieldChange{
+		"user_id":         {To: doctor.UserID},
+		"specialization":  {To: doctor.Specialization},
+		"experience":      {To: doctor.Experience},
+		"phone":           {To: doctor.Phone},
+		"available_slots": {To: doctor.AvailableSlots},
+		"active":          {To: doctor.Active},
+		"timezone":        {To: doctor.Timezone},
+		"working_hours":   {To: doctor.WorkingHours},
+		"slot_duration":   {To: doctor.SlotDuration},
+		"buffer_minutes":  {To: doctor.BufferMinutes},
+		"languages":       {To: doctor.Languages},
+		"clinic":          {To: doctor.Clinic},
+	}
+}
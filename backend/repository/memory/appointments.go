@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// AppointmentRepository implements repository.AppointmentRepository
+type AppointmentRepository struct {
+	data *data
+}
+
+// Book implements repository.AppointmentRepository
+func (r *AppointmentRepository) Book(ctx context.Context, appointment *models.Appointment, bookedBy uuid.UUID, holdID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.doctors[appointment.DoctorID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	if appointment.ID == uuid.Nil {
+		appointment.ID = uuid.New()
+	}
+	appointment.Status = models.StatusScheduled
+
+	if r.data.slotTaken(appointment) {
+		return repository.ErrSlotTaken
+	}
+	if r.data.slotHeld(appointment.DoctorID, appointment.PatientID, appointment.AppointmentDate, appointment.OccupiedUntil) {
+		return repository.ErrSlotHeld
+	}
+	if holdID != uuid.Nil && !r.data.takeHold(holdID, appointment.PatientID) {
+		return repository.ErrHoldExpired
+	}
+
+	now := time.Now()
+	appointment.CreatedAt = now
+	appointment.UpdatedAt = now
+
+	stored := *appointment
+	stored.Doctor = nil
+	stored.Patient = nil
+	r.data.appointments[stored.ID] = &stored
+	r.data.recordStatusChange(stored.ID, "", stored.Status, bookedBy)
+
+	return nil
+}
+
+// Get implements repository.AppointmentRepository
+func (r *AppointmentRepository) Get(ctx context.Context, id uuid.UUID) (*models.Appointment, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	appointment, ok := r.data.appointments[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	joined := r.data.joinAppointment(appointment)
+	return &joined, nil
+}
+
+// List implements repository.AppointmentRepository
+func (r *AppointmentRepository) List(ctx context.Context, filter repository.AppointmentFilter) ([]models.Appointment, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	appointments := r.data.matchAppointments(filter)
+	start, end := page(len(appointments), filter.Limit, filter.Offset)
+	switch {
+	case filter.After != nil:
+		start, end = keysetPage(len(appointments), filter.Limit, false,
+			appointmentPosition(appointments, *filter.After, filter.Ascending))
+	case filter.Before != nil:
+		start, end = keysetPage(len(appointments), filter.Limit, true,
+			appointmentPosition(appointments, *filter.Before, filter.Ascending))
+	}
+	return appointments[start:end], nil
+}
+
+// appointmentPosition compares appointments, ordered as matchAppointments
+// orders them, with key
+func appointmentPosition(appointments []models.Appointment, key repository.AppointmentKey, ascending bool) func(i int) int {
+	return func(i int) int {
+		c := appointments[i].AppointmentDate.Compare(key.Date)
+		if c == 0 {
+			c = strings.Compare(appointments[i].ID.String(), key.ID.String())
+		}
+		if !ascending {
+			return -c
+		}
+		return c
+	}
+}
+
+// Count implements repository.AppointmentRepository
+func (r *AppointmentRepository) Count(ctx context.Context, filter repository.AppointmentFilter) (int, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return len(r.data.matchAppointments(filter)), nil
+}
+
+// Update implements repository.AppointmentRepository
+func (r *AppointmentRepository) Update(ctx context.Context, id uuid.UUID, changedBy uuid.UUID, mutate func(appointment *models.Appointment) error) (*models.Appointment, error) {
+	r.data.updateMu.Lock()
+	defer r.data.updateMu.Unlock()
+
+	before, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	appointment := *before
+	if err := mutate(&appointment); err != nil {
+		return nil, err
+	}
+
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if appointment.Status != models.StatusCancelled && r.data.slotTaken(&appointment) {
+		return nil, repository.ErrSlotTaken
+	}
+
+	// A move must not land on time another patient holds
+	moved := !appointment.AppointmentDate.Equal(before.AppointmentDate) ||
+		!appointment.OccupiedUntil.Equal(before.OccupiedUntil)
+	if moved && appointment.Status != models.StatusCancelled &&
+		r.data.slotHeld(appointment.DoctorID, appointment.PatientID, appointment.AppointmentDate, appointment.OccupiedUntil) {
+		return nil, repository.ErrSlotHeld
+	}
+
+	stored := r.data.appointments[id]
+	stored.AppointmentDate = appointment.AppointmentDate
+	stored.Slot = appointment.Slot
+	stored.OccupiedUntil = appointment.OccupiedUntil
+	stored.Status = appointment.Status
+	stored.Notes = appointment.Notes
+	stored.ClinicalNotes = appointment.ClinicalNotes
+	stored.RescheduleRequired = appointment.RescheduleRequired
+	stored.UpdatedAt = time.Now()
+
+	if repository.IsTransition(before, &appointment) {
+		r.data.recordStatusChange(id, before.Status, appointment.Status, changedBy)
+	}
+
+	updated := r.data.joinAppointment(stored)
+	return &updated, nil
+}
+
+// slotTaken reports whether another active appointment of the doctor
+// overlaps the time appointment occupies, as the appointments_no_overlap
+// constraint does in PostgreSQL. It must be called with the lock held.
+func (d *data) slotTaken(appointment *models.Appointment) bool {
+	for _, other := range d.appointments {
+		if other.ID != appointment.ID &&
+			other.Status != models.StatusCancelled &&
+			other.DoctorID == appointment.DoctorID &&
+			other.AppointmentDate.Before(appointment.OccupiedUntil) &&
+			appointment.AppointmentDate.Before(other.OccupiedUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAppointments returns the appointments selected by filter, joined and
+// ordered. It must be called with the lock held.
+func (d *data) matchAppointments(filter repository.AppointmentFilter) []models.Appointment {
+	appointments := []models.Appointment{}
+	for _, appointment := range d.appointments {
+		switch {
+		case filter.PatientID != uuid.Nil && appointment.PatientID != filter.PatientID,
+			filter.DoctorID != uuid.Nil && appointment.DoctorID != filter.DoctorID,
+			!filter.From.IsZero() && appointment.AppointmentDate.Before(filter.From),
+			!filter.Until.IsZero() && !appointment.AppointmentDate.Before(filter.Until),
+			filter.ActiveOnly && appointment.Status == models.StatusCancelled:
+			continue
+		}
+		appointments = append(appointments, d.joinAppointment(appointment))
+	}
+
+	sort.Slice(appointments, func(i, j int) bool {
+		a, b := appointments[i], appointments[j]
+		if !a.AppointmentDate.Equal(b.AppointmentDate) {
+			return a.AppointmentDate.Before(b.AppointmentDate) == filter.Ascending
+		}
+		return (a.ID.String() < b.ID.String()) == filter.Ascending
+	})
+	return appointments
+}
+
+// joinAppointment copies an appointment with its doctor and patient summary, as the
+// PostgreSQL queries return it. It must be called with the lock held.
+func (d *data) joinAppointment(appointment *models.Appointment) models.Appointment {
+	joined := *appointment
+	doctor := models.Doctor{ID: appointment.DoctorID, User: &models.User{}}
+	if stored, ok := d.doctors[appointment.DoctorID]; ok {
+		doctor.UserID = stored.UserID
+		doctor.Specialization = stored.Specialization
+		doctor.Timezone = stored.Timezone
+		doctor.User.ID = stored.UserID
+		if user, ok := d.users[stored.UserID]; ok {
+			doctor.User.Name = user.Name
+			doctor.User.Email = user.Email
+		}
+	}
+	joined.Doctor = &doctor
+
+	patient := models.User{ID: appointment.PatientID}
+	if user, ok := d.users[appointment.PatientID]; ok {
+		patient.Name = user.Name
+		patient.Email = user.Email
+		patient.Phone = user.Phone
+	}
+	joined.Patient = &patient
+	return joined
+}
+
+// recordStatusChange must be called with the lock held
+func (d *data) recordStatusChange(appointmentID uuid.UUID, from, to string, changedBy uuid.UUID) {
+	d.history = append(d.history, statusChange{
+		AppointmentID: appointmentID,
+		FromStatus:    from,
+		ToStatus:      to,
+		ChangedBy:     changedBy,
+		ChangedAt:     time.Now(),
+	})
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// AuditRepository implements repository.AuditRepository
+type AuditRepository struct {
+	data *data
+}
+
+// List implements repository.AuditRepository
+func (r *AuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for i := len(r.data.audit) - 1; i >= 0; i-- {
+		entry := r.data.audit[i]
+		switch {
+		case filter.EntityType != "" && entry.EntityType != filter.EntityType,
+			filter.EntityID != uuid.Nil && entry.EntityID != filter.EntityID,
+			filter.ActorID != uuid.Nil && entry.ActorID != filter.ActorID:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	start, end := page(len(entries), filter.Limit, filter.Offset)
+	return entries[start:end], nil
+}
+
+// recordAudit appends an entry to the audit log. It must be called with
+// the lock held.
+func (d *data) recordAudit(actorID uuid.UUID, action, entityType string, entityID uuid.UUID, changes map[string]models.FieldChange) {
+	d.audit = append(d.audit, models.AuditEntry{
+		ID:         uuid.New(),
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+		CreatedAt:  time.Now(),
+	})
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// DeviceRepository implements repository.DeviceRepository
+type DeviceRepository struct {
+	data *data
+}
+
+// Register implements repository.DeviceRepository
+func (r *DeviceRepository) Register(ctx context.Context, device *models.DeviceToken) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.users[device.UserID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	device.CreatedAt = now
+	if existing, ok := r.data.devices[device.Token]; ok {
+		device.CreatedAt = existing.CreatedAt
+	}
+	device.UpdatedAt = now
+
+	stored := *device
+	r.data.devices[stored.Token] = &stored
+	return nil
+}
+
+// Unregister implements repository.DeviceRepository
+func (r *DeviceRepository) Unregister(ctx context.Context, userID uuid.UUID, token string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	device, ok := r.data.devices[token]
+	if !ok || device.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(r.data.devices, token)
+	return nil
+}
+
+// Tokens implements repository.DeviceRepository
+func (r *DeviceRepository) Tokens(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	devices := []*models.DeviceToken{}
+	for _, device := range r.data.devices {
+		if device.UserID == userID {
+			devices = append(devices, device)
+		}
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if !devices[i].CreatedAt.Equal(devices[j].CreatedAt) {
+			return devices[i].CreatedAt.Before(devices[j].CreatedAt)
+		}
+		return devices[i].Token < devices[j].Token
+	})
+	tokens := make([]string, len(devices))
+	for i, device := range devices {
+		tokens[i] = device.Token
+	}
+	return tokens, nil
+}
+
+// Forget implements repository.DeviceRepository
+func (r *DeviceRepository) Forget(ctx context.Context, tokens []string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, token := range tokens {
+		delete(r.data.devices, token)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+	"hospital-backend/textsearch"
+
+	"github.com/google/uuid"
+)
+
+// DoctorRepository implements repository.DoctorRepository
+type DoctorRepository struct {
+	data *data
+}
+
+// List implements repository.DoctorRepository
+func (r *DoctorRepository) List(ctx context.Context, filter repository.DoctorFilter) ([]models.Doctor, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	doctors := r.data.matchDoctors(func(doctor *models.Doctor, user *models.User) bool {
+		return (filter.Specialization == "" || doctor.Specialization == filter.Specialization) &&
+			(filter.IncludeInactive || doctor.Active)
+	})
+
+	start, end := page(len(doctors), filter.Limit, filter.Offset)
+	switch {
+	case filter.After != nil:
+		start, end = keysetPage(len(doctors), filter.Limit, false, doctorPosition(doctors, *filter.After))
+	case filter.Before != nil:
+		start, end = keysetPage(len(doctors), filter.Limit, true, doctorPosition(doctors, *filter.Before))
+	}
+	return doctors[start:end], nil
+}
+
+// doctorPosition compares doctors, ordered as matchDoctors orders them,
+// with key
+func doctorPosition(doctors []models.Doctor, key repository.DoctorKey) func(i int) int {
+	return func(i int) int {
+		if c := strings.Compare(doctors[i].User.Name, key.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(doctors[i].ID.String(), key.ID.String())
+	}
+}
+
+// Count implements repository.DoctorRepository
+func (r *DoctorRepository) Count(ctx context.Context, filter repository.DoctorFilter) (int, error) {
+	doctors, err := r.List(ctx, repository.DoctorFilter{
+		Specialization:  filter.Specialization,
+		IncludeInactive: filter.IncludeInactive,
+	})
+	return len(doctors), err
+}
+
+// Search implements repository.DoctorRepository
+func (r *DoctorRepository) Search(ctx context.Context, search repository.DoctorSearch) ([]models.DoctorMatch, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	matches := r.data.searchDoctors(search)
+	start, end := page(len(matches), search.Limit, search.Offset)
+	return matches[start:end], nil
+}
+
+// CountSearch implements repository.DoctorRepository
+func (r *DoctorRepository) CountSearch(ctx context.Context, search repository.DoctorSearch) (int, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return len(r.data.searchDoctors(search)), nil
+}
+
+// Get implements repository.DoctorRepository
+func (r *DoctorRepository) Get(ctx context.Context, id uuid.UUID) (*models.Doctor, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	doctors := r.data.matchDoctors(func(doctor *models.Doctor, user *models.User) bool {
+		return doctor.ID == id
+	})
+	if len(doctors) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &doctors[0], nil
+}
+
+// GetByUserID implements repository.DoctorRepository
+func (r *DoctorRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Doctor, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	doctors := r.data.matchDoctors(func(doctor *models.Doctor, user *models.User) bool {
+		return doctor.UserID == userID
+	})
+	if len(doctors) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &doctors[0], nil
+}
+
+// Create implements repository.DoctorRepository
+func (r *DoctorRepository) Create(ctx context.Context, doctor *models.Doctor, actorID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.users[doctor.UserID]; !ok {
+		return repository.ErrNotFound
+	}
+	for _, existing := range r.data.doctors {
+		if existing.UserID == doctor.UserID {
+			return repository.ErrConflict
+		}
+	}
+
+	if doctor.ID == uuid.Nil {
+		doctor.ID = uuid.New()
+	}
+	doctor.Active = true
+	now := time.Now()
+	doctor.CreatedAt = now
+	doctor.UpdatedAt = now
+
+	stored := *doctor
+	stored.AvailableSlots = copySlots(doctor.AvailableSlots)
+	stored.WorkingHours = copyWorkingHours(doctor.WorkingHours)
+	stored.Languages = copyLanguages(doctor.Languages)
+	stored.User = nil
+	r.data.doctors[stored.ID] = &stored
+	r.data.recordAudit(actorID, repository.ActionDoctorCreate, repository.EntityDoctor,
+		stored.ID, repository.DoctorFields(doctor))
+
+	return nil
+}
+
+// Update implements repository.DoctorRepository
+func (r *DoctorRepository) Update(ctx context.Context, id uuid.UUID, actorID uuid.UUID, mutate func(doctor *models.Doctor) error) (*models.Doctor, error) {
+	r.data.updateMu.Lock()
+	defer r.data.updateMu.Unlock()
+
+	before, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	doctor := *before
+	doctor.AvailableSlots = copySlots(before.AvailableSlots)
+	doctor.WorkingHours = copyWorkingHours(before.WorkingHours)
+	if err := mutate(&doctor); err != nil {
+		return nil, err
+	}
+
+	action, changes := repository.DoctorChanges(before, &doctor)
+	if len(changes) == 0 {
+		return &doctor, nil
+	}
+
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	stored := r.data.doctors[id]
+	stored.Specialization = doctor.Specialization
+	stored.Experience = doctor.Experience
+	stored.Phone = doctor.Phone
+	stored.Languages = copyLanguages(doctor.Languages)
+	stored.Clinic = doctor.Clinic
+	stored.AvailableSlots = copySlots(doctor.AvailableSlots)
+	stored.Active = doctor.Active
+	stored.Timezone = doctor.Timezone
+	stored.WorkingHours = copyWorkingHours(doctor.WorkingHours)
+	stored.SlotDuration = doctor.SlotDuration
+	stored.BufferMinutes = doctor.BufferMinutes
+	stored.UpdatedAt = time.Now()
+	r.data.recordAudit(actorID, action, repository.EntityDoctor, id, changes)
+
+	updated := r.data.joinDoctor(stored)
+	return &updated, nil
+}
+
+// matchDoctors returns copies of the doctors with a users row that satisfy
+// match, joined with that user and ordered by name. It must be called with
+// the lock held.
+func (d *data) matchDoctors(match func(doctor *models.Doctor, user *models.User) bool) []models.Doctor {
+	doctors := []models.Doctor{}
+	for _, doctor := range d.doctors {
+		user, ok := d.users[doctor.UserID]
+		if !ok || !match(doctor, user) {
+			continue
+		}
+		doctors = append(doctors, d.joinDoctor(doctor))
+	}
+
+	sort.Slice(doctors, func(i, j int) bool {
+		if doctors[i].User.Name != doctors[j].User.Name {
+			return doctors[i].User.Name < doctors[j].User.Name
+		}
+		return doctors[i].ID.String() < doctors[j].ID.String()
+	})
+	return doctors
+}
+
+// searchDoctors returns the active doctors matching search, best first, as
+// the PostgreSQL search orders them. It must be called with the lock held.
+func (d *data) searchDoctors(search repository.DoctorSearch) []models.DoctorMatch {
+	doctors := d.matchDoctors(func(doctor *models.Doctor, user *models.User) bool {
+		return doctor.Active && (search.Specialization == "" || doctor.Specialization == search.Specialization)
+	})
+
+	matches := []models.DoctorMatch{}
+	for _, doctor := range doctors {
+		match := models.DoctorMatch{Doctor: doctor}
+		if search.Query != "" {
+			text := strings.Join([]string{doctor.User.Name, doctor.Specialization,
+				strings.Join(doctor.Languages, " "), doctor.Clinic}, " ")
+			if !textsearch.Matches(search.Query, text) {
+				continue
+			}
+			match.Score = textsearch.WordSimilarity(search.Query, text)
+		}
+		matches = append(matches, match)
+	}
+
+	// Doctors are already ordered by name within each score
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// joinDoctor copies a doctor with its user's name and email, as the
+// PostgreSQL queries return them. It must be called with the lock held.
+func (d *data) joinDoctor(doctor *models.Doctor) models.Doctor {
+	joined := *doctor
+	joined.AvailableSlots = copySlots(doctor.AvailableSlots)
+	joined.WorkingHours = copyWorkingHours(doctor.WorkingHours)
+	joined.Languages = copyLanguages(doctor.Languages)
+	joined.User = &models.User{ID: doctor.UserID}
+	if user, ok := d.users[doctor.UserID]; ok {
+		joined.User.Name = user.Name
+		joined.User.Email = user.Email
+	}
+	return joined
+}
+
+func copySlots(slots map[string][]string) map[string][]string {
+	copied := make(map[string][]string, len(slots))
+	for day, times := range slots {
+		copied[day] = append([]string(nil), times...)
+	}
+	return copied
+}
+
+func copyLanguages(languages []string) []string {
+	if len(languages) == 0 {
+		return nil
+	}
+	return append([]string(nil), languages...)
+}
+
+func copyWorkingHours(hours map[string][]models.WorkingPeriod) map[string][]models.WorkingPeriod {
+	if len(hours) == 0 {
+		return nil
+	}
+	copied := make(map[string][]models.WorkingPeriod, len(hours))
+	for day, periods := range hours {
+		copied[day] = append([]models.WorkingPeriod(nil), periods...)
+	}
+	return copied
+}
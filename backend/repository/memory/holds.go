@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// HoldRepository implements repository.HoldRepository
+type HoldRepository struct {
+	data *data
+}
+
+// Create implements repository.HoldRepository
+func (r *HoldRepository) Create(ctx context.Context, hold *models.SlotHold) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.doctors[hold.DoctorID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	booked := &models.Appointment{
+		DoctorID:        hold.DoctorID,
+		AppointmentDate: hold.StartsAt,
+		OccupiedUntil:   hold.OccupiedUntil,
+	}
+	if r.data.slotTaken(booked) {
+		return repository.ErrSlotTaken
+	}
+	if r.data.slotHeld(hold.DoctorID, hold.PatientID, hold.StartsAt, hold.OccupiedUntil) {
+		return repository.ErrSlotHeld
+	}
+
+	if hold.Kind == "" {
+		hold.Kind = models.HoldCheckout
+	}
+
+	// A new checkout hold replaces the patient's other checkout holds with
+	// the doctor, leaving the holds of waitlist offers alone
+	if hold.Kind == models.HoldCheckout {
+		for id, other := range r.data.holds {
+			if other.DoctorID == hold.DoctorID && other.PatientID == hold.PatientID && other.Kind == models.HoldCheckout {
+				delete(r.data.holds, id)
+			}
+		}
+	}
+
+	if hold.ID == uuid.Nil {
+		hold.ID = uuid.New()
+	}
+	hold.CreatedAt = time.Now()
+
+	stored := *hold
+	r.data.holds[stored.ID] = &stored
+	return nil
+}
+
+// Get implements repository.HoldRepository
+func (r *HoldRepository) Get(ctx context.Context, id uuid.UUID) (*models.SlotHold, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	hold, ok := r.data.holds[id]
+	if !ok || !hold.ExpiresAt.After(time.Now()) {
+		return nil, repository.ErrNotFound
+	}
+	copied := *hold
+	return &copied, nil
+}
+
+// List implements repository.HoldRepository
+func (r *HoldRepository) List(ctx context.Context, doctorID uuid.UUID, from, until time.Time) ([]models.SlotHold, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	now := time.Now()
+	holds := []models.SlotHold{}
+	for _, hold := range r.data.holds {
+		if hold.DoctorID == doctorID && hold.ExpiresAt.After(now) &&
+			hold.StartsAt.Before(until) && from.Before(hold.OccupiedUntil) {
+			holds = append(holds, *hold)
+		}
+	}
+
+	sort.Slice(holds, func(i, j int) bool {
+		if !holds[i].StartsAt.Equal(holds[j].StartsAt) {
+			return holds[i].StartsAt.Before(holds[j].StartsAt)
+		}
+		return holds[i].ID.String() < holds[j].ID.String()
+	})
+	return holds, nil
+}
+
+// Release implements repository.HoldRepository
+func (r *HoldRepository) Release(ctx context.Context, id, patientID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if !r.data.takeHold(id, patientID) {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired implements repository.HoldRepository
+func (r *HoldRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	count := 0
+	for id, hold := range r.data.holds {
+		if !hold.ExpiresAt.After(now) {
+			delete(r.data.holds, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// slotHeld reports whether a patient other than patientID holds time of
+// the doctor overlapping [start, end). It must be called with the lock
+// held.
+func (d *data) slotHeld(doctorID, patientID uuid.UUID, start, end time.Time) bool {
+	now := time.Now()
+	for _, hold := range d.holds {
+		if hold.DoctorID == doctorID && hold.PatientID != patientID && hold.ExpiresAt.After(now) &&
+			hold.StartsAt.Before(end) && start.Before(hold.OccupiedUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// takeHold deletes the patient's unexpired hold id and reports whether
+// there was one. It must be called with the lock held.
+func (d *data) takeHold(id, patientID uuid.UUID) bool {
+	hold, ok := d.holds[id]
+	if !ok || hold.PatientID != patientID || !hold.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	delete(d.holds, id)
+	return true
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// idempotencyKey identifies a record like the primary key of
+// idempotency_keys
+type idempotencyKey struct {
+	userID uuid.UUID
+	key    string
+}
+
+// IdempotencyRepository implements repository.IdempotencyRepository
+type IdempotencyRepository struct {
+	data *data
+}
+
+// Start implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Start(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	now := time.Now()
+	key := idempotencyKey{record.UserID, record.Key}
+	if existing, ok := r.data.idempotency[key]; ok && existing.ExpiresAt.After(now) {
+		copied := copyRecord(existing)
+		return &copied, nil
+	}
+
+	record.CreatedAt = now
+	stored := copyRecord(record)
+	r.data.idempotency[key] = &stored
+	return nil, nil
+}
+
+// Get implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	record, ok := r.data.idempotency[idempotencyKey{userID, key}]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, repository.ErrNotFound
+	}
+	copied := copyRecord(record)
+	return &copied, nil
+}
+
+// Complete implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	record, ok := r.data.idempotency[idempotencyKey{userID, key}]
+	if !ok || record.StatusCode != 0 {
+		return repository.ErrNotFound
+	}
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = append([]byte{}, body...)
+	return nil
+}
+
+// Abandon implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Abandon(ctx context.Context, userID uuid.UUID, key string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	mapKey := idempotencyKey{userID, key}
+	record, ok := r.data.idempotency[mapKey]
+	if !ok || record.StatusCode != 0 {
+		return repository.ErrNotFound
+	}
+	delete(r.data.idempotency, mapKey)
+	return nil
+}
+
+// DeleteExpired implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	count := 0
+	for key, record := range r.data.idempotency {
+		if !record.ExpiresAt.After(now) {
+			delete(r.data.idempotency, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// copyRecord returns a copy of record that shares no slices with it
+func copyRecord(record *models.IdempotencyRecord) models.IdempotencyRecord {
+	copied := *record
+	copied.Body = append([]byte(nil), record.Body...)
+	return copied
+}
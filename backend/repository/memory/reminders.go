@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// reminderKey identifies a reminder like the primary key of
+// appointment_reminders
+type reminderKey struct {
+	appointmentID uuid.UUID
+	startsAt      int64
+	offsetMinutes int
+}
+
+// ReminderRepository implements repository.ReminderRepository
+type ReminderRepository struct {
+	data *data
+}
+
+// Claim implements repository.ReminderRepository
+func (r *ReminderRepository) Claim(ctx context.Context, appointmentID uuid.UUID, startsAt time.Time, offset time.Duration, maxAttempts int) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	now := time.Now()
+	key := reminderKey{appointmentID, startsAt.UnixNano(), int(offset / time.Minute)}
+	reminder, ok := r.data.reminders[key]
+	if !ok {
+		r.data.reminders[key] = &models.Reminder{
+			AppointmentID: appointmentID,
+			StartsAt:      startsAt,
+			OffsetMinutes: key.offsetMinutes,
+			Status:        models.ReminderSending,
+			Attempts:      1,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		return true, nil
+	}
+
+	if reminder.Status != models.ReminderFailed || reminder.Attempts >= maxAttempts {
+		return false, nil
+	}
+	reminder.Status = models.ReminderSending
+	reminder.Attempts++
+	reminder.UpdatedAt = now
+	return true, nil
+}
+
+// Finish implements repository.ReminderRepository
+func (r *ReminderRepository) Finish(ctx context.Context, appointmentID uuid.UUID, startsAt time.Time, offset time.Duration, status, errMessage string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	reminder, ok := r.data.reminders[reminderKey{appointmentID, startsAt.UnixNano(), int(offset / time.Minute)}]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	reminder.Status = status
+	reminder.LastError = errMessage
+	if status == models.ReminderSent {
+		reminder.SentAt = &now
+	}
+	reminder.UpdatedAt = now
+	return nil
+}
+
+// List implements repository.ReminderRepository
+func (r *ReminderRepository) List(ctx context.Context, filter repository.ReminderFilter) ([]models.Reminder, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	reminders := []models.Reminder{}
+	for _, reminder := range r.data.reminders {
+		if filter.AppointmentID != uuid.Nil && reminder.AppointmentID != filter.AppointmentID {
+			continue
+		}
+		if filter.Status != "" && reminder.Status != filter.Status {
+			continue
+		}
+		reminders = append(reminders, *reminder)
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		a, b := reminders[i], reminders[j]
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		if a.AppointmentID != b.AppointmentID {
+			return a.AppointmentID.String() < b.AppointmentID.String()
+		}
+		return a.OffsetMinutes < b.OffsetMinutes
+	})
+	start, end := page(len(reminders), filter.Limit, filter.Offset)
+	return reminders[start:end], nil
+}
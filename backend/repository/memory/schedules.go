@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// ScheduleRepository implements repository.ScheduleRepository
+type ScheduleRepository struct {
+	data *data
+}
+
+// ListExceptions implements repository.ScheduleRepository
+func (r *ScheduleRepository) ListExceptions(ctx context.Context, doctorID uuid.UUID, from, to string) ([]models.ScheduleException, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	exceptions := []models.ScheduleException{}
+	for _, exception := range r.data.exceptions {
+		if exception.DoctorID == doctorID && exception.Date >= from && exception.Date <= to {
+			copied := *exception
+			copied.Slots = append([]string(nil), exception.Slots...)
+			exceptions = append(exceptions, copied)
+		}
+	}
+
+	sort.Slice(exceptions, func(i, j int) bool {
+		a, b := exceptions[i], exceptions[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return exceptions, nil
+}
+
+// CreateException implements repository.ScheduleRepository
+func (r *ScheduleRepository) CreateException(ctx context.Context, exception *models.ScheduleException) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.doctors[exception.DoctorID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	if exception.ID == uuid.Nil {
+		exception.ID = uuid.New()
+	}
+	exception.CreatedAt = time.Now()
+
+	stored := *exception
+	stored.Slots = append([]string(nil), exception.Slots...)
+	r.data.exceptions[stored.ID] = &stored
+	return nil
+}
+
+// DeleteException implements repository.ScheduleRepository
+func (r *ScheduleRepository) DeleteException(ctx context.Context, doctorID, id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	exception, ok := r.data.exceptions[id]
+	if !ok || exception.DoctorID != doctorID {
+		return repository.ErrNotFound
+	}
+	delete(r.data.exceptions, id)
+	return nil
+}
+
+// ListHolidays implements repository.ScheduleRepository
+func (r *ScheduleRepository) ListHolidays(ctx context.Context, from, to string) ([]models.Holiday, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	holidays := []models.Holiday{}
+	for _, holiday := range r.data.holidays {
+		if holiday.Date >= from && holiday.Date <= to {
+			holidays = append(holidays, *holiday)
+		}
+	}
+
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date < holidays[j].Date
+	})
+	return holidays, nil
+}
+
+// CreateHoliday implements repository.ScheduleRepository
+func (r *ScheduleRepository) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, existing := range r.data.holidays {
+		if existing.Date == holiday.Date {
+			return repository.ErrConflict
+		}
+	}
+
+	if holiday.ID == uuid.Nil {
+		holiday.ID = uuid.New()
+	}
+	holiday.CreatedAt = time.Now()
+
+	stored := *holiday
+	r.data.holidays[stored.ID] = &stored
+	return nil
+}
+
+// DeleteHoliday implements repository.ScheduleRepository
+func (r *ScheduleRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.holidays[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.data.holidays, id)
+	return nil
+}
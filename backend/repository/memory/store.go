@@ -0,0 +1,165 @@
+// Package memory implements the repository interfaces in process memory.
+// It is meant for development and tests; nothing is persisted.
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// data holds every table of the store behind one lock, so operations that
+// span tables stay consistent
+type data struct {
+	mu sync.RWMutex
+	// updateMu serializes read-modify-write updates, whose callbacks run
+	// without mu held so they can use the other repositories
+	updateMu     sync.Mutex
+	users        map[uuid.UUID]*models.User
+	doctors      map[uuid.UUID]*models.Doctor
+	appointments map[uuid.UUID]*models.Appointment
+	history      []statusChange
+	audit        []models.AuditEntry
+	exceptions   map[uuid.UUID]*models.ScheduleException
+	holidays     map[uuid.UUID]*models.Holiday
+	holds        map[uuid.UUID]*models.SlotHold
+	waitlist     map[uuid.UUID]*models.WaitlistEntry
+	offers       map[uuid.UUID]*models.WaitlistOffer
+	reminders    map[reminderKey]*models.Reminder
+	devices      map[string]*models.DeviceToken
+	idempotency  map[idempotencyKey]*models.IdempotencyRecord
+	// calendarTokens maps calendar feed token hashes to user IDs
+	calendarTokens map[string]uuid.UUID
+}
+
+// statusChange is an entry of the appointment status history
+type statusChange struct {
+	AppointmentID uuid.UUID
+	FromStatus    string
+	ToStatus      string
+	ChangedBy     uuid.UUID
+	ChangedAt     time.Time
+}
+
+// NewStore returns empty in-memory repositories
+func NewStore() repository.Store {
+	d := &data{
+		users:          make(map[uuid.UUID]*models.User),
+		doctors:        make(map[uuid.UUID]*models.Doctor),
+		appointments:   make(map[uuid.UUID]*models.Appointment),
+		exceptions:     make(map[uuid.UUID]*models.ScheduleException),
+		holidays:       make(map[uuid.UUID]*models.Holiday),
+		holds:          make(map[uuid.UUID]*models.SlotHold),
+		waitlist:       make(map[uuid.UUID]*models.WaitlistEntry),
+		offers:         make(map[uuid.UUID]*models.WaitlistOffer),
+		reminders:      make(map[reminderKey]*models.Reminder),
+		devices:        make(map[string]*models.DeviceToken),
+		idempotency:    make(map[idempotencyKey]*models.IdempotencyRecord),
+		calendarTokens: make(map[string]uuid.UUID),
+	}
+	return repository.Store{
+		Doctors:      &DoctorRepository{data: d},
+		Appointments: &AppointmentRepository{data: d},
+		Users:        &UserRepository{data: d},
+		Audit:        &AuditRepository{data: d},
+		Schedules:    &ScheduleRepository{data: d},
+		Holds:        &HoldRepository{data: d},
+		Waitlist:     &WaitlistRepository{data: d},
+		Reminders:    &ReminderRepository{data: d},
+		Devices:      &DeviceRepository{data: d},
+		Idempotency:  &IdempotencyRepository{data: d},
+	}
+}
+
+// NewSampleStore returns in-memory repositories holding the same sample
+// users and doctors as the sample data migration
+func NewSampleStore() repository.Store {
+	store := NewStore()
+	d := store.Users.(*UserRepository).data
+
+	samples := []struct {
+		firebaseUID, name, email, role string
+		specialization                 string
+		experience                     int
+		phone                          string
+		slots                          map[string][]string
+	}{
+		{"sample_firebase_uid_1", "Dr. John Smith", "john.smith@hospital.com", models.RoleDoctor,
+			"Cardiologist", 10, "+1234567890",
+			map[string][]string{"Monday": {"09:00", "10:00", "11:00"}, "Tuesday": {"09:00", "10:00"}, "Wednesday": {"14:00", "15:00"}}},
+		{"sample_firebase_uid_2", "Dr. Sarah Johnson", "sarah.johnson@hospital.com", models.RoleDoctor,
+			"Dermatologist", 8, "+1234567891",
+			map[string][]string{"Monday": {"10:00", "11:00"}, "Thursday": {"09:00", "10:00"}, "Friday": {"14:00", "15:00"}}},
+		{"sample_firebase_uid_3", "Patient User", "patient@example.com", models.RolePatient,
+			"", 0, "", nil},
+	}
+
+	now := time.Now()
+	for _, sample := range samples {
+		user := &models.User{
+			ID:          uuid.New(),
+			FirebaseUID: sample.firebaseUID,
+			Name:        sample.name,
+			Email:       sample.email,
+			Role:        sample.role,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		d.users[user.ID] = user
+
+		if sample.specialization == "" {
+			continue
+		}
+		doctor := &models.Doctor{
+			ID:             uuid.New(),
+			UserID:         user.ID,
+			Specialization: sample.specialization,
+			Experience:     sample.experience,
+			Phone:          sample.phone,
+			AvailableSlots: sample.slots,
+			SlotDuration:   models.DefaultSlotDuration,
+			BufferMinutes:  models.DefaultBufferMinutes,
+			Active:         true,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		d.doctors[doctor.ID] = doctor
+	}
+
+	return store
+}
+
+// page applies limit and offset to n items and returns the bounds to slice
+func page(n, limit, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// keysetPage returns the bounds of the page of a sorted listing of n items
+// that keyset paging selects: the first limit items after a key, or with
+// backward the last limit items before it. position compares item i with
+// the key, negative when the item comes first.
+func keysetPage(n, limit int, backward bool, position func(i int) int) (int, int) {
+	if !backward {
+		start := sort.Search(n, func(i int) bool { return position(i) > 0 })
+		return page(n, limit, start)
+	}
+
+	end := sort.Search(n, func(i int) bool { return position(i) >= 0 })
+	start := 0
+	if limit > 0 && end > limit {
+		start = end - limit
+	}
+	return start, end
+}
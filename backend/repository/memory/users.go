@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// UserRepository implements repository.UserRepository
+type UserRepository struct {
+	data *data
+}
+
+// Get implements repository.UserRepository
+func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// GetByFirebaseUID implements repository.UserRepository
+func (r *UserRepository) GetByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	if user := r.data.userByFirebaseUID(firebaseUID); user != nil {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Upsert implements repository.UserRepository
+func (r *UserRepository) Upsert(ctx context.Context, user *models.User) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	now := time.Now()
+	existing := r.data.userByFirebaseUID(user.FirebaseUID)
+	if existing == nil {
+		existing = &models.User{
+			ID:          uuid.New(),
+			FirebaseUID: user.FirebaseUID,
+			Role:        user.Role,
+			CreatedAt:   now,
+		}
+		r.data.users[existing.ID] = existing
+	}
+
+	existing.Name = user.Name
+	existing.Email = user.Email
+	existing.UpdatedAt = now
+
+	*user = *existing
+	return nil
+}
+
+// List implements repository.UserRepository
+func (r *UserRepository) List(ctx context.Context, role string, limit, offset int) ([]models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	users := []models.User{}
+	for _, user := range r.data.users {
+		if role == "" || user.Role == role {
+			users = append(users, *user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Name != users[j].Name {
+			return users[i].Name < users[j].Name
+		}
+		return users[i].ID.String() < users[j].ID.String()
+	})
+
+	start, end := page(len(users), limit, offset)
+	return users[start:end], nil
+}
+
+// Create implements repository.UserRepository
+func (r *UserRepository) Create(ctx context.Context, user *models.User, actorID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if r.data.userByFirebaseUID(user.FirebaseUID) != nil {
+		return repository.ErrConflict
+	}
+
+	now := time.Now()
+	user.ID = uuid.New()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	stored := *user
+	r.data.users[stored.ID] = &stored
+	r.data.recordAudit(actorID, repository.ActionUserCreate, repository.EntityUser, stored.ID,
+		map[string]models.FieldChange{
+			"firebase_uid": {To: user.FirebaseUID},
+			"name":         {To: user.Name},
+			"email":        {To: user.Email},
+			"role":         {To: user.Role},
+		})
+
+	return nil
+}
+
+// UpdateRole implements repository.UserRepository
+func (r *UserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role string, actorID uuid.UUID) (*models.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	if user.Role != role {
+		r.data.recordAudit(actorID, repository.ActionUserRoleChange, repository.EntityUser, id,
+			map[string]models.FieldChange{"role": {From: user.Role, To: role}})
+		user.Role = role
+		user.UpdatedAt = time.Now()
+	}
+
+	copied := *user
+	return &copied, nil
+}
+
+// UpdatePhone implements repository.UserRepository
+func (r *UserRepository) UpdatePhone(ctx context.Context, id uuid.UUID, phone string) (*models.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	user.Phone = phone
+	user.UpdatedAt = time.Now()
+
+	copied := *user
+	return &copied, nil
+}
+
+// SetCalendarToken implements repository.UserRepository
+func (r *UserRepository) SetCalendarToken(ctx context.Context, id uuid.UUID, tokenHash string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	for hash, userID := range r.data.calendarTokens {
+		if userID == id {
+			delete(r.data.calendarTokens, hash)
+		}
+	}
+	if tokenHash != "" {
+		r.data.calendarTokens[tokenHash] = id
+	}
+	return nil
+}
+
+// GetByCalendarToken implements repository.UserRepository
+func (r *UserRepository) GetByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	user, ok := r.data.users[r.data.calendarTokens[tokenHash]]
+	if !ok || tokenHash == "" {
+		return nil, repository.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// userByFirebaseUID must be called with the lock held
+func (d *data) userByFirebaseUID(firebaseUID string) *models.User {
+	for _, user := range d.users {
+		if user.FirebaseUID == firebaseUID {
+			return user
+		}
+	}
+	return nil
+}
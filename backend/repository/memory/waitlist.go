@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// WaitlistRepository implements repository.WaitlistRepository
+type WaitlistRepository struct {
+	data *data
+}
+
+// CreateEntry implements repository.WaitlistRepository
+func (r *WaitlistRepository) CreateEntry(ctx context.Context, entry *models.WaitlistEntry) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.doctors[entry.DoctorID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.PreferredSlots == nil {
+		entry.PreferredSlots = []string{}
+	}
+	entry.Status = models.WaitlistWaiting
+	entry.CreatedAt = time.Now()
+	entry.UpdatedAt = entry.CreatedAt
+
+	stored := copyEntry(entry)
+	r.data.waitlist[stored.ID] = &stored
+	return nil
+}
+
+// GetEntry implements repository.WaitlistRepository
+func (r *WaitlistRepository) GetEntry(ctx context.Context, id uuid.UUID) (*models.WaitlistEntry, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	entry, ok := r.data.waitlist[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := copyEntry(entry)
+	return &copied, nil
+}
+
+// ListEntries implements repository.WaitlistRepository
+func (r *WaitlistRepository) ListEntries(ctx context.Context, filter repository.WaitlistFilter) ([]models.WaitlistEntry, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	entries := []models.WaitlistEntry{}
+	for _, entry := range r.data.waitlist {
+		if filter.PatientID != uuid.Nil && entry.PatientID != filter.PatientID {
+			continue
+		}
+		if filter.DoctorID != uuid.Nil && entry.DoctorID != filter.DoctorID {
+			continue
+		}
+		if filter.Date != "" && (entry.FromDate > filter.Date || entry.ToDate < filter.Date) {
+			continue
+		}
+		if filter.Status != "" && entry.Status != filter.Status {
+			continue
+		}
+		entries = append(entries, copyEntry(entry))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID.String() < entries[j].ID.String()
+	})
+	return entries, nil
+}
+
+// CancelEntry implements repository.WaitlistRepository
+func (r *WaitlistRepository) CancelEntry(ctx context.Context, id, patientID uuid.UUID) (*models.WaitlistOffer, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	entry, ok := r.data.waitlist[id]
+	if !ok || entry.PatientID != patientID {
+		return nil, repository.ErrNotFound
+	}
+	if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistOffered {
+		return nil, repository.ErrConflict
+	}
+
+	now := time.Now()
+	entry.Status = models.WaitlistCancelled
+	entry.UpdatedAt = now
+
+	for _, offer := range r.data.offers {
+		if offer.EntryID == id && offer.Status == models.OfferPending {
+			offer.Status = models.OfferDeclined
+			offer.RespondedAt = &now
+			copied := r.data.offerView(offer)
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateOffer implements repository.WaitlistRepository
+func (r *WaitlistRepository) CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	entry, ok := r.data.waitlist[offer.EntryID]
+	if !ok || entry.Status != models.WaitlistWaiting {
+		return repository.ErrConflict
+	}
+	entry.Status = models.WaitlistOffered
+	entry.UpdatedAt = time.Now()
+
+	if offer.ID == uuid.Nil {
+		offer.ID = uuid.New()
+	}
+	offer.Status = models.OfferPending
+	offer.CreatedAt = entry.UpdatedAt
+
+	stored := *offer
+	r.data.offers[stored.ID] = &stored
+	return nil
+}
+
+// GetOffer implements repository.WaitlistRepository
+func (r *WaitlistRepository) GetOffer(ctx context.Context, id uuid.UUID) (*models.WaitlistOffer, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	offer, ok := r.data.offers[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := r.data.offerView(offer)
+	return &copied, nil
+}
+
+// ListOffers implements repository.WaitlistRepository
+func (r *WaitlistRepository) ListOffers(ctx context.Context, filter repository.OfferFilter) ([]models.WaitlistOffer, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	offers := []models.WaitlistOffer{}
+	for _, stored := range r.data.offers {
+		offer := r.data.offerView(stored)
+		if filter.PatientID != uuid.Nil && offer.PatientID != filter.PatientID {
+			continue
+		}
+		if filter.DoctorID != uuid.Nil && offer.DoctorID != filter.DoctorID {
+			continue
+		}
+		if !filter.StartsAt.IsZero() && !offer.StartsAt.Equal(filter.StartsAt) {
+			continue
+		}
+		if filter.Status != "" && offer.Status != filter.Status {
+			continue
+		}
+		offers = append(offers, offer)
+	}
+
+	sortOffers(offers)
+	return offers, nil
+}
+
+// ResolveOffer implements repository.WaitlistRepository
+func (r *WaitlistRepository) ResolveOffer(ctx context.Context, id uuid.UUID, status string) (*models.WaitlistOffer, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	offer, ok := r.data.offers[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if offer.Status != models.OfferPending {
+		return nil, repository.ErrConflict
+	}
+
+	now := time.Now()
+	offer.Status = status
+	offer.RespondedAt = &now
+
+	entryStatus := models.WaitlistWaiting
+	if status == models.OfferAccepted {
+		entryStatus = models.WaitlistBooked
+	}
+	if entry, ok := r.data.waitlist[offer.EntryID]; ok && entry.Status == models.WaitlistOffered {
+		entry.Status = entryStatus
+		entry.UpdatedAt = now
+	}
+
+	copied := r.data.offerView(offer)
+	return &copied, nil
+}
+
+// ExpireOffers implements repository.WaitlistRepository
+func (r *WaitlistRepository) ExpireOffers(ctx context.Context, now time.Time) ([]models.WaitlistOffer, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	expired := []models.WaitlistOffer{}
+	for _, offer := range r.data.offers {
+		if offer.Status != models.OfferPending || offer.ExpiresAt.After(now) {
+			continue
+		}
+		offer.Status = models.OfferExpired
+		if entry, ok := r.data.waitlist[offer.EntryID]; ok && entry.Status == models.WaitlistOffered {
+			entry.Status = models.WaitlistWaiting
+			entry.UpdatedAt = now
+		}
+		expired = append(expired, r.data.offerView(offer))
+	}
+
+	sortOffers(expired)
+	return expired, nil
+}
+
+// offerView returns a copy of offer with the fields it shares with its
+// entry filled in from the entry, as the postgres join does. It must be
+// called with the lock held.
+func (d *data) offerView(offer *models.WaitlistOffer) models.WaitlistOffer {
+	copied := *offer
+	if entry, ok := d.waitlist[offer.EntryID]; ok {
+		copied.DoctorID = entry.DoctorID
+		copied.PatientID = entry.PatientID
+		copied.Type = entry.Type
+	}
+	return copied
+}
+
+// copyEntry returns a copy of entry that shares no slices with it
+func copyEntry(entry *models.WaitlistEntry) models.WaitlistEntry {
+	copied := *entry
+	copied.PreferredSlots = append([]string{}, entry.PreferredSlots...)
+	return copied
+}
+
+// sortOffers orders offers oldest first
+func sortOffers(offers []models.WaitlistOffer) {
+	sort.Slice(offers, func(i, j int) bool {
+		if !offers[i].CreatedAt.Equal(offers[j].CreatedAt) {
+			return offers[i].CreatedAt.Before(offers[j].CreatedAt)
+		}
+		return offers[i].ID.String() < offers[j].ID.String()
+	})
+}
@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+
+	"hospital-backend/database"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// noOverlapConstraint is the exclusion constraint that keeps the
+// non-cancelled appointments of a doctor from overlapping
+const noOverlapConstraint = "appointments_no_overlap"
+
+// appointmentColumns are read by scanAppointment, in order
+const appointmentColumns = `
+	a.id, a.doctor_id, a.patient_id, a.appointment_date, a.slot,
+	a.appointment_type, a.duration_minutes, a.occupied_until, a.status, COALESCE(a.notes, ''), COALESCE(a.clinical_notes, ''), a.reschedule_required,
+	a.created_at, a.updated_at,
+	d.user_id, d.specialization, COALESCE(d.timezone, ''), COALESCE(u.name, ''), COALESCE(u.email, ''),
+	COALESCE(p.name, ''), COALESCE(p.email, ''), COALESCE(p.phone, '')
+`
+
+const appointmentJoins = `
+	FROM appointments a
+	JOIN doctors d ON a.doctor_id = d.id
+	LEFT JOIN users u ON d.user_id = u.id
+	LEFT JOIN users p ON a.patient_id = p.id
+`
+
+// AppointmentRepository implements repository.AppointmentRepository
+type AppointmentRepository struct {
+	db *sql.DB
+}
+
+// Book implements repository.AppointmentRepository. The insert relies on
+// noOverlapConstraint, so two concurrent bookings of overlapping times
+// cannot both commit.
+func (r *AppointmentRepository) Book(ctx context.Context, appointment *models.Appointment, bookedBy uuid.UUID, holdID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDoctorSchedule(ctx, tx, appointment.DoctorID); err != nil {
+		return err
+	}
+
+	if holdID != uuid.Nil {
+		err := deleted(tx.ExecContext(ctx, `
+			DELETE FROM slot_holds WHERE id = $1 AND patient_id = $2 AND expires_at > NOW()
+		`, holdID, appointment.PatientID))
+		if err == repository.ErrNotFound {
+			return repository.ErrHoldExpired
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = checkHeld(ctx, tx, appointment.DoctorID, appointment.PatientID,
+		appointment.AppointmentDate, appointment.OccupiedUntil)
+	if err != nil {
+		return err
+	}
+
+	if appointment.ID == uuid.Nil {
+		appointment.ID = uuid.New()
+	}
+	appointment.Status = models.StatusScheduled
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO appointments (id, doctor_id, patient_id, appointment_date, slot,
+		                          appointment_type, duration_minutes, occupied_until, status, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at
+	`, appointment.ID, appointment.DoctorID, appointment.PatientID, appointment.AppointmentDate,
+		appointment.Slot, appointment.Type, appointment.DurationMinutes, appointment.OccupiedUntil,
+		appointment.Status, appointment.Notes,
+	).Scan(&appointment.CreatedAt, &appointment.UpdatedAt)
+	if database.IsExclusionViolation(err, noOverlapConstraint) {
+		return repository.ErrSlotTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recordStatusChange(ctx, tx, appointment.ID, "", appointment.Status, bookedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get implements repository.AppointmentRepository
+func (r *AppointmentRepository) Get(ctx context.Context, id uuid.UUID) (*models.Appointment, error) {
+	return getAppointment(ctx, r.db, id, false)
+}
+
+// List implements repository.AppointmentRepository
+func (r *AppointmentRepository) List(ctx context.Context, filter repository.AppointmentFilter) ([]models.Appointment, error) {
+	where, args := appointmentConditions(filter)
+
+	// Keyset pages read away from the key; pages before it are read
+	// against the listing order and reversed
+	key, backward := filter.After, false
+	if filter.Before != nil {
+		key, backward = filter.Before, true
+	}
+	descending := !filter.Ascending != backward
+	if key != nil {
+		args = append(args, key.Date, key.ID)
+		comparison := " > "
+		if descending {
+			comparison = " < "
+		}
+		where += " AND (a.appointment_date, a.id)" + comparison +
+			"($" + strconv.Itoa(len(args)-1) + ", $" + strconv.Itoa(len(args)) + ")"
+	}
+	order := " ORDER BY a.appointment_date, a.id"
+	if descending {
+		order = " ORDER BY a.appointment_date DESC, a.id DESC"
+	}
+
+	query, args := appendLimitOffset(`SELECT `+appointmentColumns+appointmentJoins+where+order, args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		appointment, err := scanAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, *appointment)
+	}
+	if backward {
+		slices.Reverse(appointments)
+	}
+	return appointments, rows.Err()
+}
+
+// Count implements repository.AppointmentRepository
+func (r *AppointmentRepository) Count(ctx context.Context, filter repository.AppointmentFilter) (int, error) {
+	where, args := appointmentConditions(filter)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM appointments a`+where, args...).Scan(&total)
+	return total, err
+}
+
+// Update implements repository.AppointmentRepository
+func (r *AppointmentRepository) Update(ctx context.Context, id uuid.UUID, changedBy uuid.UUID, mutate func(appointment *models.Appointment) error) (*models.Appointment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	appointment, err := getAppointment(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *appointment
+	if err := mutate(appointment); err != nil {
+		return nil, err
+	}
+
+	// A move must not land on time another patient holds
+	moved := !appointment.AppointmentDate.Equal(before.AppointmentDate) ||
+		!appointment.OccupiedUntil.Equal(before.OccupiedUntil)
+	if moved && appointment.Status != models.StatusCancelled {
+		if err := lockDoctorSchedule(ctx, tx, appointment.DoctorID); err != nil {
+			return nil, err
+		}
+		err := checkHeld(ctx, tx, appointment.DoctorID, appointment.PatientID,
+			appointment.AppointmentDate, appointment.OccupiedUntil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE appointments
+		SET appointment_date = $1, slot = $2, occupied_until = $3, status = $4, notes = $5,
+		    clinical_notes = $6, reschedule_required = $7, updated_at = NOW()
+		WHERE id = $8
+		RETURNING updated_at
+	`, appointment.AppointmentDate, appointment.Slot, appointment.OccupiedUntil, appointment.Status,
+		appointment.Notes, sql.NullString{String: appointment.ClinicalNotes, Valid: appointment.ClinicalNotes != ""},
+		appointment.RescheduleRequired, id,
+	).Scan(&appointment.UpdatedAt)
+	if database.IsExclusionViolation(err, noOverlapConstraint) {
+		return nil, repository.ErrSlotTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if repository.IsTransition(&before, appointment) {
+		if err := recordStatusChange(ctx, tx, id, before.Status, appointment.Status, changedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// appointmentConditions builds the WHERE clause for filter
+func appointmentConditions(filter repository.AppointmentFilter) (string, []interface{}) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where += " AND " + condition + " $" + strconv.Itoa(len(args))
+	}
+
+	if filter.PatientID != uuid.Nil {
+		add("a.patient_id =", filter.PatientID)
+	}
+	if filter.DoctorID != uuid.Nil {
+		add("a.doctor_id =", filter.DoctorID)
+	}
+	if !filter.From.IsZero() {
+		add("a.appointment_date >=", filter.From)
+	}
+	if !filter.Until.IsZero() {
+		add("a.appointment_date <", filter.Until)
+	}
+	if filter.ActiveOnly {
+		where += " AND a.status != 'cancelled'"
+	}
+
+	return where, args
+}
+
+func getAppointment(ctx context.Context, q queryer, id uuid.UUID, forUpdate bool) (*models.Appointment, error) {
+	query := `SELECT ` + appointmentColumns + appointmentJoins + ` WHERE a.id = $1`
+	if forUpdate {
+		query += " FOR UPDATE OF a"
+	}
+
+	appointment, err := scanAppointment(q.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return appointment, err
+}
+
+// scanAppointment reads a row selected with appointmentColumns
+func scanAppointment(row rowScanner) (*models.Appointment, error) {
+	var appointment models.Appointment
+	var doctor models.Doctor
+	var user models.User
+	var patient models.User
+	var doctorUserID uuid.NullUUID
+
+	err := row.Scan(
+		&appointment.ID, &appointment.DoctorID, &appointment.PatientID,
+		&appointment.AppointmentDate, &appointment.Slot,
+		&appointment.Type, &appointment.DurationMinutes, &appointment.OccupiedUntil, &appointment.Status,
+		&appointment.Notes, &appointment.ClinicalNotes, &appointment.RescheduleRequired,
+		&appointment.CreatedAt, &appointment.UpdatedAt,
+		&doctorUserID, &doctor.Specialization, &doctor.Timezone, &user.Name, &user.Email,
+		&patient.Name, &patient.Email, &patient.Phone,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	patient.ID = appointment.PatientID
+	appointment.Patient = &patient
+
+	doctor.ID = appointment.DoctorID
+	doctor.UserID = doctorUserID.UUID
+	user.ID = doctorUserID.UUID
+	doctor.User = &user
+	appointment.Doctor = &doctor
+	return &appointment, nil
+}
+
+// recordStatusChange appends a row to appointment_status_history. An empty
+// from status records the creation of the appointment.
+func recordStatusChange(ctx context.Context, tx *sql.Tx, appointmentID uuid.UUID, from, to string, changedBy uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO appointment_status_history (appointment_id, from_status, to_status, changed_by)
+		VALUES ($1, $2, $3, $4)
+	`, appointmentID, sql.NullString{String: from, Valid: from != ""}, to, changedBy)
+	return err
+}
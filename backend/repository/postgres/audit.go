@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// AuditRepository implements repository.AuditRepository
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// List implements repository.AuditRepository
+func (r *AuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, error) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where += " AND " + condition + " $" + strconv.Itoa(len(args))
+	}
+
+	if filter.EntityType != "" {
+		add("entity_type =", filter.EntityType)
+	}
+	if filter.EntityID != uuid.Nil {
+		add("entity_id =", filter.EntityID)
+	}
+	if filter.ActorID != uuid.Nil {
+		add("actor_id =", filter.ActorID)
+	}
+
+	query, args := appendLimitOffset(`
+		SELECT id, actor_id, action, entity_type, entity_id, changes, created_at
+		FROM audit_log`+where+`
+		ORDER BY created_at DESC, id DESC
+	`, args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var actorID uuid.NullUUID
+		var changesJSON []byte
+
+		err := rows.Scan(&entry.ID, &actorID, &entry.Action, &entry.EntityType,
+			&entry.EntityID, &changesJSON, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changesJSON, &entry.Changes); err != nil {
+			return nil, err
+		}
+		entry.ActorID = actorID.UUID
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// recordAudit appends a row to audit_log
+func recordAudit(ctx context.Context, tx *sql.Tx, actorID uuid.UUID, action, entityType string, entityID uuid.UUID, changes map[string]models.FieldChange) error {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, changes)
+		VALUES ($1, $2, $3, $4, $5)
+	`, uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil}, action, entityType, entityID, string(changesJSON))
+	return err
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"hospital-backend/database"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DeviceRepository implements repository.DeviceRepository
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+// Register implements repository.DeviceRepository
+func (r *DeviceRepository) Register(ctx context.Context, device *models.DeviceToken) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO device_tokens (token, user_id, platform)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (token) DO UPDATE
+		SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, device.Token, device.UserID, device.Platform).Scan(&device.CreatedAt, &device.UpdatedAt)
+	if database.IsForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// Unregister implements repository.DeviceRepository
+func (r *DeviceRepository) Unregister(ctx context.Context, userID uuid.UUID, token string) error {
+	return deleted(r.db.ExecContext(ctx, `
+		DELETE FROM device_tokens WHERE token = $1 AND user_id = $2
+	`, token, userID))
+}
+
+// Tokens implements repository.DeviceRepository
+func (r *DeviceRepository) Tokens(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT token FROM device_tokens WHERE user_id = $1 ORDER BY created_at, token
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []string{}
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Forget implements repository.DeviceRepository
+func (r *DeviceRepository) Forget(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM device_tokens WHERE token = ANY($1)`, pq.Array(tokens))
+	return err
+}
@@ -0,0 +1,356 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hospital-backend/database"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+	"hospital-backend/textsearch"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// doctorColumns are read by scanDoctor, in order
+const doctorColumns = `
+	d.id, d.user_id, d.specialization, d.experience, COALESCE(d.phone, ''),
+	COALESCE(d.available_slots, '{}'), d.active, COALESCE(d.timezone, ''),
+	COALESCE(d.working_hours, '{}'), d.slot_duration, d.buffer_minutes, d.languages, d.clinic,
+	d.created_at, d.updated_at, u.name, u.email
+`
+
+// doctorUserIndex allows a single doctor profile per user
+const doctorUserIndex = "idx_doctors_user_id_unique"
+
+// DoctorRepository implements repository.DoctorRepository
+type DoctorRepository struct {
+	db *sql.DB
+}
+
+// List implements repository.DoctorRepository
+func (r *DoctorRepository) List(ctx context.Context, filter repository.DoctorFilter) ([]models.Doctor, error) {
+	query := `SELECT ` + doctorColumns + `
+		FROM doctors d
+		JOIN users u ON d.user_id = u.id
+		WHERE ($1 = '' OR d.specialization = $1) AND ($2 OR d.active)`
+	args := []interface{}{filter.Specialization, filter.IncludeInactive}
+
+	// Keyset pages read away from the key; pages before it are read
+	// backward and reversed
+	order := " ORDER BY u.name, d.id"
+	switch {
+	case filter.After != nil:
+		args = append(args, filter.After.Name, filter.After.ID)
+		query += " AND (u.name, d.id) > ($3, $4)"
+	case filter.Before != nil:
+		args = append(args, filter.Before.Name, filter.Before.ID)
+		query += " AND (u.name, d.id) < ($3, $4)"
+		order = " ORDER BY u.name DESC, d.id DESC"
+	}
+	query, args = appendLimitOffset(query+order, args, filter.Limit, filter.Offset)
+
+	doctors, err := r.queryDoctors(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Before != nil {
+		slices.Reverse(doctors)
+	}
+	return doctors, nil
+}
+
+// Count implements repository.DoctorRepository
+func (r *DoctorRepository) Count(ctx context.Context, filter repository.DoctorFilter) (int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM doctors d
+		JOIN users u ON d.user_id = u.id
+		WHERE ($1 = '' OR d.specialization = $1) AND ($2 OR d.active)
+	`, filter.Specialization, filter.IncludeInactive).Scan(&total)
+	return total, err
+}
+
+// searchConditions select the doctors matching a search: $1 is the query,
+// $2 its prefix tsquery and $3 the specialization. search_vector and
+// search_text are kept up to date by triggers.
+const searchConditions = `
+	d.active AND ($3 = '' OR d.specialization = $3)
+	AND ($1 = '' OR d.search_vector @@ to_tsquery('simple', $2) OR $1 <% d.search_text)
+`
+
+// Search implements repository.DoctorRepository
+func (r *DoctorRepository) Search(ctx context.Context, search repository.DoctorSearch) ([]models.DoctorMatch, error) {
+	query := `SELECT ` + doctorColumns + `,
+		       CASE WHEN $1 = '' THEN 0 ELSE ROUND(word_similarity($1, d.search_text)::numeric, 3) END AS score
+		FROM doctors d
+		JOIN users u ON d.user_id = u.id
+		WHERE ` + searchConditions + `
+		ORDER BY score DESC, ts_rank(d.search_vector, to_tsquery('simple', $2)) DESC, u.name, d.id
+	`
+	args := []interface{}{search.Query, prefixQuery(search.Query), search.Specialization}
+	query, args = appendLimitOffset(query, args, search.Limit, search.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []models.DoctorMatch{}
+	for rows.Next() {
+		var score float64
+		doctor, err := scanDoctor(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, models.DoctorMatch{Doctor: *doctor, Score: score})
+	}
+	return matches, rows.Err()
+}
+
+// CountSearch implements repository.DoctorRepository
+func (r *DoctorRepository) CountSearch(ctx context.Context, search repository.DoctorSearch) (int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM doctors d WHERE `+searchConditions,
+		search.Query, prefixQuery(search.Query), search.Specialization,
+	).Scan(&total)
+	return total, err
+}
+
+// prefixQuery returns the tsquery matching text with a word starting with
+// each word of query. Words hold only letters and digits, so they need no
+// quoting.
+func prefixQuery(query string) string {
+	words := textsearch.Words(query)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Get implements repository.DoctorRepository
+func (r *DoctorRepository) Get(ctx context.Context, id uuid.UUID) (*models.Doctor, error) {
+	return r.getDoctor(ctx, "d.id = $1", id)
+}
+
+// GetByUserID implements repository.DoctorRepository
+func (r *DoctorRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Doctor, error) {
+	return r.getDoctor(ctx, "d.user_id = $1", userID)
+}
+
+// Create implements repository.DoctorRepository
+func (r *DoctorRepository) Create(ctx context.Context, doctor *models.Doctor, actorID uuid.UUID) error {
+	slotsJSON, err := json.Marshal(doctor.AvailableSlots)
+	if err != nil {
+		return err
+	}
+	hoursJSON, err := workingHoursJSON(doctor.WorkingHours)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if doctor.ID == uuid.Nil {
+		doctor.ID = uuid.New()
+	}
+	doctor.Active = true
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO doctors (id, user_id, specialization, experience, phone, available_slots, active, timezone,
+		                     working_hours, slot_duration, buffer_minutes, languages, clinic)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at, updated_at
+	`, doctor.ID, doctor.UserID, doctor.Specialization, doctor.Experience,
+		sql.NullString{String: doctor.Phone, Valid: doctor.Phone != ""}, string(slotsJSON), doctor.Active,
+		sql.NullString{String: doctor.Timezone, Valid: doctor.Timezone != ""},
+		hoursJSON, doctor.SlotDuration, doctor.BufferMinutes, languagesArray(doctor.Languages), doctor.Clinic,
+	).Scan(&doctor.CreatedAt, &doctor.UpdatedAt)
+	if database.IsUniqueViolation(err, doctorUserIndex) {
+		return repository.ErrConflict
+	}
+	if database.IsForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(ctx, tx, actorID, repository.ActionDoctorCreate, repository.EntityDoctor,
+		doctor.ID, repository.DoctorFields(doctor))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update implements repository.DoctorRepository
+func (r *DoctorRepository) Update(ctx context.Context, id uuid.UUID, actorID uuid.UUID, mutate func(doctor *models.Doctor) error) (*models.Doctor, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	doctor, err := getDoctor(ctx, tx, "d.id = $1 FOR UPDATE OF d", id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *doctor
+	if err := mutate(doctor); err != nil {
+		return nil, err
+	}
+
+	action, changes := repository.DoctorChanges(&before, doctor)
+	if len(changes) == 0 {
+		return doctor, nil
+	}
+
+	slotsJSON, err := json.Marshal(doctor.AvailableSlots)
+	if err != nil {
+		return nil, err
+	}
+	hoursJSON, err := workingHoursJSON(doctor.WorkingHours)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE doctors
+		SET specialization = $1, experience = $2, phone = $3, available_slots = $4, active = $5,
+		    timezone = $6, working_hours = $7, slot_duration = $8, buffer_minutes = $9,
+		    languages = $10, clinic = $11, updated_at = NOW()
+		WHERE id = $12
+		RETURNING updated_at
+	`, doctor.Specialization, doctor.Experience, sql.NullString{String: doctor.Phone, Valid: doctor.Phone != ""},
+		string(slotsJSON), doctor.Active, sql.NullString{String: doctor.Timezone, Valid: doctor.Timezone != ""},
+		hoursJSON, doctor.SlotDuration, doctor.BufferMinutes, languagesArray(doctor.Languages), doctor.Clinic, id,
+	).Scan(&doctor.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, actorID, action, repository.EntityDoctor, id, changes); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return doctor, nil
+}
+
+func (r *DoctorRepository) getDoctor(ctx context.Context, condition string, arg interface{}) (*models.Doctor, error) {
+	return getDoctor(ctx, r.db, condition, arg)
+}
+
+func getDoctor(ctx context.Context, q queryer, condition string, arg interface{}) (*models.Doctor, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+doctorColumns+`
+		FROM doctors d
+		JOIN users u ON d.user_id = u.id
+		WHERE `+condition, arg)
+
+	doctor, err := scanDoctor(row)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return doctor, err
+}
+
+func (r *DoctorRepository) queryDoctors(ctx context.Context, query string, args ...interface{}) ([]models.Doctor, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	doctors := []models.Doctor{}
+	for rows.Next() {
+		doctor, err := scanDoctor(rows)
+		if err != nil {
+			return nil, err
+		}
+		doctors = append(doctors, *doctor)
+	}
+	return doctors, rows.Err()
+}
+
+// scanDoctor reads a row selected with doctorColumns, followed by any
+// extra columns into extra
+func scanDoctor(row rowScanner, extra ...interface{}) (*models.Doctor, error) {
+	var doctor models.Doctor
+	var user models.User
+	var slotsJSON, hoursJSON string
+
+	dest := []interface{}{
+		&doctor.ID, &doctor.UserID, &doctor.Specialization, &doctor.Experience,
+		&doctor.Phone, &slotsJSON, &doctor.Active, &doctor.Timezone,
+		&hoursJSON, &doctor.SlotDuration, &doctor.BufferMinutes, pq.Array(&doctor.Languages), &doctor.Clinic,
+		&doctor.CreatedAt, &doctor.UpdatedAt, &user.Name, &user.Email,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if len(doctor.Languages) == 0 {
+		doctor.Languages = nil
+	}
+
+	// Parse available slots JSON
+	if err := json.Unmarshal([]byte(slotsJSON), &doctor.AvailableSlots); err != nil {
+		doctor.AvailableSlots = make(map[string][]string)
+	}
+	if err := json.Unmarshal([]byte(hoursJSON), &doctor.WorkingHours); err != nil || len(doctor.WorkingHours) == 0 {
+		doctor.WorkingHours = nil
+	}
+
+	user.ID = doctor.UserID
+	doctor.User = &user
+	return &doctor, nil
+}
+
+// workingHoursJSON encodes working hours for the working_hours column,
+// which is NULL when none are set
+func workingHoursJSON(hours map[string][]models.WorkingPeriod) (sql.NullString, error) {
+	if len(hours) == 0 {
+		return sql.NullString{}, nil
+	}
+	encoded, err := json.Marshal(hours)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
+
+// languagesArray encodes languages for the languages column, which is
+// never NULL
+func languagesArray(languages []string) interface{} {
+	if languages == nil {
+		languages = []string{}
+	}
+	return pq.Array(languages)
+}
+
+// appendLimitOffset adds LIMIT and OFFSET clauses for positive values
+func appendLimitOffset(query string, args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit > 0 {
+		args = append(args, limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+	return query, args
+}
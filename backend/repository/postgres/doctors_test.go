@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// tsqueryTerm is a prefix term that needs no quoting in a tsquery
+var tsqueryTerm = regexp.MustCompile(`^[\p{L}\p{N}]+:\*$`)
+
+func TestPrefixQuery(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"John Smith", "john:* & smith:*"},
+		{"O'Brien", "o:* & brien:*"},
+		{"smith & !john | (doe) <-> x:*", "smith:* & john:* & doe:* & x:*"},
+		{"Müller-Lüdenscheidt", "müller:* & lüdenscheidt:*"},
+		{"Ковальчук", "ковальчук:*"},
+		{"王小明，内科", "王小明:* & 内科:*"},
+		{"'\\:*&|!()", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		got := prefixQuery(test.query)
+		if got != test.want {
+			t.Errorf("prefixQuery(%q) = %q, want %q", test.query, got, test.want)
+		}
+		if got == "" {
+			continue
+		}
+		for _, term := range strings.Split(got, " & ") {
+			if !tsqueryTerm.MatchString(term) {
+				t.Errorf("prefixQuery(%q) has the term %q, which a tsquery would parse as an operator", test.query, term)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// holdColumns are read by scanHold, in order
+const holdColumns = `
+	id, doctor_id, patient_id, starts_at, occupied_until, slot, appointment_type, kind, expires_at, created_at
+`
+
+// HoldRepository implements repository.HoldRepository
+type HoldRepository struct {
+	db *sql.DB
+}
+
+// Create implements repository.HoldRepository
+func (r *HoldRepository) Create(ctx context.Context, hold *models.SlotHold) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDoctorSchedule(ctx, tx, hold.DoctorID); err != nil {
+		return err
+	}
+
+	if hold.Kind == "" {
+		hold.Kind = models.HoldCheckout
+	}
+
+	// A new checkout hold replaces the patient's other checkout holds with
+	// the doctor, leaving the holds of waitlist offers alone
+	if hold.Kind == models.HoldCheckout {
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM slot_holds WHERE doctor_id = $1 AND patient_id = $2 AND kind = $3
+		`, hold.DoctorID, hold.PatientID, models.HoldCheckout)
+		if err != nil {
+			return err
+		}
+	}
+
+	var booked bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM appointments
+			WHERE doctor_id = $1 AND status != 'cancelled'
+			  AND tstzrange(appointment_date, occupied_until) && tstzrange($2, $3)
+		)
+	`, hold.DoctorID, hold.StartsAt, hold.OccupiedUntil).Scan(&booked)
+	if err != nil {
+		return err
+	}
+	if booked {
+		return repository.ErrSlotTaken
+	}
+
+	if err := checkHeld(ctx, tx, hold.DoctorID, hold.PatientID, hold.StartsAt, hold.OccupiedUntil); err != nil {
+		return err
+	}
+
+	if hold.ID == uuid.Nil {
+		hold.ID = uuid.New()
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO slot_holds (id, doctor_id, patient_id, starts_at, occupied_until, slot, appointment_type, kind, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`, hold.ID, hold.DoctorID, hold.PatientID, hold.StartsAt, hold.OccupiedUntil, hold.Slot, hold.Type,
+		hold.Kind, hold.ExpiresAt,
+	).Scan(&hold.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get implements repository.HoldRepository
+func (r *HoldRepository) Get(ctx context.Context, id uuid.UUID) (*models.SlotHold, error) {
+	hold, err := scanHold(r.db.QueryRowContext(ctx, `SELECT `+holdColumns+`
+		FROM slot_holds
+		WHERE id = $1 AND expires_at > NOW()
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return hold, err
+}
+
+// List implements repository.HoldRepository
+func (r *HoldRepository) List(ctx context.Context, doctorID uuid.UUID, from, until time.Time) ([]models.SlotHold, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+holdColumns+`
+		FROM slot_holds
+		WHERE doctor_id = $1 AND expires_at > NOW()
+		  AND tstzrange(starts_at, occupied_until) && tstzrange($2, $3)
+		ORDER BY starts_at, id
+	`, doctorID, from, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []models.SlotHold{}
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+	return holds, rows.Err()
+}
+
+// Release implements repository.HoldRepository
+func (r *HoldRepository) Release(ctx context.Context, id, patientID uuid.UUID) error {
+	return deleted(r.db.ExecContext(ctx, `
+		DELETE FROM slot_holds WHERE id = $1 AND patient_id = $2 AND expires_at > NOW()
+	`, id, patientID))
+}
+
+// DeleteExpired implements repository.HoldRepository
+func (r *HoldRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM slot_holds WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	return int(rows), err
+}
+
+// lockDoctorSchedule serializes the transactions that book or hold the
+// doctor's time, since holds and appointments live in different tables and
+// no single constraint keeps them from overlapping
+func lockDoctorSchedule(ctx context.Context, tx *sql.Tx, doctorID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM doctors WHERE id = $1 FOR NO KEY UPDATE`, doctorID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// checkHeld returns ErrSlotHeld if a patient other than patientID holds
+// time of the doctor overlapping [start, end)
+func checkHeld(ctx context.Context, tx *sql.Tx, doctorID, patientID uuid.UUID, start, end time.Time) error {
+	var held bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM slot_holds
+			WHERE doctor_id = $1 AND patient_id != $2 AND expires_at > NOW()
+			  AND tstzrange(starts_at, occupied_until) && tstzrange($3, $4)
+		)
+	`, doctorID, patientID, start, end).Scan(&held)
+	if err != nil {
+		return err
+	}
+	if held {
+		return repository.ErrSlotHeld
+	}
+	return nil
+}
+
+// scanHold reads a row selected with holdColumns
+func scanHold(row rowScanner) (*models.SlotHold, error) {
+	var hold models.SlotHold
+	err := row.Scan(&hold.ID, &hold.DoctorID, &hold.PatientID, &hold.StartsAt, &hold.OccupiedUntil,
+		&hold.Slot, &hold.Type, &hold.Kind, &hold.ExpiresAt, &hold.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
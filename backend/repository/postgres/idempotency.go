@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyRepository implements repository.IdempotencyRepository
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+// Start implements repository.IdempotencyRepository. An expired record of
+// the key is replaced.
+func (r *IdempotencyRepository) Start(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL,
+		    response_body = NULL, created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING created_at
+	`, record.UserID, record.Key, record.RequestHash, record.ExpiresAt).Scan(&record.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	existing, err := r.Get(ctx, record.UserID, record.Key)
+	if err == repository.ErrNotFound {
+		// The record expired between the insert and the read
+		return r.Start(ctx, record)
+	}
+	return existing, err
+}
+
+// Get implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id, idempotency_key, request_hash, status_code, content_type, response_body,
+		       created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND expires_at > NOW()
+	`, userID, key).Scan(&record.UserID, &record.Key, &record.RequestHash, &statusCode, &contentType,
+		&record.Body, &record.CreatedAt, &record.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+	return &record, nil
+}
+
+// Complete implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	return deleted(r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`, userID, key, statusCode, contentType, body))
+}
+
+// Abandon implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) Abandon(ctx context.Context, userID uuid.UUID, key string) error {
+	return deleted(r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`, userID, key))
+}
+
+// DeleteExpired implements repository.IdempotencyRepository
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	return int(rows), err
+}
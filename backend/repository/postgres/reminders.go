@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// reminderColumns are read by scanReminder, in order
+const reminderColumns = `
+	appointment_id, starts_at, offset_minutes, status, attempts, COALESCE(last_error, ''), sent_at,
+	created_at, updated_at
+`
+
+// ReminderRepository implements repository.ReminderRepository
+type ReminderRepository struct {
+	db *sql.DB
+}
+
+// Claim implements repository.ReminderRepository. A conflicting row is only
+// taken over when its last attempt failed.
+func (r *ReminderRepository) Claim(ctx context.Context, appointmentID uuid.UUID, startsAt time.Time, offset time.Duration, maxAttempts int) (bool, error) {
+	var attempts int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO appointment_reminders (appointment_id, starts_at, offset_minutes, status, attempts)
+		VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (appointment_id, starts_at, offset_minutes) DO UPDATE
+		SET status = EXCLUDED.status, attempts = appointment_reminders.attempts + 1, updated_at = NOW()
+		WHERE appointment_reminders.status = $5 AND appointment_reminders.attempts < $6
+		RETURNING attempts
+	`, appointmentID, startsAt, int(offset/time.Minute), models.ReminderSending, models.ReminderFailed,
+		maxAttempts).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Finish implements repository.ReminderRepository
+func (r *ReminderRepository) Finish(ctx context.Context, appointmentID uuid.UUID, startsAt time.Time, offset time.Duration, status, errMessage string) error {
+	return deleted(r.db.ExecContext(ctx, `
+		UPDATE appointment_reminders
+		SET status = $4, last_error = $5,
+		    sent_at = CASE WHEN $4 = 'sent' THEN NOW() ELSE sent_at END,
+		    updated_at = NOW()
+		WHERE appointment_id = $1 AND starts_at = $2 AND offset_minutes = $3
+	`, appointmentID, startsAt, int(offset/time.Minute), status,
+		sql.NullString{String: errMessage, Valid: errMessage != ""}))
+}
+
+// List implements repository.ReminderRepository
+func (r *ReminderRepository) List(ctx context.Context, filter repository.ReminderFilter) ([]models.Reminder, error) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where += " AND " + condition + " $" + strconv.Itoa(len(args))
+	}
+
+	if filter.AppointmentID != uuid.Nil {
+		add("appointment_id =", filter.AppointmentID)
+	}
+	if filter.Status != "" {
+		add("status =", filter.Status)
+	}
+
+	query, args := appendLimitOffset(`SELECT `+reminderColumns+`
+		FROM appointment_reminders`+where+`
+		ORDER BY updated_at DESC, appointment_id, offset_minutes`, args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []models.Reminder{}
+	for rows.Next() {
+		var reminder models.Reminder
+		var sentAt sql.NullTime
+		err := rows.Scan(&reminder.AppointmentID, &reminder.StartsAt, &reminder.OffsetMinutes,
+			&reminder.Status, &reminder.Attempts, &reminder.LastError, &sentAt,
+			&reminder.CreatedAt, &reminder.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if sentAt.Valid {
+			reminder.SentAt = &sentAt.Time
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"hospital-backend/database"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// holidayDateIndex is the unique constraint allowing one holiday per date
+const holidayDateIndex = "holidays_holiday_date_key"
+
+// ScheduleRepository implements repository.ScheduleRepository
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+// ListExceptions implements repository.ScheduleRepository
+func (r *ScheduleRepository) ListExceptions(ctx context.Context, doctorID uuid.UUID, from, to string) ([]models.ScheduleException, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, doctor_id, to_char(exception_date, 'YYYY-MM-DD'), kind,
+		       COALESCE(to_char(start_time, 'HH24:MI'), ''), COALESCE(to_char(end_time, 'HH24:MI'), ''),
+		       slots, COALESCE(reason, ''), created_at
+		FROM schedule_exceptions
+		WHERE doctor_id = $1 AND exception_date BETWEEN $2::date AND $3::date
+		ORDER BY exception_date, start_time NULLS FIRST, id
+	`, doctorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := []models.ScheduleException{}
+	for rows.Next() {
+		var exception models.ScheduleException
+		var slotsJSON []byte
+
+		err := rows.Scan(&exception.ID, &exception.DoctorID, &exception.Date, &exception.Kind,
+			&exception.StartTime, &exception.EndTime, &slotsJSON, &exception.Reason, &exception.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(slotsJSON, &exception.Slots); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, rows.Err()
+}
+
+// CreateException implements repository.ScheduleRepository
+func (r *ScheduleRepository) CreateException(ctx context.Context, exception *models.ScheduleException) error {
+	slots := exception.Slots
+	if slots == nil {
+		slots = []string{}
+	}
+	slotsJSON, err := json.Marshal(slots)
+	if err != nil {
+		return err
+	}
+
+	if exception.ID == uuid.Nil {
+		exception.ID = uuid.New()
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO schedule_exceptions (id, doctor_id, exception_date, kind, start_time, end_time, slots, reason)
+		VALUES ($1, $2, $3::date, $4, $5::time, $6::time, $7, $8)
+		RETURNING created_at
+	`, exception.ID, exception.DoctorID, exception.Date, exception.Kind,
+		sql.NullString{String: exception.StartTime, Valid: exception.StartTime != ""},
+		sql.NullString{String: exception.EndTime, Valid: exception.EndTime != ""},
+		string(slotsJSON), sql.NullString{String: exception.Reason, Valid: exception.Reason != ""},
+	).Scan(&exception.CreatedAt)
+	if database.IsForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// DeleteException implements repository.ScheduleRepository
+func (r *ScheduleRepository) DeleteException(ctx context.Context, doctorID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM schedule_exceptions WHERE id = $1 AND doctor_id = $2
+	`, id, doctorID)
+	return deleted(result, err)
+}
+
+// ListHolidays implements repository.ScheduleRepository
+func (r *ScheduleRepository) ListHolidays(ctx context.Context, from, to string) ([]models.Holiday, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, to_char(holiday_date, 'YYYY-MM-DD'), name, created_at
+		FROM holidays
+		WHERE holiday_date BETWEEN $1::date AND $2::date
+		ORDER BY holiday_date
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := []models.Holiday{}
+	for rows.Next() {
+		var holiday models.Holiday
+		if err := rows.Scan(&holiday.ID, &holiday.Date, &holiday.Name, &holiday.CreatedAt); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, rows.Err()
+}
+
+// CreateHoliday implements repository.ScheduleRepository
+func (r *ScheduleRepository) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	if holiday.ID == uuid.Nil {
+		holiday.ID = uuid.New()
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO holidays (id, holiday_date, name)
+		VALUES ($1, $2::date, $3)
+		RETURNING created_at
+	`, holiday.ID, holiday.Date, holiday.Name).Scan(&holiday.CreatedAt)
+	if database.IsUniqueViolation(err, holidayDateIndex) {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+// DeleteHoliday implements repository.ScheduleRepository
+func (r *ScheduleRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM holidays WHERE id = $1`, id)
+	return deleted(result, err)
+}
+
+// deleted returns ErrNotFound when a DELETE matched no rows
+func deleted(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package postgres implements the repository interfaces on PostgreSQL
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"hospital-backend/repository"
+)
+
+// NewStore returns repositories backed by db
+func NewStore(db *sql.DB) repository.Store {
+	return repository.Store{
+		Doctors:      &DoctorRepository{db: db},
+		Appointments: &AppointmentRepository{db: db},
+		Users:        &UserRepository{db: db},
+		Audit:        &AuditRepository{db: db},
+		Schedules:    &ScheduleRepository{db: db},
+		Holds:        &HoldRepository{db: db},
+		Waitlist:     &WaitlistRepository{db: db},
+		Reminders:    &ReminderRepository{db: db},
+		Devices:      &DeviceRepository{db: db},
+		Idempotency:  &IdempotencyRepository{db: db},
+	}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"hospital-backend/database"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+const userColumns = `id, firebase_uid, name, email, COALESCE(phone, ''), role, created_at, updated_at`
+
+// UserRepository implements repository.UserRepository
+type UserRepository struct {
+	db *sql.DB
+}
+
+// Get implements repository.UserRepository
+func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return r.getUser(ctx, "id = $1", id)
+}
+
+// GetByFirebaseUID implements repository.UserRepository
+func (r *UserRepository) GetByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error) {
+	return r.getUser(ctx, "firebase_uid = $1", firebaseUID)
+}
+
+// Upsert implements repository.UserRepository
+func (r *UserRepository) Upsert(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO users (firebase_uid, name, email, role)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (firebase_uid) DO UPDATE
+		SET name = EXCLUDED.name, email = EXCLUDED.email, updated_at = NOW()
+		RETURNING ` + userColumns
+
+	row := r.db.QueryRowContext(ctx, query, user.FirebaseUID, user.Name, user.Email, user.Role)
+	return scanUserInto(row, user)
+}
+
+// List implements repository.UserRepository
+func (r *UserRepository) List(ctx context.Context, role string, limit, offset int) ([]models.User, error) {
+	query := `SELECT ` + userColumns + `
+		FROM users
+		WHERE $1 = '' OR role = $1
+		ORDER BY name, id
+	`
+	query, args := appendLimitOffset(query, []interface{}{role}, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := scanUserInto(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Create implements repository.UserRepository
+func (r *UserRepository) Create(ctx context.Context, user *models.User, actorID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO users (firebase_uid, name, email, role)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+userColumns, user.FirebaseUID, user.Name, user.Email, user.Role)
+	err = scanUserInto(row, user)
+	if database.IsUniqueViolation(err, "") {
+		return repository.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(ctx, tx, actorID, repository.ActionUserCreate, repository.EntityUser, user.ID,
+		map[string]models.FieldChange{
+			"firebase_uid": {To: user.FirebaseUID},
+			"name":         {To: user.Name},
+			"email":        {To: user.Email},
+			"role":         {To: user.Role},
+		})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRole implements repository.UserRepository
+func (r *UserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role string, actorID uuid.UUID) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	row := tx.QueryRowContext(ctx, `
+		UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2
+		RETURNING `+userColumns, role, id)
+	if err := scanUserInto(row, &user); err != nil {
+		return nil, err
+	}
+
+	if previous != role {
+		err = recordAudit(ctx, tx, actorID, repository.ActionUserRoleChange, repository.EntityUser, id,
+			map[string]models.FieldChange{"role": {From: previous, To: role}})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdatePhone implements repository.UserRepository
+func (r *UserRepository) UpdatePhone(ctx context.Context, id uuid.UUID, phone string) (*models.User, error) {
+	var user models.User
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE users SET phone = $1, updated_at = NOW() WHERE id = $2
+		RETURNING `+userColumns, sql.NullString{String: phone, Valid: phone != ""}, id)
+	err := scanUserInto(row, &user)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetCalendarToken implements repository.UserRepository
+func (r *UserRepository) SetCalendarToken(ctx context.Context, id uuid.UUID, tokenHash string) error {
+	return deleted(r.db.ExecContext(ctx, `
+		UPDATE users SET calendar_token_hash = $1 WHERE id = $2
+	`, sql.NullString{String: tokenHash, Valid: tokenHash != ""}, id))
+}
+
+// GetByCalendarToken implements repository.UserRepository
+func (r *UserRepository) GetByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	return r.getUser(ctx, "calendar_token_hash = $1", tokenHash)
+}
+
+func (r *UserRepository) getUser(ctx context.Context, condition string, arg interface{}) (*models.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+condition, arg)
+
+	var user models.User
+	err := scanUserInto(row, &user)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// scanUserInto reads a row selected with userColumns
+func scanUserInto(row rowScanner, user *models.User) error {
+	return row.Scan(
+		&user.ID, &user.FirebaseUID, &user.Name, &user.Email, &user.Phone,
+		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+	)
+}
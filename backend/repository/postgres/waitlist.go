@@ -0,0 +1,365 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"hospital-backend/database"
+	"hospital-backend/models"
+	"hospital-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// entryColumns are read by scanEntry, in order
+const entryColumns = `
+	e.id, e.patient_id, e.doctor_id, to_char(e.from_date, 'YYYY-MM-DD'), to_char(e.to_date, 'YYYY-MM-DD'),
+	e.preferred_slots, e.appointment_type, e.status, e.created_at, e.updated_at
+`
+
+// offerColumns are read by scanOffer, in order
+const offerColumns = `
+	o.id, o.entry_id, e.doctor_id, e.patient_id, o.hold_id, o.starts_at, o.slot, e.appointment_type,
+	o.status, o.expires_at, o.created_at, o.responded_at
+`
+
+// offerJoins adds the entry of each offer
+const offerJoins = `
+	FROM waitlist_offers o
+	JOIN waitlist_entries e ON o.entry_id = e.id
+`
+
+// WaitlistRepository implements repository.WaitlistRepository
+type WaitlistRepository struct {
+	db *sql.DB
+}
+
+// CreateEntry implements repository.WaitlistRepository
+func (r *WaitlistRepository) CreateEntry(ctx context.Context, entry *models.WaitlistEntry) error {
+	slots := entry.PreferredSlots
+	if slots == nil {
+		slots = []string{}
+	}
+	slotsJSON, err := json.Marshal(slots)
+	if err != nil {
+		return err
+	}
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	entry.Status = models.WaitlistWaiting
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO waitlist_entries (id, patient_id, doctor_id, from_date, to_date, preferred_slots, appointment_type, status)
+		VALUES ($1, $2, $3, $4::date, $5::date, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`, entry.ID, entry.PatientID, entry.DoctorID, entry.FromDate, entry.ToDate, string(slotsJSON),
+		entry.Type, entry.Status,
+	).Scan(&entry.CreatedAt, &entry.UpdatedAt)
+	if database.IsForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// GetEntry implements repository.WaitlistRepository
+func (r *WaitlistRepository) GetEntry(ctx context.Context, id uuid.UUID) (*models.WaitlistEntry, error) {
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, `SELECT `+entryColumns+`
+		FROM waitlist_entries e
+		WHERE e.id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return entry, err
+}
+
+// ListEntries implements repository.WaitlistRepository
+func (r *WaitlistRepository) ListEntries(ctx context.Context, filter repository.WaitlistFilter) ([]models.WaitlistEntry, error) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where += " AND " + condition + " $" + strconv.Itoa(len(args))
+	}
+
+	if filter.PatientID != uuid.Nil {
+		add("e.patient_id =", filter.PatientID)
+	}
+	if filter.DoctorID != uuid.Nil {
+		add("e.doctor_id =", filter.DoctorID)
+	}
+	if filter.Date != "" {
+		add("e.from_date <=", filter.Date)
+		add("e.to_date >=", filter.Date)
+	}
+	if filter.Status != "" {
+		add("e.status =", filter.Status)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+entryColumns+`
+		FROM waitlist_entries e`+where+`
+		ORDER BY e.created_at, e.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// CancelEntry implements repository.WaitlistRepository
+func (r *WaitlistRepository) CancelEntry(ctx context.Context, id, patientID uuid.UUID) (*models.WaitlistOffer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM waitlist_entries WHERE id = $1 AND patient_id = $2 FOR UPDATE
+	`, id, patientID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != models.WaitlistWaiting && status != models.WaitlistOffered {
+		return nil, repository.ErrConflict
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE waitlist_entries SET status = $2, updated_at = NOW() WHERE id = $1
+	`, id, models.WaitlistCancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	offer, err := scanOffer(tx.QueryRowContext(ctx, `
+		UPDATE waitlist_offers o SET status = $2, responded_at = NOW()
+		FROM waitlist_entries e
+		WHERE o.entry_id = e.id AND o.entry_id = $1 AND o.status = 'pending'
+		RETURNING `+offerColumns, id, models.OfferDeclined))
+	if err == sql.ErrNoRows {
+		offer, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return offer, tx.Commit()
+}
+
+// CreateOffer implements repository.WaitlistRepository
+func (r *WaitlistRepository) CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE waitlist_entries SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status = $3
+	`, offer.EntryID, models.WaitlistOffered, models.WaitlistWaiting)
+	if err := deleted(result, err); err == repository.ErrNotFound {
+		return repository.ErrConflict
+	} else if err != nil {
+		return err
+	}
+
+	if offer.ID == uuid.Nil {
+		offer.ID = uuid.New()
+	}
+	offer.Status = models.OfferPending
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO waitlist_offers (id, entry_id, hold_id, starts_at, slot, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, offer.ID, offer.EntryID, offer.HoldID, offer.StartsAt, offer.Slot, offer.Status, offer.ExpiresAt,
+	).Scan(&offer.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetOffer implements repository.WaitlistRepository
+func (r *WaitlistRepository) GetOffer(ctx context.Context, id uuid.UUID) (*models.WaitlistOffer, error) {
+	offer, err := scanOffer(r.db.QueryRowContext(ctx, `SELECT `+offerColumns+offerJoins+`
+		WHERE o.id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	return offer, err
+}
+
+// ListOffers implements repository.WaitlistRepository
+func (r *WaitlistRepository) ListOffers(ctx context.Context, filter repository.OfferFilter) ([]models.WaitlistOffer, error) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where += " AND " + condition + " $" + strconv.Itoa(len(args))
+	}
+
+	if filter.PatientID != uuid.Nil {
+		add("e.patient_id =", filter.PatientID)
+	}
+	if filter.DoctorID != uuid.Nil {
+		add("e.doctor_id =", filter.DoctorID)
+	}
+	if !filter.StartsAt.IsZero() {
+		add("o.starts_at =", filter.StartsAt)
+	}
+	if filter.Status != "" {
+		add("o.status =", filter.Status)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+offerColumns+offerJoins+where+`
+		ORDER BY o.created_at, o.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOffers(rows)
+}
+
+// ResolveOffer implements repository.WaitlistRepository
+func (r *WaitlistRepository) ResolveOffer(ctx context.Context, id uuid.UUID, status string) (*models.WaitlistOffer, error) {
+	entryStatus := models.WaitlistWaiting
+	if status == models.OfferAccepted {
+		entryStatus = models.WaitlistBooked
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	offer, err := scanOffer(tx.QueryRowContext(ctx, `
+		UPDATE waitlist_offers o SET status = $2, responded_at = NOW()
+		FROM waitlist_entries e
+		WHERE o.entry_id = e.id AND o.id = $1 AND o.status = 'pending'
+		RETURNING `+offerColumns, id, status))
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM waitlist_offers WHERE id = $1)`, id).Scan(&exists)
+		if err == nil && exists {
+			err = repository.ErrConflict
+		} else if err == nil {
+			err = repository.ErrNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE waitlist_entries SET status = $2, updated_at = NOW() WHERE id = $1 AND status = $3
+	`, offer.EntryID, entryStatus, models.WaitlistOffered)
+	if err != nil {
+		return nil, err
+	}
+
+	return offer, tx.Commit()
+}
+
+// ExpireOffers implements repository.WaitlistRepository
+func (r *WaitlistRepository) ExpireOffers(ctx context.Context, now time.Time) ([]models.WaitlistOffer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE waitlist_offers o SET status = $2
+		FROM waitlist_entries e
+		WHERE o.entry_id = e.id AND o.status = 'pending' AND o.expires_at <= $1
+		RETURNING `+offerColumns, now, models.OfferExpired)
+	if err != nil {
+		return nil, err
+	}
+	offers, err := scanOffers(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, offer := range offers {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE waitlist_entries SET status = $2, updated_at = NOW() WHERE id = $1 AND status = $3
+		`, offer.EntryID, models.WaitlistWaiting, models.WaitlistOffered)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return offers, tx.Commit()
+}
+
+// scanEntry reads a row selected with entryColumns
+func scanEntry(row rowScanner) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	var slotsJSON []byte
+	err := row.Scan(&entry.ID, &entry.PatientID, &entry.DoctorID, &entry.FromDate, &entry.ToDate,
+		&slotsJSON, &entry.Type, &entry.Status, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(slotsJSON, &entry.PreferredSlots); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// scanOffer reads a row selected with offerColumns
+func scanOffer(row rowScanner) (*models.WaitlistOffer, error) {
+	var offer models.WaitlistOffer
+	var respondedAt sql.NullTime
+	err := row.Scan(&offer.ID, &offer.EntryID, &offer.DoctorID, &offer.PatientID, &offer.HoldID,
+		&offer.StartsAt, &offer.Slot, &offer.Type, &offer.Status, &offer.ExpiresAt, &offer.CreatedAt,
+		&respondedAt)
+	if err != nil {
+		return nil, err
+	}
+	if respondedAt.Valid {
+		offer.RespondedAt = &respondedAt.Time
+	}
+	return &offer, nil
+}
+
+// scanOffers reads every row of rows with scanOffer
+func scanOffers(rows *sql.Rows) ([]models.WaitlistOffer, error) {
+	offers := []models.WaitlistOffer{}
+	for rows.Next() {
+		offer, err := scanOffer(rows)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, *offer)
+	}
+	return offers, rows.Err()
+}
@@ -0,0 +1,336 @@
+// Package repository defines the storage interfaces used by the handlers.
+// The postgres subpackage implements them on PostgreSQL and the memory
+// subpackage keeps everything in process memory for development and tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"hospital-backend/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrSlotTaken is returned when an active appointment of the doctor
+	// overlaps the time being booked
+	ErrSlotTaken = errors.New("appointment slot is already booked")
+	// ErrConflict is returned when a record with the same unique key exists
+	ErrConflict = errors.New("record already exists")
+	// ErrSlotHeld is returned when another patient's unexpired hold
+	// overlaps the time being booked or held
+	ErrSlotHeld = errors.New("appointment slot is held by another patient")
+	// ErrHoldExpired is returned when a booking names a hold that has
+	// expired or was already used
+	ErrHoldExpired = errors.New("slot hold has expired")
+)
+
+// DoctorFilter selects a page of doctors ordered by name and ID
+type DoctorFilter struct {
+	Specialization string
+	// IncludeInactive also returns deactivated doctors
+	IncludeInactive bool
+	// After and Before select the doctors after or before a key in that
+	// order, for keyset paging. Before returns the doctors nearest the
+	// key, still in listing order.
+	After  *DoctorKey
+	Before *DoctorKey
+	Limit  int
+	Offset int
+}
+
+// DoctorKey is the position of a doctor in listings ordered by name and ID
+type DoctorKey struct {
+	Name string
+	ID   uuid.UUID
+}
+
+// DoctorSearch matches active doctors against Query by their name,
+// specialization, languages and clinic: each word of Query starts a word
+// of them, or Query is similar to them despite typos. Matches are ordered
+// best first; an empty Query matches every doctor, by name.
+type DoctorSearch struct {
+	Query          string
+	Specialization string
+	Limit          int
+	Offset         int
+}
+
+// AppointmentFilter selects appointments. Zero fields do not filter.
+type AppointmentFilter struct {
+	PatientID uuid.UUID
+	DoctorID  uuid.UUID
+	// From and Until bound the start instant appointment_date to
+	// [From, Until)
+	From  time.Time
+	Until time.Time
+	// ActiveOnly excludes cancelled appointments
+	ActiveOnly bool
+	// Ascending orders by appointment_date ascending instead of descending
+	Ascending bool
+	// After and Before select the appointments after or before a key in
+	// the listing order, for keyset paging. Before returns the
+	// appointments nearest the key, still in listing order.
+	After  *AppointmentKey
+	Before *AppointmentKey
+	Limit  int
+	Offset int
+}
+
+// AppointmentKey is the position of an appointment in listings ordered by
+// appointment_date and ID
+type AppointmentKey struct {
+	Date time.Time
+	ID   uuid.UUID
+}
+
+// DoctorRepository stores doctor profiles joined with their users row.
+// Get and GetByUserID also return deactivated doctors. Changes are
+// recorded in the audit log as made by actorID.
+type DoctorRepository interface {
+	List(ctx context.Context, filter DoctorFilter) ([]models.Doctor, error)
+	Count(ctx context.Context, filter DoctorFilter) (int, error)
+	Search(ctx context.Context, search DoctorSearch) ([]models.DoctorMatch, error)
+	CountSearch(ctx context.Context, search DoctorSearch) (int, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Doctor, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Doctor, error)
+	// Create inserts an active doctor profile. It returns ErrNotFound if
+	// the user does not exist and ErrConflict if it already has a profile.
+	Create(ctx context.Context, doctor *models.Doctor, actorID uuid.UUID) error
+	// Update locks the doctor, lets mutate change its specialization,
+	// experience, phone, available slots, active flag, timezone, working
+	// hours, slot settings, languages and clinic, and
+	// persists the result atomically. An error from mutate aborts the
+	// update and is returned as is.
+	Update(ctx context.Context, id uuid.UUID, actorID uuid.UUID, mutate func(doctor *models.Doctor) error) (*models.Doctor, error)
+}
+
+// AppointmentRepository stores appointments and their status history.
+// Appointments are returned with Doctor (and Doctor.User) and Patient
+// populated.
+type AppointmentRepository interface {
+	// Book inserts a scheduled appointment and records its creation in the
+	// status history. It returns ErrSlotTaken if another active appointment
+	// of the doctor overlaps [AppointmentDate, OccupiedUntil), and
+	// ErrSlotHeld if another patient's unexpired hold does. A non-nil
+	// holdID is consumed by the booking; ErrHoldExpired is returned if it
+	// is no longer held for the patient.
+	Book(ctx context.Context, appointment *models.Appointment, bookedBy uuid.UUID, holdID uuid.UUID) error
+	Get(ctx context.Context, id uuid.UUID) (*models.Appointment, error)
+	List(ctx context.Context, filter AppointmentFilter) ([]models.Appointment, error)
+	Count(ctx context.Context, filter AppointmentFilter) (int, error)
+	// Update locks the appointment, lets mutate change its date, slot,
+	// occupied range, status, notes, clinical notes and reschedule flag, and persists the
+	// result atomically. An error from mutate aborts the update and is
+	// returned as is. Moves return ErrSlotTaken and ErrSlotHeld like Book.
+	// Status changes, and moves of an already rescheduled appointment, are
+	// recorded in the status history.
+	Update(ctx context.Context, id uuid.UUID, changedBy uuid.UUID, mutate func(appointment *models.Appointment) error) (*models.Appointment, error)
+}
+
+// UserRepository stores users keyed by their Firebase UID
+type UserRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error)
+	// Upsert inserts the user or updates name and email of the user with
+	// the same FirebaseUID, and fills in ID, role and timestamps. The role of
+	// an existing user is kept; it changes only through UpdateRole.
+	Upsert(ctx context.Context, user *models.User) error
+	List(ctx context.Context, role string, limit, offset int) ([]models.User, error)
+	// Create inserts a user on behalf of actorID and records it in the
+	// audit log. It returns ErrConflict if the FirebaseUID is taken.
+	Create(ctx context.Context, user *models.User, actorID uuid.UUID) error
+	// UpdateRole changes the role of a user on behalf of actorID and
+	// records it in the audit log
+	UpdateRole(ctx context.Context, id uuid.UUID, role string, actorID uuid.UUID) (*models.User, error)
+	// UpdatePhone sets the phone number of a user; an empty phone clears it
+	UpdatePhone(ctx context.Context, id uuid.UUID, phone string) (*models.User, error)
+	// SetCalendarToken stores the hash of the user's calendar feed token,
+	// replacing any previous one; an empty hash revokes the feed
+	SetCalendarToken(ctx context.Context, id uuid.UUID, tokenHash string) error
+	// GetByCalendarToken returns the user whose calendar feed token has
+	// tokenHash
+	GetByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error)
+}
+
+// AuditFilter selects audit entries, newest first. Zero fields do not
+// filter.
+type AuditFilter struct {
+	EntityType string
+	EntityID   uuid.UUID
+	ActorID    uuid.UUID
+	Limit      int
+	Offset     int
+}
+
+// AuditRepository reads the audit log written by the other repositories
+type AuditRepository interface {
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error)
+}
+
+// ScheduleRepository stores doctors' schedule exceptions and the hospital
+// holiday calendar. Dates are "YYYY-MM-DD" strings and ranges are
+// inclusive.
+type ScheduleRepository interface {
+	ListExceptions(ctx context.Context, doctorID uuid.UUID, from, to string) ([]models.ScheduleException, error)
+	CreateException(ctx context.Context, exception *models.ScheduleException) error
+	// DeleteException returns ErrNotFound unless the exception belongs to
+	// doctorID
+	DeleteException(ctx context.Context, doctorID, id uuid.UUID) error
+	ListHolidays(ctx context.Context, from, to string) ([]models.Holiday, error)
+	// CreateHoliday returns ErrConflict if the date already is a holiday
+	CreateHoliday(ctx context.Context, holiday *models.Holiday) error
+	DeleteHoliday(ctx context.Context, id uuid.UUID) error
+}
+
+// HoldRepository stores temporary slot holds. Expired holds are ignored by
+// every method but DeleteExpired.
+type HoldRepository interface {
+	// Create stores hold. A checkout hold releases the patient's other
+	// checkout holds on the doctor; offer holds are never released by it.
+	// It returns ErrSlotTaken if an active appointment overlaps
+	// [StartsAt, OccupiedUntil) and ErrSlotHeld if another patient's hold
+	// does.
+	Create(ctx context.Context, hold *models.SlotHold) error
+	Get(ctx context.Context, id uuid.UUID) (*models.SlotHold, error)
+	// List returns the holds on the doctor's time that overlap [from, until)
+	List(ctx context.Context, doctorID uuid.UUID, from, until time.Time) ([]models.SlotHold, error)
+	// Release deletes a hold; it returns ErrNotFound unless the hold
+	// belongs to patientID
+	Release(ctx context.Context, id, patientID uuid.UUID) error
+	// DeleteExpired deletes the holds that expired before now and returns
+	// how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// WaitlistFilter selects waitlist entries, oldest first. Zero fields do
+// not filter.
+type WaitlistFilter struct {
+	PatientID uuid.UUID
+	DoctorID  uuid.UUID
+	// Date keeps the entries whose date range includes this "YYYY-MM-DD"
+	// date
+	Date   string
+	Status string
+}
+
+// OfferFilter selects waitlist offers, oldest first. Zero fields do not
+// filter.
+type OfferFilter struct {
+	PatientID uuid.UUID
+	DoctorID  uuid.UUID
+	StartsAt  time.Time
+	Status    string
+}
+
+// WaitlistRepository stores waitlist entries and the offers of freed slots
+// made to them
+type WaitlistRepository interface {
+	// CreateEntry stores a waiting entry. It returns ErrNotFound if the
+	// doctor does not exist.
+	CreateEntry(ctx context.Context, entry *models.WaitlistEntry) error
+	GetEntry(ctx context.Context, id uuid.UUID) (*models.WaitlistEntry, error)
+	ListEntries(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error)
+	// CancelEntry cancels a waiting or offered entry of patientID and
+	// declines its pending offer, which is returned (nil if there was
+	// none). It returns ErrNotFound unless the entry belongs to patientID
+	// and ErrConflict if it is already booked or cancelled.
+	CancelEntry(ctx context.Context, id, patientID uuid.UUID) (*models.WaitlistOffer, error)
+	// CreateOffer stores a pending offer and marks its entry offered. It
+	// returns ErrConflict unless the entry is waiting.
+	CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error
+	GetOffer(ctx context.Context, id uuid.UUID) (*models.WaitlistOffer, error)
+	ListOffers(ctx context.Context, filter OfferFilter) ([]models.WaitlistOffer, error)
+	// ResolveOffer moves a pending offer to status, accepted or declined,
+	// and its entry to booked or back to waiting. It returns ErrConflict
+	// unless the offer is pending.
+	ResolveOffer(ctx context.Context, id uuid.UUID, status string) (*models.WaitlistOffer, error)
+	// ExpireOffers marks the pending offers that expired before now as
+	// expired, puts their entries back to waiting and returns them
+	ExpireOffers(ctx context.Context, now time.Time) ([]models.WaitlistOffer, error)
+}
+
+// ReminderFilter selects reminders, most recently updated first. Zero
+// fields do not filter.
+type ReminderFilter struct {
+	AppointmentID uuid.UUID
+	Status        string
+	Limit         int
+	Offset        int
+}
+
+// ReminderRepository tracks the delivery of appointment reminders, so that
+// each reminder is sent at most once even across restarts
+type ReminderRepository interface {
+	// Claim marks the reminder sent offset before the appointment starting
+	// at startsAt as sending and returns true. It returns false if the
+	// reminder was sent or skipped, is being sent, or has failed
+	// maxAttempts times.
+	Claim(ctx context.Context, appointmentID uuid.UUID, startsAt time.Time, offset time.Duration, maxAttempts int) (bool, error)
+	// Finish records the outcome of a claimed reminder: ReminderSent,
+	// ReminderFailed with errMessage, or ReminderSkipped
+	Finish(ctx context.Context, appointmentID uuid.UUID, startsAt time.Time, offset time.Duration, status, errMessage string) error
+	List(ctx context.Context, filter ReminderFilter) ([]models.Reminder, error)
+}
+
+// DeviceRepository stores the push notification tokens of users' devices
+type DeviceRepository interface {
+	// Register stores device, or moves its token to device.UserID if
+	// another user registered it, as when a phone changes hands
+	Register(ctx context.Context, device *models.DeviceToken) error
+	// Unregister forgets one of userID's tokens, returning ErrNotFound if
+	// userID has not registered it
+	Unregister(ctx context.Context, userID uuid.UUID, token string) error
+	// Tokens returns the tokens registered by userID
+	Tokens(ctx context.Context, userID uuid.UUID) ([]string, error)
+	// Forget removes tokens whoever registered them, as when the push
+	// service reports them stale
+	Forget(ctx context.Context, tokens []string) error
+}
+
+// IdempotencyRepository stores the responses to requests sent with an
+// Idempotency-Key, so that retries are answered without repeating them
+type IdempotencyRepository interface {
+	// Start stores record, which has no response yet, unless the user
+	// already has an unexpired record for the key. It returns that record,
+	// or nil when record was stored.
+	Start(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Get returns the unexpired record of the user's key
+	Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error)
+	// Complete stores the response of a started record
+	Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error
+	// Abandon deletes a started record without a response, so that the key
+	// can be retried
+	Abandon(ctx context.Context, userID uuid.UUID, key string) error
+	// DeleteExpired deletes the records that expired before now and returns
+	// how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// Store bundles the repositories of one storage backend
+type Store struct {
+	Doctors      DoctorRepository
+	Appointments AppointmentRepository
+	Users        UserRepository
+	Audit        AuditRepository
+	Schedules    ScheduleRepository
+	Holds        HoldRepository
+	Waitlist     WaitlistRepository
+	Reminders    ReminderRepository
+	Devices      DeviceRepository
+	Idempotency  IdempotencyRepository
+}
+
+// IsTransition reports whether an update from before to after is a status
+// transition worth recording in the status history
+func IsTransition(before, after *models.Appointment) bool {
+	if before.Status != after.Status {
+		return true
+	}
+	moved := !before.AppointmentDate.Equal(after.AppointmentDate) || before.Slot != after.Slot
+	return moved && after.Status == models.StatusRescheduled
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"hospital-backend/apierror"
+	"hospital-backend/handlers"
+	"hospital-backend/middleware"
+	"hospital-backend/repository"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter registers every route on a new Gin engine, serving them with h
+// and authenticating users from store. Responses to mutating appointment
+// requests sent with an Idempotency-Key are kept for idempotencyTTL, and
+// the legacy routes are retired on the schedule of deprecation. It fails
+// when a route is missing from routeDocs, so the OpenAPI document served at
+// /openapi.json covers every route.
+func newRouter(store repository.Store, h *handlers.Handler, idempotencyTTL time.Duration, deprecation middleware.Deprecation) (*gin.Engine, error) {
+
+	// Create Gin router
+	r := gin.Default()
+
+	// Request IDs and error envelopes, ahead of every other middleware
+	r.Use(middleware.Errors())
+
+	// CORS configuration
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"*"}, // In production, specify your frontend URL
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed", middleware.RequestIDHeader, "Deprecation", "Sunset", "Link"},
+		AllowCredentials: true,
+	}))
+
+	// Health check endpoint
+	r.GET("/health", health)
+
+	// API documentation, generated from this route table
+	spec := &apiSpec{}
+	r.GET("/openapi.json", spec.openAPISpec)
+	r.GET("/docs", swaggerUI)
+	r.GET("/docs/assets/*filepath", swaggerAssets)
+
+	// Retries of these routes with the same Idempotency-Key are answered
+	// from the first response
+	idempotent := middleware.Idempotency(store.Idempotency, idempotencyTTL)
+
+	// Current API, answering in the MobileResponse envelope
+	v1 := r.Group("/api/v1")
+	{
+		v1Protected := v1.Group("/")
+		v1Protected.Use(middleware.ValidateToken(store.Users))
+		registerRoutes(v1, v1Protected, h, idempotent)
+
+		v1.GET("/doctors", h.ListDoctors)
+		v1.GET("/doctors/search", h.SearchDoctors)
+
+		v1Protected.POST("/appointments", idempotent, h.CreateAppointment)
+		v1Protected.GET("/appointments", h.ListAppointments)
+		v1Protected.POST("/slots/hold", h.HoldSlot)
+		v1Protected.DELETE("/slots/hold/:token", h.ReleaseSlotHold)
+	}
+
+	// Legacy and mobile routes, kept until deprecation.Sunset as adapters
+	// over the /api/v1 handlers
+	api := r.Group("/api")
+	api.Use(middleware.Deprecated(deprecation))
+	{
+		protected := api.Group("/")
+		protected.Use(middleware.ValidateToken(store.Users))
+		registerRoutes(api, protected, h, idempotent)
+
+		api.GET("/doctors", h.GetDoctors)
+		protected.POST("/appointments", idempotent, h.CreateAppointmentLegacy)
+		protected.GET("/appointments", h.GetUserAppointments)
+
+		// Mobile routes
+		api.GET("/mobile/doctors", h.ListDoctors)
+		api.GET("/mobile/doctors/:id/availability", h.GetDoctorAvailability)
+		api.GET("/mobile/search/doctors", h.SearchDoctors)
+		protected.POST("/mobile/appointments", idempotent, h.CreateAppointmentMobile)
+		protected.GET("/mobile/appointments", h.ListAppointments)
+		protected.POST("/mobile/slots/hold", h.HoldSlot)
+		protected.DELETE("/mobile/slots/hold/:token", h.ReleaseSlotHold)
+	}
+
+	r.NoRoute(func(c *gin.Context) {
+		middleware.AbortWithError(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Route not found"))
+	})
+
+	doc, err := buildSpec(r.Routes())
+	if err != nil {
+		return nil, err
+	}
+	if spec.body, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// registerRoutes registers the routes served alike under /api/v1 and
+// /api: public ones on api and authenticated ones on protected
+func registerRoutes(api, protected *gin.RouterGroup, h *handlers.Handler, idempotent gin.HandlerFunc) {
+	// Public routes (no auth required)
+	api.GET("/doctors/:id", h.GetDoctorByID)
+	api.GET("/doctors/:id/availability", h.GetDoctorAvailability)
+	api.GET("/holidays", h.ListHolidays)
+
+	// Calendar feeds are authenticated by the secret token in the URL
+	api.GET("/calendar/:token", h.GetCalendarFeed)
+
+	// Protected routes (require a verified token)
+	protected.GET("/me", h.GetCurrentUser)
+	protected.PUT("/me", h.UpdateCurrentUser)
+	protected.POST("/me/calendar-feed", h.CreateCalendarFeed)
+	protected.DELETE("/me/calendar-feed", h.DeleteCalendarFeed)
+
+	protected.POST("/devices", h.RegisterDevice)
+	protected.DELETE("/devices/:token", h.UnregisterDevice)
+
+	protected.GET("/appointments/:id", h.GetAppointment)
+	protected.PUT("/appointments/:id", idempotent, h.UpdateAppointment)
+	protected.DELETE("/appointments/:id", idempotent, h.CancelAppointment)
+
+	protected.POST("/waitlist", h.JoinWaitlist)
+	protected.GET("/waitlist", h.GetWaitlist)
+	protected.DELETE("/waitlist/:id", h.LeaveWaitlist)
+	protected.POST("/waitlist/offers/:id/accept", idempotent, h.AcceptWaitlistOffer)
+	protected.POST("/waitlist/offers/:id/decline", h.DeclineWaitlistOffer)
+
+	// Admin-only routes
+	admin := protected.Group("/admin")
+	admin.Use(middleware.AdminOnly.Enforce())
+	{
+		admin.GET("/users", h.ListUsers)
+		admin.POST("/users", h.CreateUser)
+		admin.GET("/users/:id", h.GetUser)
+		admin.PUT("/users/:id/role", h.UpdateUserRole)
+
+		admin.GET("/doctors", h.ListDoctorsAdmin)
+		admin.POST("/doctors", h.CreateDoctor)
+		admin.PUT("/doctors/:id", h.UpdateDoctor)
+		admin.DELETE("/doctors/:id", h.DeactivateDoctor)
+
+		admin.POST("/holidays", h.CreateHoliday)
+		admin.DELETE("/holidays/:id", h.DeleteHoliday)
+
+		admin.GET("/audit", h.ListAuditLog)
+		admin.GET("/reminders", h.ListReminders)
+	}
+
+	// Doctor-only routes
+	doctor := protected.Group("/doctor")
+	doctor.Use(middleware.DoctorOnly.Enforce())
+	{
+		doctor.GET("/profile", h.GetDoctorProfile)
+		doctor.GET("/agenda", h.GetDoctorAgenda)
+		doctor.GET("/agenda/week", h.GetDoctorWeekAgenda)
+		doctor.PUT("/appointments/:id/status", idempotent, h.UpdateVisitStatus)
+		doctor.POST("/appointments/:id/notes", idempotent, h.AddClinicalNotes)
+		doctor.PUT("/slots", h.UpdateAvailableSlots)
+		doctor.PUT("/working-hours", h.UpdateWorkingHours)
+		doctor.GET("/exceptions", h.ListScheduleExceptions)
+		doctor.POST("/exceptions", h.CreateScheduleException)
+		doctor.DELETE("/exceptions/:id", h.DeleteScheduleException)
+	}
+}
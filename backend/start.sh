@@ -30,6 +30,11 @@ if [ ! -f .env ]; then
     echo "⚠️  Please update the Firebase project ID in .env file"
 fi
 
+if [ ! -f dev-keys/jwks.json ]; then
+    echo "🔑 Generating local token signing key..."
+    go run ./cmd/devtoken -keygen
+fi
+
 echo "🚀 Starting Go server..."
 echo "The server will start on http://localhost:8080"
 echo "API endpoints will be available at http://localhost:8080/api/"
@@ -37,6 +42,6 @@ echo ""
 echo "Press Ctrl+C to stop the server"
 echo ""
 
-go run main.go
+go run .
 
 
@@ -0,0 +1,147 @@
+// Package textsearch matches search queries against text the way the
+// PostgreSQL doctor search does: by word prefix, as a prefix tsquery of the
+// simple configuration matches, and by trigram similarity, as pg_trgm's
+// word_similarity does, so that misspelled queries still match. It also
+// highlights the matching words of a text.
+package textsearch
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Threshold is the word similarity from which a query fuzzily matches a
+// text, the default of pg_trgm.word_similarity_threshold
+const Threshold = 0.6
+
+// Mark tags enclose highlighted words
+const (
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+)
+
+// Words splits s into lower-case words of letters and digits
+func Words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// Matches reports whether query matches text: each word of query starts a
+// word of text, or the query is at least Threshold similar to text
+func Matches(query, text string) bool {
+	return PrefixMatch(query, text) || WordSimilarity(query, text) >= Threshold
+}
+
+// PrefixMatch reports whether each word of query starts a word of text.
+// Queries without words match nothing.
+func PrefixMatch(query, text string) bool {
+	queryWords := Words(query)
+	if len(queryWords) == 0 {
+		return false
+	}
+
+	textWords := Words(text)
+	for _, queryWord := range queryWords {
+		if !startsAny(textWords, queryWord) {
+			return false
+		}
+	}
+	return true
+}
+
+// WordSimilarity approximates pg_trgm's word_similarity(query, text): the
+// share of the query's trigrams found in the closest run of as many
+// consecutive words of text as the query has, from 0 to 1 in steps of
+// 0.001
+func WordSimilarity(query, text string) float64 {
+	queryWords := Words(query)
+	queryTrigrams := trigrams(queryWords)
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+
+	textWords := Words(text)
+	best := 0
+	for start := range textWords {
+		end := min(start+len(queryWords), len(textWords))
+		shared := 0
+		for trigram := range trigrams(textWords[start:end]) {
+			if queryTrigrams[trigram] {
+				shared++
+			}
+		}
+		best = max(best, shared)
+	}
+	return math.Round(float64(best)/float64(len(queryTrigrams))*1000) / 1000
+}
+
+// Highlight returns text as HTML with each word that matches a word of
+// query, by prefix or by similarity, enclosed in MarkStart and MarkEnd.
+// highlighted reports whether any word matched.
+func Highlight(query, text string) (marked string, highlighted bool) {
+	queryWords := Words(query)
+
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) == isWordRune(runes[i]) {
+			j++
+		}
+
+		segment := string(runes[i:j])
+		if isWordRune(runes[i]) && matchesWord(queryWords, strings.ToLower(segment)) {
+			b.WriteString(MarkStart + html.EscapeString(segment) + MarkEnd)
+			highlighted = true
+		} else {
+			b.WriteString(html.EscapeString(segment))
+		}
+		i = j
+	}
+	return b.String(), highlighted
+}
+
+// matchesWord reports whether a query word starts word or is at least
+// Threshold similar to it
+func matchesWord(queryWords []string, word string) bool {
+	for _, queryWord := range queryWords {
+		if strings.HasPrefix(word, queryWord) {
+			return true
+		}
+		if WordSimilarity(queryWord, word) >= Threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// startsAny reports whether prefix starts any of words
+func startsAny(words []string, prefix string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trigrams returns the set of trigrams of words, each padded like pg_trgm
+// pads them: two spaces before and one after
+func trigrams(words []string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// isWordRune reports whether r belongs in words
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,74 @@
+package textsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Dr. John Smith", []string{"dr", "john", "smith"}},
+		{"O'Brien-Walsh", []string{"o", "brien", "walsh"}},
+		{"a & b | !c:* (d) <-> 'e'", []string{"a", "b", "c", "d", "e"}},
+		{"Dr. Müller-Lüdenscheidt", []string{"dr", "müller", "lüdenscheidt"}},
+		{"ÉLODIE Dupré", []string{"élodie", "dupré"}},
+		{"Ярослав Ковальчук", []string{"ярослав", "ковальчук"}},
+		{"王小明，内科", []string{"王小明", "内科"}},
+		{"cardio2024", []string{"cardio2024"}},
+		{"  ...  ", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := Words(test.text); !slices.Equal(got, test.want) {
+			t.Errorf("Words(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        bool
+	}{
+		{"jo sm", "Dr. John Smith", true},
+		{"smith john", "Dr. John Smith", true},
+		{"smyth", "Dr. John Smith", false},
+		{"jonh smith", "Dr. John Smith", true},
+		{"MÜL", "Dr. Müller-Lüdenscheidt", true},
+		{"lüdensch", "Dr. Müller-Lüdenscheidt", true},
+		{"ковал", "Ярослав Ковальчук", true},
+		{"'&|!", "Dr. John Smith", false},
+		{"", "Dr. John Smith", false},
+	}
+	for _, test := range tests {
+		if got := Matches(test.query, test.text); got != test.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", test.query, test.text, got, test.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        string
+		highlighted bool
+	}{
+		{"smith", "Dr. John Smith", "Dr. John <mark>Smith</mark>", true},
+		{"mül", "Dr. Müller-Lüdenscheidt", "Dr. <mark>Müller</mark>-Lüdenscheidt", true},
+		{"lüd mü", "Dr. Müller-Lüdenscheidt", "Dr. <mark>Müller</mark>-<mark>Lüdenscheidt</mark>", true},
+		{"ковал", "Ярослав Ковальчук", "Ярослав <mark>Ковальчук</mark>", true},
+		{"王", "王小明，内科", "<mark>王小明</mark>，内科", true},
+		{"brien", "O'Brien & <Sons>", "O&#39;<mark>Brien</mark> &amp; &lt;Sons&gt;", true},
+		{"cardiology", "Dr. John Smith", "Dr. John Smith", false},
+	}
+	for _, test := range tests {
+		got, highlighted := Highlight(test.query, test.text)
+		if got != test.want || highlighted != test.highlighted {
+			t.Errorf("Highlight(%q, %q) = %q, %v, want %q, %v",
+				test.query, test.text, got, highlighted, test.want, test.highlighted)
+		}
+	}
+}
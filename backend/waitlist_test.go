@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"hospital-backend/handlers"
+	"hospital-backend/models"
+	"hospital-backend/repository/memory"
+)
+
+// offerFreedSlot runs the waitlist worker on the next freed slot
+func (api *testAPI) offerFreedSlot() {
+	api.t.Helper()
+	select {
+	case appointment := <-api.h.FreedSlots():
+		if err := api.h.OfferFreedSlot(context.Background(), appointment); err != nil {
+			api.t.Fatalf("offering freed slot: %v", err)
+		}
+	default:
+		api.t.Fatal("no slot was freed")
+	}
+}
+
+// TestWaitlistOfferKeepsCheckoutHolds checks that the hold of a waitlist
+// offer and the patient's checkout holds on the doctor do not replace each
+// other
+func TestWaitlistOfferKeepsCheckoutHolds(t *testing.T) {
+	store := memory.NewSampleStore()
+	api := newTestAPI(t, store, handlers.Config{})
+	doctor := sampleDoctor(t, store, sampleCardiologist)
+	alice := api.token("alice", models.RolePatient)
+	bob := api.token("bob", models.RolePatient)
+	monday := nextWeekday(time.Monday)
+
+	booked := api.mustBook(bob, doctor, monday, "09:00")
+	response := api.do(http.MethodPost, "/api/v1/waitlist", alice, models.CreateWaitlistEntryRequest{
+		DoctorID: doctor.ID, FromDate: monday, ToDate: monday,
+	})
+	if response.Status != http.StatusCreated {
+		t.Fatalf("joining waitlist: status = %d (%s)", response.Status, response.Error)
+	}
+	api.mustHold(alice, doctor, monday, "10:00")
+
+	// Offering the freed slot keeps the checkout hold
+	response = api.do(http.MethodDelete, "/api/v1/appointments/"+booked.ID.String(), bob, nil)
+	if response.Status != http.StatusOK {
+		t.Fatalf("cancelling: status = %d (%s)", response.Status, response.Error)
+	}
+	api.offerFreedSlot()
+	if got, want := api.freeSlots(doctor, monday, ""), []string{"11:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots after the offer = %v, want %v", got, want)
+	}
+
+	// A new checkout hold replaces the old one but keeps the offer's
+	token := api.mustHold(alice, doctor, monday, "11:00")
+	if got, want := api.freeSlots(doctor, monday, ""), []string{"10:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots after a new hold = %v, want %v", got, want)
+	}
+
+	response = api.do(http.MethodGet, "/api/v1/waitlist", alice, nil)
+	var waitlist struct {
+		Offers []models.WaitlistOffer `json:"offers"`
+	}
+	response.decode(t, &waitlist)
+	if len(waitlist.Offers) != 1 || waitlist.Offers[0].Slot != "09:00" {
+		t.Fatalf("pending offers = %+v, want one for 09:00", waitlist.Offers)
+	}
+
+	response = api.do(http.MethodPost, "/api/v1/waitlist/offers/"+waitlist.Offers[0].ID.String()+"/accept", alice, nil)
+	if response.Status != http.StatusCreated {
+		t.Fatalf("accepting offer: status = %d (%s)", response.Status, response.Error)
+	}
+
+	request := bookingRequest(doctor, monday, "11:00")
+	request.HoldToken = token
+	response = api.do(http.MethodPost, "/api/v1/appointments", alice, request)
+	if response.Status != http.StatusCreated {
+		t.Fatalf("booking held slot: status = %d (%s)", response.Status, response.Error)
+	}
+}
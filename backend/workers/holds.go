@@ -0,0 +1,34 @@
+// Package workers runs the background jobs of the API server
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hospital-backend/repository"
+)
+
+// SweepHolds deletes expired slot holds every interval until ctx is done.
+// Expired holds no longer block anything, so sweeping only keeps the
+// table small.
+func SweepHolds(ctx context.Context, holds repository.HoldRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := holds.DeleteExpired(ctx, now)
+			if err != nil {
+				log.Printf("Failed to sweep expired slot holds: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("Released %d expired slot holds", count)
+			}
+		}
+	}
+}
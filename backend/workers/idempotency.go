@@ -0,0 +1,33 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hospital-backend/repository"
+)
+
+// SweepIdempotencyKeys deletes expired idempotency records every interval
+// until ctx is done. Expired records are already ignored, so sweeping only
+// keeps the table small.
+func SweepIdempotencyKeys(ctx context.Context, records repository.IdempotencyRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := records.DeleteExpired(ctx, now)
+			if err != nil {
+				log.Printf("Failed to sweep expired idempotency keys: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("Deleted %d expired idempotency keys", count)
+			}
+		}
+	}
+}
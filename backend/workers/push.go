@@ -0,0 +1,92 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/push"
+	"hospital-backend/repository"
+)
+
+// pushTimeout bounds the delivery of one push notification
+const pushTimeout = 30 * time.Second
+
+// PushNotifications tells patients about changes to their appointments on
+// the devices they registered
+type PushNotifications struct {
+	Devices   repository.DeviceRepository
+	Messenger push.Messenger
+	// Location is the hospital timezone, used for doctors without their own
+	Location *time.Location
+}
+
+// Run sends a notification for each event as it arrives, until ctx is done
+func (p *PushNotifications) Run(ctx context.Context, events <-chan models.AppointmentEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			if err := p.Send(ctx, event); err != nil {
+				log.Printf("Failed to push %s for appointment %s: %v", event.Kind, event.Appointment.ID, err)
+			}
+		}
+	}
+}
+
+// Send notifies the patient of event on each of their devices and forgets
+// the devices the push service no longer knows
+func (p *PushNotifications) Send(ctx context.Context, event models.AppointmentEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+
+	tokens, err := p.Devices.Tokens(ctx, event.Appointment.PatientID)
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	stale, err := p.Messenger.Send(ctx, tokens, p.message(event))
+	if len(stale) > 0 {
+		if err := p.Devices.Forget(ctx, stale); err != nil {
+			log.Printf("Failed to forget %d stale device tokens: %v", len(stale), err)
+		}
+	}
+	return err
+}
+
+// message describes event to the patient, with the time in the doctor's
+// timezone
+func (p *PushNotifications) message(event models.AppointmentEvent) push.Message {
+	appointment := event.Appointment
+	local, doctorName := describe(appointment, p.Location)
+	when := local.Format("Mon, 2 Jan at 15:04")
+
+	msg := push.Message{
+		Data: map[string]string{
+			"event":          event.Kind,
+			"appointment_id": appointment.ID.String(),
+			"starts_at":      appointment.AppointmentDate.UTC().Format(time.RFC3339),
+		},
+	}
+	switch event.Kind {
+	case models.EventBooked:
+		msg.Title = "Appointment confirmed"
+		msg.Body = fmt.Sprintf("Your appointment with %s is booked for %s.", doctorName, when)
+	case models.EventRescheduled:
+		msg.Title = "Appointment rescheduled"
+		msg.Body = fmt.Sprintf("Your appointment with %s is now on %s.", doctorName, when)
+	case models.EventCancelled:
+		msg.Title = "Appointment cancelled"
+		msg.Body = fmt.Sprintf("Your appointment with %s on %s has been cancelled.", doctorName, when)
+	case models.EventNotesAdded:
+		msg.Title = "New notes from your doctor"
+		msg.Body = fmt.Sprintf("%s added notes to your appointment on %s.", doctorName, when)
+	default:
+		msg.Title = "Appointment updated"
+		msg.Body = fmt.Sprintf("Your appointment with %s on %s has changed.", doctorName, when)
+	}
+	return msg
+}
@@ -0,0 +1,157 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/notify"
+	"hospital-backend/repository"
+)
+
+// Reminders sends patients reminders of their upcoming appointments at
+// each of Offsets before the appointment starts
+type Reminders struct {
+	Appointments repository.AppointmentRepository
+	Reminders    repository.ReminderRepository
+	Notifier     notify.Notifier
+	// Offsets are how long before the start reminders are sent, such as
+	// 24h and 1h
+	Offsets []time.Duration
+	// MaxAttempts caps the deliveries tried for a failing reminder
+	MaxAttempts int
+	// Location is the hospital timezone, used for doctors without their own
+	Location *time.Location
+}
+
+// Run sends the due reminders every interval until ctx is done
+func (r *Reminders) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.SendDue(ctx, time.Now()); err != nil {
+			log.Printf("Failed to send appointment reminders: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends the reminders due at now. An appointment gets one reminder
+// per offset; when several offsets are due at once, as for an appointment
+// booked shortly before it starts, only the closest to the start is sent.
+// Each reminder is claimed before delivery, so it is sent at most once.
+func (r *Reminders) SendDue(ctx context.Context, now time.Time) error {
+	if len(r.Offsets) == 0 {
+		return nil
+	}
+	offsets := append([]time.Duration(nil), r.Offsets...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	appointments, err := r.Appointments.List(ctx, repository.AppointmentFilter{
+		From:       now,
+		Until:      now.Add(offsets[len(offsets)-1]),
+		ActiveOnly: true,
+		Ascending:  true,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, appointment := range appointments {
+		if appointment.Status != models.StatusScheduled && appointment.Status != models.StatusRescheduled {
+			continue
+		}
+
+		// The smallest offset at least as long as the time left is due
+		left := appointment.AppointmentDate.Sub(now)
+		i := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= left })
+		if i == len(offsets) {
+			continue
+		}
+
+		if err := r.send(ctx, appointment, offsets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send claims and delivers the reminder of appointment at offset. Delivery
+// failures are recorded on the reminder; only storage errors are returned.
+func (r *Reminders) send(ctx context.Context, appointment models.Appointment, offset time.Duration) error {
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	claimed, err := r.Reminders.Claim(ctx, appointment.ID, appointment.AppointmentDate, offset, maxAttempts)
+	if err != nil || !claimed {
+		return err
+	}
+
+	status, errMessage := models.ReminderSent, ""
+	err = r.Notifier.Notify(ctx, r.message(appointment))
+	switch {
+	case errors.Is(err, notify.ErrNoAddress):
+		status, errMessage = models.ReminderSkipped, err.Error()
+	case err != nil:
+		status, errMessage = models.ReminderFailed, err.Error()
+		log.Printf("Failed to send the %s reminder of appointment %s: %v", offset, appointment.ID, err)
+	}
+
+	return r.Reminders.Finish(ctx, appointment.ID, appointment.AppointmentDate, offset, status, errMessage)
+}
+
+// message describes appointment to its patient, with the time in the
+// doctor's timezone
+func (r *Reminders) message(appointment models.Appointment) notify.Message {
+	local, doctorName := describe(appointment, r.Location)
+
+	var to notify.Recipient
+	if patient := appointment.Patient; patient != nil {
+		to = notify.Recipient{Name: patient.Name, Email: patient.Email, Phone: patient.Phone}
+	}
+
+	kind := strings.ReplaceAll(appointment.Type, "_", "-")
+	if kind == "" {
+		kind = models.TypeConsultation
+	}
+	return notify.Message{
+		To:      to,
+		Subject: fmt.Sprintf("Reminder: appointment with %s on %s", doctorName, local.Format("Mon, 2 Jan")),
+		Body: fmt.Sprintf("Your %s with %s is on %s at %s (%s).",
+			kind, doctorName, local.Format("Monday, 2 January 2006"), local.Format("15:04"), local.Location()),
+	}
+}
+
+// describe returns the start of appointment in its doctor's timezone, or in
+// fallback when the doctor has none, and the doctor's name for messages
+func describe(appointment models.Appointment, fallback *time.Location) (time.Time, string) {
+	loc := fallback
+	if loc == nil {
+		loc = time.UTC
+	}
+	doctorName := "your doctor"
+	if doctor := appointment.Doctor; doctor != nil {
+		if doctor.Timezone != "" {
+			if doctorLoc, err := time.LoadLocation(doctor.Timezone); err == nil {
+				loc = doctorLoc
+			}
+		}
+		if doctor.User != nil && doctor.User.Name != "" {
+			doctorName = doctor.User.Name
+		}
+	}
+	return appointment.AppointmentDate.In(loc), doctorName
+}
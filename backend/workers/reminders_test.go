@@ -0,0 +1,172 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"hospital-backend/models"
+	"hospital-backend/notify"
+	"hospital-backend/repository"
+	"hospital-backend/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+// recordingNotifier keeps the messages it is asked to deliver
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.messages)
+}
+
+// bookSample books an appointment of the sample patient with a sample
+// doctor starting at startsAt
+func bookSample(t *testing.T, store repository.Store, startsAt time.Time) *models.Appointment {
+	t.Helper()
+	ctx := context.Background()
+
+	doctors, err := store.Doctors.List(ctx, repository.DoctorFilter{})
+	if err != nil || len(doctors) == 0 {
+		t.Fatalf("listing sample doctors: %v", err)
+	}
+	patient, err := store.Users.GetByFirebaseUID(ctx, "sample_firebase_uid_3")
+	if err != nil {
+		t.Fatalf("fetching sample patient: %v", err)
+	}
+
+	appointment := &models.Appointment{
+		DoctorID:        doctors[0].ID,
+		PatientID:       patient.ID,
+		AppointmentDate: startsAt,
+		OccupiedUntil:   startsAt.Add(30 * time.Minute),
+		Slot:            startsAt.Format("15:04"),
+		Type:            models.TypeConsultation,
+		DurationMinutes: 30,
+	}
+	if err := store.Appointments.Book(ctx, appointment, patient.ID, uuid.Nil); err != nil {
+		t.Fatalf("booking: %v", err)
+	}
+	return appointment
+}
+
+func TestRemindersSendOncePerOffset(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewSampleStore()
+	startsAt := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	bookSample(t, store, startsAt)
+
+	notifier := &recordingNotifier{}
+	newWorker := func() *Reminders {
+		return &Reminders{
+			Appointments: store.Appointments,
+			Reminders:    store.Reminders,
+			Notifier:     notifier,
+			Offsets:      []time.Duration{24 * time.Hour, time.Hour},
+			MaxAttempts:  3,
+		}
+	}
+
+	worker := newWorker()
+	steps := []struct {
+		before time.Duration
+		want   int
+	}{
+		{30 * time.Hour, 0},
+		{23 * time.Hour, 1},
+		{22 * time.Hour, 1},
+		{50 * time.Minute, 2},
+		{40 * time.Minute, 2},
+	}
+	for _, step := range steps {
+		if err := worker.SendDue(ctx, startsAt.Add(-step.before)); err != nil {
+			t.Fatalf("SendDue %s before: %v", step.before, err)
+		}
+		if got := notifier.count(); got != step.want {
+			t.Errorf("%s before the start: %d reminders sent, want %d", step.before, got, step.want)
+		}
+	}
+
+	// A restarted worker finds the reminders already sent
+	if err := newWorker().SendDue(ctx, startsAt.Add(-30*time.Minute)); err != nil {
+		t.Fatalf("SendDue after restart: %v", err)
+	}
+	if got := notifier.count(); got != 2 {
+		t.Errorf("after restart: %d reminders sent, want 2", got)
+	}
+
+	sent, err := store.Reminders.List(ctx, repository.ReminderFilter{Status: models.ReminderSent})
+	if err != nil {
+		t.Fatalf("listing reminders: %v", err)
+	}
+	if len(sent) != 2 {
+		t.Errorf("%d reminders recorded as sent, want 2", len(sent))
+	}
+}
+
+// TestRemindersClaimedNotResent checks that a reminder claimed by a worker
+// that stopped before finishing is not sent again after a restart
+func TestRemindersClaimedNotResent(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewSampleStore()
+	startsAt := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	appointment := bookSample(t, store, startsAt)
+
+	claimed, err := store.Reminders.Claim(ctx, appointment.ID, startsAt, time.Hour, 3)
+	if err != nil || !claimed {
+		t.Fatalf("Claim = %v, %v, want true", claimed, err)
+	}
+
+	notifier := &recordingNotifier{}
+	worker := &Reminders{
+		Appointments: store.Appointments,
+		Reminders:    store.Reminders,
+		Notifier:     notifier,
+		Offsets:      []time.Duration{time.Hour},
+		MaxAttempts:  3,
+	}
+	if err := worker.SendDue(ctx, startsAt.Add(-30*time.Minute)); err != nil {
+		t.Fatalf("SendDue: %v", err)
+	}
+	if got := notifier.count(); got != 0 {
+		t.Errorf("%d reminders sent for a claimed reminder, want 0", got)
+	}
+}
+
+// TestRemindersLateBooking checks that an appointment booked inside
+// several offsets gets only the reminder closest to its start
+func TestRemindersLateBooking(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewSampleStore()
+	startsAt := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	bookSample(t, store, startsAt)
+
+	notifier := &recordingNotifier{}
+	worker := &Reminders{
+		Appointments: store.Appointments,
+		Reminders:    store.Reminders,
+		Notifier:     notifier,
+		Offsets:      []time.Duration{24 * time.Hour, time.Hour},
+	}
+	for _, before := range []time.Duration{30 * time.Minute, 20 * time.Minute} {
+		if err := worker.SendDue(ctx, startsAt.Add(-before)); err != nil {
+			t.Fatalf("SendDue: %v", err)
+		}
+	}
+	if got := notifier.count(); got != 1 {
+		t.Errorf("%d reminders sent, want 1", got)
+	}
+}
@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hospital-backend/models"
+)
+
+// WaitlistPromoter offers freed slots to waitlisted patients
+type WaitlistPromoter interface {
+	// FreedSlots delivers the appointments whose time was freed
+	FreedSlots() <-chan models.Appointment
+	// OfferFreedSlot offers the time of appointment to the next patient
+	OfferFreedSlot(ctx context.Context, appointment models.Appointment) error
+	// ExpireWaitlistOffers passes on the offers not accepted before now
+	ExpireWaitlistOffers(ctx context.Context, now time.Time) error
+}
+
+// PromoteWaitlist offers each freed slot to the waitlist as it arrives and
+// expires unanswered offers every interval, until ctx is done. Slots are
+// offered one at a time, so two freed slots never race for one patient.
+func PromoteWaitlist(ctx context.Context, promoter WaitlistPromoter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case appointment := <-promoter.FreedSlots():
+			if err := promoter.OfferFreedSlot(ctx, appointment); err != nil {
+				log.Printf("Failed to offer the slot of appointment %s to the waitlist: %v", appointment.ID, err)
+			}
+		case now := <-ticker.C:
+			if err := promoter.ExpireWaitlistOffers(ctx, now); err != nil {
+				log.Printf("Failed to expire waitlist offers: %v", err)
+			}
+		}
+	}
+}